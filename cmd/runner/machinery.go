@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"task-runner-service/internal/broker/eager"
+	"task-runner-service/internal/config"
+	"task-runner-service/internal/storage/redis"
+
+	"github.com/RichardKnop/machinery/v1"
+	backendiface "github.com/RichardKnop/machinery/v1/backends/iface"
+	redisbackend "github.com/RichardKnop/machinery/v1/backends/redis"
+	brokeriface "github.com/RichardKnop/machinery/v1/brokers/iface"
+	redisbroker "github.com/RichardKnop/machinery/v1/brokers/redis"
+	machineryConfig "github.com/RichardKnop/machinery/v1/config"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// Pool defaults of machinery, used when the redis section leaves them unset.
+const (
+	machineryMaxIdle     = 10
+	machineryMaxActive   = 100
+	machineryIdleTimeout = 300
+	machineryTimeout     = 15 * time.Second
+	machineryPollPeriod  = 1000
+	machineryDelayedPoll = 20
+)
+
+// newMachineryServer creates a machinery server whose default queue is queue.
+// The broker and the result backend connect to the Redis deployment of the
+// redis section, with the same options as the storage, unless broker.broker
+// or broker.result_backend name other servers.
+func newMachineryServer(cfg *config.Config, queue string) (*machinery.Server, error) {
+	if cfg.EagerBroker() {
+		return newEagerServer(cfg, queue)
+	}
+
+	opts, err := redis.Options(*cfg.Redis)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := cfg.Redis.Mode()
+	cnf := &machineryConfig.Config{
+		Broker:        cfg.Broker.Broker,
+		DefaultQueue:  queue,
+		ResultBackend: cfg.Broker.ResultBackend,
+		NoUnixSignals: true,
+		TLSConfig:     opts.TLSConfig,
+		Redis: &machineryConfig.RedisConfig{
+			MaxIdle:                machineryMaxIdle,
+			MaxActive:              positive(opts.PoolSize, machineryMaxActive),
+			IdleTimeout:            machineryIdleTimeout,
+			Wait:                   true,
+			ReadTimeout:            seconds(opts.ReadTimeout, machineryTimeout),
+			WriteTimeout:           seconds(opts.WriteTimeout, machineryTimeout),
+			ConnectTimeout:         seconds(opts.DialTimeout, machineryTimeout),
+			NormalTasksPollPeriod:  machineryPollPeriod,
+			DelayedTasksPollPeriod: machineryDelayedPoll,
+			MasterName:             opts.MasterName,
+			ClusterMode:            mode == config.RedisCluster,
+		},
+	}
+
+	if mode == config.RedisStandalone {
+		// machinery connects to a single server on its own, given a URL.
+		if cnf.Broker == "" {
+			cnf.Broker = machineryURL(opts)
+		}
+		if cnf.ResultBackend == "" {
+			cnf.ResultBackend = machineryURL(opts)
+		}
+		return machinery.NewServer(cnf)
+	}
+
+	// The Sentinel and Cluster clients of machinery take the addresses
+	// directly and know neither TLS nor Sentinel passwords.
+	if (cnf.Broker == "" || cnf.ResultBackend == "") && (opts.TLSConfig != nil || opts.SentinelPassword != "") {
+		return nil, fmt.Errorf("machinery does not support TLS or Sentinel passwords with Redis %s, set broker.broker and broker.result_backend", mode)
+	}
+
+	var broker brokeriface.Broker
+	if cnf.Broker == "" {
+		broker = redisbroker.NewGR(cnf, machineryAddrs(opts), opts.DB)
+	} else if broker, err = machinery.BrokerFactory(cnf); err != nil {
+		return nil, err
+	}
+	var backend backendiface.Backend
+	if cnf.ResultBackend == "" {
+		backend = redisbackend.NewGR(cnf, machineryAddrs(opts), opts.DB)
+	} else if backend, err = machinery.BackendFactory(cnf); err != nil {
+		return nil, err
+	}
+	lock, err := machinery.LockFactory(cnf)
+	if err != nil {
+		return nil, err
+	}
+	return machinery.NewServerWithBrokerBackendLock(cnf, broker, backend, lock), nil
+}
+
+// eagerHub connects the machinery servers of the process when tasks do not
+// go through Redis.
+var eagerHub = eager.NewHub()
+
+// newEagerServer creates a machinery server on eagerHub. Task states are kept
+// by the hub as well unless broker.result_backend names a server.
+func newEagerServer(cfg *config.Config, queue string) (*machinery.Server, error) {
+	cnf := &machineryConfig.Config{
+		Broker:        config.BrokerEager,
+		DefaultQueue:  queue,
+		ResultBackend: cfg.Broker.ResultBackend,
+		NoUnixSignals: true,
+	}
+
+	var backend backendiface.Backend = eagerHub.Backend()
+	if cnf.ResultBackend != "" && cnf.ResultBackend != config.BrokerEager {
+		var err error
+		if backend, err = machinery.BackendFactory(cnf); err != nil {
+			return nil, err
+		}
+	}
+	lock, err := machinery.LockFactory(cnf)
+	if err != nil {
+		return nil, err
+	}
+	return machinery.NewServerWithBrokerBackendLock(cnf, eagerHub.Broker(cnf), backend, lock), nil
+}
+
+// machineryUsesRedis tells whether machinery connects to the redis section,
+// rather than to the servers named by broker.broker and
+// broker.result_backend or to none.
+func machineryUsesRedis(cfg *config.Config) bool {
+	return !cfg.EagerBroker() && (cfg.Broker.Broker == "" || cfg.Broker.ResultBackend == "")
+}
+
+// machineryURL builds the URL machinery parses for a single server. TLS is
+// configured through machineryConfig.Config.TLSConfig rather than the scheme.
+func machineryURL(opts *goredis.UniversalOptions) string {
+	u := url.URL{Scheme: "redis", Host: opts.Addrs[0], Path: "/" + strconv.Itoa(opts.DB)}
+	if opts.Password != "" {
+		u.User = url.UserPassword(opts.Username, opts.Password)
+	}
+	return u.String()
+}
+
+// machineryAddrs returns the addresses for the go-redis clients of machinery,
+// which expect the password in front of the first one.
+func machineryAddrs(opts *goredis.UniversalOptions) []string {
+	addrs := append([]string(nil), opts.Addrs...)
+	if opts.Password != "" {
+		addrs[0] = opts.Password + "@" + addrs[0]
+	}
+	return addrs
+}
+
+func positive(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// seconds converts a timeout for machinery, which counts whole seconds and
+// takes zero for no timeout.
+func seconds(value, fallback time.Duration) int {
+	if value <= 0 {
+		value = fallback
+	}
+	return int((value + time.Second - 1) / time.Second)
+}
@@ -2,68 +2,157 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"syscall"
 	"time"
 
 	"task-runner-service/internal/api"
 	"task-runner-service/internal/config"
+	"task-runner-service/internal/health"
+	"task-runner-service/internal/metrics"
+	"task-runner-service/internal/registry"
+	"task-runner-service/internal/scheduler"
 	"task-runner-service/internal/service"
+	"task-runner-service/internal/storage"
 	"task-runner-service/internal/storage/redis"
+	"task-runner-service/internal/tracing"
+	"task-runner-service/internal/worker"
 	"task-runner-service/pkg/logger"
 
 	v1 "task-runner-service/internal/api/v1"
 
 	"github.com/RichardKnop/machinery/v1"
-	machineryConfig "github.com/RichardKnop/machinery/v1/config"
+	machinerylog "github.com/RichardKnop/machinery/v1/log"
+	"go.uber.org/zap"
 )
 
+const interruptTimeout = 10 * time.Second
+
 func main() {
-	absPath, err := filepath.Abs("./internal/config/config.yaml")
+	ctx := context.Background()
+
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
 	if err != nil {
-		log.Fatalf("Failed to resolve config file path: %v", err)
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		logger.Fatal(ctx, "invalid configuration", zap.Error(err))
 	}
+	if cfg.Log != nil {
+		if err := logger.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+			logger.Fatal(ctx, "invalid configuration", zap.Error(err))
+		}
+	}
+	machinerylog.SetDebug(logger.StdLog(zap.DebugLevel))
+	machinerylog.SetInfo(logger.StdLog(zap.InfoLevel))
+	machinerylog.SetWarning(logger.StdLog(zap.WarnLevel))
+	machinerylog.SetError(logger.StdLog(zap.ErrorLevel))
+	machinerylog.SetFatal(logger.StdLog(zap.FatalLevel))
+	logger.Info(ctx, "configuration loaded", zap.Any("config", cfg.Redacted()))
 
-	cfg, err := config.ParseConfig(absPath)
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
 	if err != nil {
-		logger.Errorf("Error parsing configuration: %v", err)
-		log.Fatal("Exiting due to configuration error")
+		logger.Fatal(ctx, "failed to initialize tracing", zap.Error(err))
 	}
-	logger.Infof("Configuration loaded: %+v", cfg)
 
-	redisStorage, err := redis.NewStorage(*cfg.Redis)
+	store, err := newStorage(cfg)
 	if err != nil {
-		logger.Errorf("Error initializing Redis storage: %v", err)
-		log.Fatal("Exiting due to Redis initialization error")
+		logger.Fatal(ctx, "failed to initialize storage", zap.String("storage", cfg.Storage), zap.Error(err))
 	}
-	logger.Info("Redis storage initialized successfully")
+	defer store.Close()
+	logger.Info(ctx, "storage initialized", zap.String("storage", cfg.Storage))
 
-	machineryCfg := &machineryConfig.Config{
-		Broker:        cfg.Broker.Broker,
-		DefaultQueue:  cfg.Broker.DefaultQueue,
-		ResultBackend: cfg.Broker.ResultBackend,
-	}
-	machineryServer, err := machinery.NewServer(machineryCfg)
+	machineryServer, err := newMachineryServer(cfg, cfg.Broker.DefaultQueue)
 	if err != nil {
-		logger.Errorf("Error creating Machinery server: %v", err)
-		log.Fatal("Exiting due to Machinery server error")
+		logger.Fatal(ctx, "failed to create Machinery server", zap.Error(err))
 	}
-	logger.Info("Machinery server created")
+	logger.Info(ctx, "Machinery server created")
 
-	go func() {
-		if err := runWorkers(machineryServer); err != nil {
-			logger.Errorf("Error starting workers: %v", err)
-			log.Fatal("Exiting due to worker startup error")
+	taskRegistry := registry.New()
+	if err := registry.RegisterBuiltins(taskRegistry); err != nil {
+		logger.Fatal(ctx, "failed to register tasks", zap.Error(err))
+	}
+	if err := setRetryPolicies(taskRegistry, cfg.Retries); err != nil {
+		logger.Fatal(ctx, "failed to configure retries", zap.Error(err))
+	}
+
+	queues := declaredQueues(cfg)
+	runnerService := service.NewRunnerService(machineryServer, store, taskRegistry)
+	if err := runnerService.SetQueues(queues); err != nil {
+		logger.Fatal(ctx, "failed to configure queues", zap.Error(err))
+	}
+
+	runAPI := cfg.Mode == config.ModeAPI || cfg.Mode == config.ModeAll
+	runWorker := cfg.Mode == config.ModeWorker || cfg.Mode == config.ModeAll
+	logger.Info(ctx, "starting", zap.String("mode", cfg.Mode))
+
+	checker := newHealthChecker(cfg, store)
+	if err := metrics.RegisterQueueDepth(store.GetQueueDepth); err != nil {
+		logger.Error(ctx, "failed to register queue depth metric", zap.Error(err))
+	}
+
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	var (
+		pool      *worker.Pool
+		lifecycle *worker.Lifecycle
+	)
+	executor := worker.NewExecutor(store)
+	if runWorker {
+		lifecycle = worker.NewLifecycle(store, machineryServer.GetBackend())
+		go func() {
+			if err := executor.Watch(workerCtx); err != nil {
+				logger.Error(workerCtx, "failed to watch task cancellations", zap.Error(err))
+			}
+		}()
+
+		pool, err = runWorkers(cfg, queues, taskRegistry, lifecycle, executor)
+		if err != nil {
+			logger.Fatal(ctx, "failed to start workers", zap.Error(err))
 		}
-	}()
+		go func() {
+			if err := pool.Wait(); err != nil {
+				logger.Fatal(ctx, "workers stopped unexpectedly", zap.Error(err))
+			}
+		}()
+
+		heartbeat := worker.NewHeartbeat(store, executor, queueNames(queues))
+		go heartbeat.Run(workerCtx)
+		checker.Add("workers", true, worker.CheckHeartbeats(store, heartbeat.WorkerID()))
+	} else {
+		// The API tier only reports whether any worker is alive.
+		checker.Add("workers", false, worker.CheckHeartbeats(store, ""))
+	}
+
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	if runAPI {
+		go scheduler.New(store, runnerService, store).Run(schedulerCtx)
+	}
+
+	reloader := &reloader{
+		cfg:          cfg,
+		queues:       queues,
+		taskRegistry: taskRegistry,
+		executor:     executor,
+		lifecycle:    lifecycle,
+		pool:         pool,
+	}
+	watcher, err := config.NewWatcher(os.Args[0], os.Args[1:], cfg, reloader.apply)
+	if err != nil {
+		logger.Fatal(ctx, "failed to watch configuration", zap.Error(err))
+	}
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go watcher.Run(watchCtx)
 
-	runnerService := service.NewRunnerService(machineryServer, redisStorage)
 	v1Handler := v1.NewHandler(runnerService)
+	v1Handler.SetHealthChecker(checker)
 
 	httpConfig := &api.HTTPConfig{
 		Host:         cfg.Server.Host,
@@ -71,35 +160,260 @@ func main() {
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 	}
-	server := api.NewServer(httpConfig, v1Handler)
+	// Worker processes still serve the health endpoints so that the drain
+	// can be observed.
+	server := api.NewHealthServer(httpConfig, v1Handler)
+	if runAPI {
+		server = api.NewServer(httpConfig, v1Handler)
+	}
 
 	go func() {
 		if err := server.Run(); err != nil && err != http.ErrServerClosed {
-			logger.Errorf("Error starting HTTP server: %v", err)
-			log.Fatal("Exiting due to HTTP server error")
+			logger.Fatal(ctx, "failed to start HTTP server", zap.Error(err))
 		}
 	}()
-	logger.Infof("HTTP server listening on port %s", httpConfig.Port)
+	logger.Info(ctx, "HTTP server listening", zap.String("port", httpConfig.Port))
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 
-	logger.Info("Shutting down application...")
+	logger.Info(ctx, "shutting down")
+	checker.SetNotReady("shutting down")
+	stopWatching()
+	stopScheduler()
+
+	if pool != nil {
+		drainWorkers(pool, executor, checker, cfg.Broker.DrainTimeout)
+		stopWorker()
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	stopCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	if err := server.Stop(ctx); err != nil {
-		logger.Errorf("Error stopping HTTP server: %v", err)
-		log.Fatal("Exiting due to HTTP shutdown error")
+	if err := server.Stop(stopCtx); err != nil {
+		logger.Fatal(ctx, "failed to stop HTTP server", zap.Error(err))
+	}
+	if err := shutdownTracing(stopCtx); err != nil {
+		logger.Error(ctx, "failed to flush traces", zap.Error(err))
 	}
 }
 
-func runWorkers(server *machinery.Server) error {
-	worker := server.NewWorker("task_worker", 10)
-	if err := worker.Launch(); err != nil {
-		return fmt.Errorf("failed to launch worker: %w", err)
+// drainWorkers stops fetching new tasks and waits up to timeout for running
+// ones. Tasks still running after that are interrupted: their state is
+// recorded and they are requeued for another worker.
+func drainWorkers(pool *worker.Pool, executor *worker.Executor, checker *health.Checker, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logger.Info(ctx, "draining workers", zap.Int("running", executor.Running()), zap.Duration("timeout", timeout))
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				running := executor.Running()
+				checker.SetNotReady(fmt.Sprintf("draining, %d tasks running", running))
+				logger.Info(ctx, "draining workers", zap.Int("running", running))
+			}
+		}
+	}()
+
+	if err := pool.Stop(ctx); err == nil {
+		logger.Info(ctx, "workers drained")
+		return
+	}
+
+	interrupted := executor.Interrupt()
+	logger.Warn(ctx, "drain timeout reached, interrupted and requeued tasks", zap.Int("interrupted", interrupted))
+
+	interruptCtx, cancelInterrupt := context.WithTimeout(context.Background(), interruptTimeout)
+	defer cancelInterrupt()
+	if err := pool.Stop(interruptCtx); err != nil {
+		logger.Error(interruptCtx, "failed to stop workers", zap.Error(err))
+		return
+	}
+	logger.Info(interruptCtx, "workers stopped")
+}
+
+// newHealthChecker checks the storage and the Redis servers used by
+// machinery, when they are not the ones of the storage. Worker heartbeats
+// are added depending on the run mode.
+func newHealthChecker(cfg *config.Config, store storage.Storage) *health.Checker {
+	checker := health.NewChecker()
+	checker.Add(cfg.Storage, true, health.Ping(store.Ping))
+	if cfg.Storage != config.StorageRedis && machineryUsesRedis(cfg) {
+		if client, err := redis.NewClient(*cfg.Redis); err != nil {
+			logger.Warn(context.Background(), "health check is disabled", zap.String("component", "redis"), zap.Error(err))
+		} else {
+			checker.Add("redis", true, health.Ping(func(ctx context.Context) error {
+				return client.Ping(ctx).Err()
+			}))
+		}
+	}
+
+	for name, url := range map[string]string{
+		"broker":         cfg.Broker.Broker,
+		"result_backend": cfg.Broker.ResultBackend,
+	} {
+		if url == "" || url == config.BrokerEager {
+			continue
+		}
+		check, err := health.RedisURL(url)
+		if err != nil {
+			logger.Warn(context.Background(), "health check is disabled", zap.String("component", name), zap.Error(err))
+			continue
+		}
+		checker.Add(name, true, check)
+	}
+	return checker
+}
+
+func queueNames(queues []service.Queue) []string {
+	names := make([]string, 0, len(queues))
+	for _, queue := range queues {
+		if queue.Enabled {
+			names = append(names, queue.Name)
+		}
+	}
+	return names
+}
+
+// runWorkers launches workers for every enabled queue.
+func runWorkers(cfg *config.Config, queues []service.Queue, taskRegistry *registry.TaskRegistry, lifecycle *worker.Lifecycle, executor *worker.Executor) (*worker.Pool, error) {
+	pool := worker.NewPool()
+	for _, queue := range queues {
+		if !queue.Enabled {
+			logger.Info(context.Background(), "queue is disabled, no workers started", zap.String("queue", queue.Name))
+			continue
+		}
+
+		executor.SetRateLimit(queue.Name, queue.RateLimit)
+		workers, err := queueWorkers(cfg, queue, taskRegistry, lifecycle, executor)
+		if err != nil {
+			return nil, err
+		}
+		pool.Launch(queue.Name, workers...)
+	}
+	if pool.Len() == 0 {
+		return nil, fmt.Errorf("no enabled queues")
+	}
+	return pool, nil
+}
+
+// queueWorkers creates the workers of a queue. Each priority level is
+// consumed by its own machinery server, so the brokers do not share
+// connections or stop channels, and the queue's slots are split between the
+// levels by the configured weights.
+func queueWorkers(cfg *config.Config, queue service.Queue, taskRegistry *registry.TaskRegistry, lifecycle *worker.Lifecycle, executor *worker.Executor) ([]*machinery.Worker, error) {
+	handlers := executor.WrapQueue(queue.Name, queueTasks(queue, taskRegistry))
+
+	var workers []*machinery.Worker
+	for level, slots := range worker.SplitConcurrency(queue.Concurrency, priorityWeights(cfg)) {
+		name := service.PriorityQueue(queue.Name, level)
+		server, err := newMachineryServer(cfg, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create server for queue %s: %w", name, err)
+		}
+		if err := server.RegisterTasks(handlers); err != nil {
+			return nil, fmt.Errorf("failed to register tasks for queue %s: %w", name, err)
+		}
+
+		taskWorker := server.NewCustomQueueWorker(fmt.Sprintf("task_worker_%s_%s", queue.Name, level), slots, name)
+		lifecycle.Attach(taskWorker)
+		workers = append(workers, taskWorker)
+		logger.Info(context.Background(), "worker started", zap.String("queue", name), zap.Int("concurrency", slots))
+	}
+	return workers, nil
+}
+
+func priorityWeights(cfg *config.Config) map[string]int {
+	return cfg.Broker.Weights()
+}
+
+// declaredQueues converts the queues section of the config. Without it the
+// default queue accepts every task with the broker concurrency.
+func declaredQueues(cfg *config.Config) []service.Queue {
+	concurrency := cfg.Broker.Concurrency
+	if len(cfg.Queues) == 0 {
+		return []service.Queue{{Name: cfg.Broker.DefaultQueue, Concurrency: concurrency, Enabled: true}}
+	}
+
+	queues := make([]service.Queue, 0, len(cfg.Queues))
+	for _, queueCfg := range cfg.Queues {
+		queue := service.Queue{
+			Name:        queueCfg.Name,
+			Concurrency: queueCfg.Concurrency,
+			RateLimit:   queueCfg.RateLimit,
+			Tasks:       queueCfg.Tasks,
+			Enabled:     queueCfg.IsEnabled(),
+		}
+		if queue.Concurrency <= 0 {
+			queue.Concurrency = concurrency
+		}
+		queues = append(queues, queue)
+	}
+	return queues
+}
+
+func queueTasks(queue service.Queue, taskRegistry *registry.TaskRegistry) map[string]interface{} {
+	all := taskRegistry.Tasks()
+	if len(queue.Tasks) == 0 {
+		return all
+	}
+
+	allowed := make(map[string]interface{}, len(queue.Tasks))
+	for _, name := range queue.Tasks {
+		if fn, ok := all[name]; ok {
+			allowed[name] = fn
+		}
+	}
+	return allowed
+}
+
+// setRetryPolicies applies the retries section; task types it leaves out get
+// the default policy. Nothing is changed when an entry is invalid.
+func setRetryPolicies(taskRegistry *registry.TaskRegistry, retries map[string]config.RetryConfig) error {
+	policies := make(map[string]registry.RetryPolicy)
+	for _, taskType := range taskRegistry.Types() {
+		policies[taskType.Name] = registry.DefaultRetryPolicy
+	}
+	for name, retryCfg := range retries {
+		if _, ok := policies[name]; !ok {
+			return fmt.Errorf("task %q is not registered", name)
+		}
+		policy := retryPolicy(retryCfg)
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("invalid retry policy for task %q: %w", name, err)
+		}
+		policies[name] = policy
+	}
+
+	for name, policy := range policies {
+		if err := taskRegistry.SetRetryPolicy(name, policy); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+func retryPolicy(cfg config.RetryConfig) registry.RetryPolicy {
+	policy := registry.RetryPolicy{
+		MaxAttempts: cfg.MaxAttempts,
+		Backoff:     cfg.Backoff,
+		Interval:    cfg.Interval,
+		MaxInterval: cfg.MaxInterval,
+		Jitter:      cfg.Jitter,
+		RetryOn:     cfg.RetryOn,
+	}
+	if policy.Backoff == "" {
+		policy.Backoff = registry.DefaultRetryPolicy.Backoff
+	}
+	if policy.Interval == 0 {
+		policy.Interval = registry.DefaultRetryPolicy.Interval
+	}
+	return policy
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"reflect"
+
+	"task-runner-service/internal/config"
+	"task-runner-service/internal/registry"
+	"task-runner-service/internal/service"
+	"task-runner-service/internal/worker"
+	"task-runner-service/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// reloader applies the configuration keys that can change while the process
+// runs, see config.Reconcile. Calls are serialized by config.Watcher.
+type reloader struct {
+	cfg          *config.Config
+	queues       []service.Queue
+	taskRegistry *registry.TaskRegistry
+	executor     *worker.Executor
+	lifecycle    *worker.Lifecycle
+	// pool is nil when the process runs no workers.
+	pool *worker.Pool
+}
+
+func (r *reloader) apply(ctx context.Context, cfg *config.Config, applied []string) {
+	if cfg.Log != nil {
+		if err := logger.SetLevel(cfg.Log.Level); err != nil {
+			logger.Error(ctx, "failed to apply log level", zap.Error(err))
+		}
+	}
+	if err := setRetryPolicies(r.taskRegistry, cfg.Retries); err != nil {
+		logger.Error(ctx, "failed to apply retries, keeping the previous policies", zap.Error(err))
+	}
+	if r.pool != nil {
+		r.applyQueues(ctx, cfg)
+	}
+	r.cfg = cfg
+}
+
+// applyQueues updates the rate limits and replaces the workers of the queues
+// whose slots changed. The replaced workers stop fetching messages and finish
+// their running tasks in the background.
+func (r *reloader) applyQueues(ctx context.Context, cfg *config.Config) {
+	queues := declaredQueues(cfg)
+	weightsChanged := !reflect.DeepEqual(priorityWeights(r.cfg), priorityWeights(cfg))
+
+	for i, queue := range queues {
+		if !queue.Enabled {
+			continue
+		}
+		r.executor.SetRateLimit(queue.Name, queue.RateLimit)
+		if queue.Concurrency == r.queues[i].Concurrency && !weightsChanged {
+			continue
+		}
+
+		workers, err := queueWorkers(cfg, queue, r.taskRegistry, r.lifecycle, r.executor)
+		if err == nil {
+			err = r.pool.Replace(queue.Name, workers...)
+		}
+		if err != nil {
+			logger.Error(ctx, "failed to replace queue workers, keeping the running ones", zap.String("queue", queue.Name), zap.Error(err))
+			queues[i].Concurrency = r.queues[i].Concurrency
+			continue
+		}
+		logger.Info(ctx, "queue workers replaced", zap.String("queue", queue.Name), zap.Int("concurrency", queue.Concurrency))
+	}
+	r.queues = queues
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"task-runner-service/internal/config"
+	"task-runner-service/internal/storage"
+	"task-runner-service/internal/storage/memory"
+	"task-runner-service/internal/storage/postgres"
+	"task-runner-service/internal/storage/redis"
+)
+
+// newStorage connects to the storage backend selected by the config.
+func newStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.Storage {
+	case config.StoragePostgres:
+		store, err := postgres.NewStorage(*cfg.Postgres)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case config.StorageMemory:
+		return memory.NewStorage(), nil
+	default:
+		store, err := redis.NewStorage(*cfg.Redis)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+}
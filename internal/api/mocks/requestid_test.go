@@ -0,0 +1,42 @@
+package mocks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"task-runner-service/internal/api"
+	"task-runner-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveWithRequestID(header string) (string, string) {
+	var seen string
+	handler := api.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logger.RequestID(r.Context())
+	}))
+
+	request := httptest.NewRequest("GET", "/api/v1/tasks", nil)
+	if header != "" {
+		request.Header.Set(api.RequestIDHeader, header)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return seen, recorder.Header().Get(api.RequestIDHeader)
+}
+
+func TestRequestID_KeepsClientID(t *testing.T) {
+	seen, returned := serveWithRequestID("req-42")
+	assert.Equal(t, "req-42", seen)
+	assert.Equal(t, "req-42", returned)
+}
+
+func TestRequestID_GeneratesMissingOrInvalidID(t *testing.T) {
+	seen, returned := serveWithRequestID("")
+	assert.Len(t, seen, 36)
+	assert.Equal(t, seen, returned)
+
+	seen, _ = serveWithRequestID("bad id\nwith newline")
+	assert.Len(t, seen, 36)
+}
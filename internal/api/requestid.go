@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"task-runner-service/pkg/logger"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits the IDs accepted from clients, so that arbitrary
+// input does not end up in logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID takes the request ID from the X-Request-ID header or generates
+// one, returns it in the response and adds it to the logging fields and the
+// span of the request.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := logger.WithRequestID(r.Context(), id)
+		ctx = context.WithValue(ctx, middleware.RequestIDKey, id)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("http.request_id", id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	v1 "task-runner-service/internal/api/v1"
+	"task-runner-service/internal/metrics"
+	"task-runner-service/internal/tracing"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -22,8 +24,22 @@ type Server struct {
 }
 
 func NewServer(cfg *HTTPConfig, handler *v1.Handler) *Server {
+	return newServer(cfg, handler.RegisterRoutes)
+}
+
+// NewHealthServer serves only the health endpoints and metrics, for worker
+// processes.
+func NewHealthServer(cfg *HTTPConfig, handler *v1.Handler) *Server {
+	return newServer(cfg, handler.RegisterHealthRoutes)
+}
+
+func newServer(cfg *HTTPConfig, registerRoutes func(chi.Router)) *Server {
 	r := chi.NewRouter()
-	handler.RegisterRoutes(r)
+	r.Use(tracing.Middleware)
+	r.Use(RequestID)
+	r.Use(metrics.Middleware)
+	r.Handle("/metrics", metrics.Handler())
+	registerRoutes(r)
 
 	srv := &http.Server{
 		Addr:         cfg.Host + ":" + cfg.Port,
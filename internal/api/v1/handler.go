@@ -2,15 +2,21 @@ package v1
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"task-runner-service/internal/domain"
 	"task-runner-service/pkg/logger"
 
-	"github.com/RichardKnop/machinery/v1/tasks"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"go.uber.org/zap"
 )
 
+const IdempotencyKeyHeader = "Idempotency-Key"
+
 func NewHandler(taskService TaskService) *Handler {
 	return &Handler{
 		taskService: taskService,
@@ -19,70 +25,407 @@ func NewHandler(taskService TaskService) *Handler {
 
 func (h *Handler) RegisterRoutes(r chi.Router) {
 	r.Route("/api/v1", func(r chi.Router) {
-		r.Get("/health", h.HealthCheck)
+		h.healthRoutes(r)
 		r.Post("/tasks", h.PostInQueue)
 		r.Get("/tasks/{id}", h.GetStatus)
+		r.Delete("/tasks/{id}", h.CancelTask)
+		r.Post("/tasks/{id}/cancel", h.CancelTask)
+		r.Post("/tasks/{id}/reschedule", h.RescheduleTask)
 		r.Get("/tasks", h.GetFilter)
+		r.Get("/task-types", h.GetTaskTypes)
+		r.Post("/workflows", h.PostWorkflow)
+		r.Get("/workflows/{id}", h.GetWorkflow)
+		r.Post("/schedules", h.PostSchedule)
+		r.Get("/schedules", h.GetSchedules)
+		r.Get("/schedules/{id}", h.GetSchedule)
+		r.Post("/schedules/{id}/pause", h.PauseSchedule)
+		r.Post("/schedules/{id}/resume", h.ResumeSchedule)
+		r.Delete("/schedules/{id}", h.DeleteSchedule)
+		r.Get("/dlq", h.GetDeadLetters)
+		r.Delete("/dlq", h.PurgeDeadLetters)
+		r.Get("/dlq/{id}", h.GetDeadLetter)
+		r.Post("/dlq/{id}/requeue", h.RequeueDeadLetter)
+		r.Delete("/dlq/{id}", h.DeleteDeadLetter)
 	})
 }
 
+// RegisterHealthRoutes registers only the health endpoints, for processes
+// that do not serve the API.
+func (h *Handler) RegisterHealthRoutes(r chi.Router) {
+	r.Route("/api/v1", h.healthRoutes)
+}
+
+func (h *Handler) healthRoutes(r chi.Router) {
+	r.Get("/health", h.HealthCheck)
+	r.Get("/health/live", h.Live)
+	r.Get("/health/ready", h.Ready)
+}
+
+// SetHealthChecker sets what the probes report. Without it the process is
+// always live and ready.
+func (h *Handler) SetHealthChecker(checker HealthChecker) {
+	h.health = checker
+}
+
 func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	logger.Info("Обработка запроса HealthCheck")
+	logger.Debug(r.Context(), "handling HealthCheck request")
 	render.JSON(w, r, map[string]string{"status": "ok"})
 }
 
+func (h *Handler) Live(w http.ResponseWriter, r *http.Request) {
+	report := HealthReport{Status: "ok"}
+	if h.health != nil {
+		report = h.health.Live(r.Context())
+	}
+	renderHealth(w, r, report)
+}
+
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := HealthReport{Status: "ok"}
+	if h.health != nil {
+		report = h.health.Ready(r.Context())
+	}
+	if report.Status == "fail" {
+		logger.Warn(r.Context(), "service is not ready", zap.Any("components", report.Components))
+	}
+	renderHealth(w, r, report)
+}
+
+func renderHealth(w http.ResponseWriter, r *http.Request, report HealthReport) {
+	if report.Status == "fail" {
+		render.Status(r, http.StatusServiceUnavailable)
+	}
+	render.JSON(w, r, report)
+}
+
 func (h *Handler) PostInQueue(w http.ResponseWriter, r *http.Request) {
-	logger.Info("Обработка запроса PostInQueue")
+	logger.Debug(r.Context(), "handling PostInQueue request")
 	var req TaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Errorf("Ошибка разбора запроса PostInQueue: %v", err)
+		logger.Warn(r.Context(), "failed to decode PostInQueue request", zap.Error(err))
 		render.Status(r, http.StatusBadRequest)
 		render.JSON(w, r, map[string]string{"error": "invalid request"})
 		return
 	}
 
 	if req.Name == "" {
-		logger.Errorf("Ошибка: отсутствует имя задачи")
+		logger.Warn(r.Context(), "task name is missing")
 		render.Status(r, http.StatusBadRequest)
 		render.JSON(w, r, map[string]string{"error": "missing task name"})
 		return
 	}
 
-	taskID, err := h.taskService.SendTask(r.Context(), req.Name, req.Args, req.Queue)
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		if req.IdempotencyKey != "" && req.IdempotencyKey != key {
+			logger.Warn(r.Context(), "idempotency keys in header and body differ")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "conflicting idempotency keys"})
+			return
+		}
+		req.IdempotencyKey = key
+	}
+
+	task, err := h.taskService.SendTask(r.Context(), req)
 	if err != nil {
-		logger.Errorf("Ошибка отправки задачи PostInQueue: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": err.Error()})
+		logger.Error(r.Context(), "failed to send task", zap.Error(err))
+		renderError(w, r, err)
 		return
 	}
 
-	logger.Infof("Задача создана: ID=%s, статус=%s", taskID, tasks.StatePending)
+	logger.Info(r.Context(), "task created", zap.String("task_id", task.ID), zap.String("status", task.Status))
 	render.JSON(w, r, TaskResponse{
-		ID:     taskID,
-		Status: tasks.StatePending,
+		ID:          task.ID,
+		Status:      task.Status,
+		ScheduledAt: task.ScheduledAt,
 	})
 }
 
 func (h *Handler) GetStatus(w http.ResponseWriter, r *http.Request) {
-	logger.Info("Обработка запроса GetStatus")
+	logger.Debug(r.Context(), "handling GetStatus request")
 	taskID := chi.URLParam(r, "id")
-	logger.Infof("Получение статуса задачи: ID=%s", taskID)
 
 	task, err := h.taskService.GetTaskStatus(r.Context(), taskID)
 	if err != nil {
-		logger.Errorf("Ошибка получения статуса задачи: %v", err)
+		logger.Warn(r.Context(), "failed to get task status", zap.String("task_id", taskID), zap.Error(err))
 		render.Status(r, http.StatusNotFound)
 		render.JSON(w, r, map[string]string{"error": "task not found"})
 		return
 	}
 
-	logger.Infof("Статус задачи получен: %+v", task)
+	logger.Debug(r.Context(), "task status fetched", zap.String("task_id", task.ID), zap.String("status", task.Status))
 	render.JSON(w, r, task)
 }
 
 func (h *Handler) GetFilter(w http.ResponseWriter, r *http.Request) {
-	logger.Info("Обработка запроса GetFilter")
-	status := r.URL.Query().Get("status")
+	logger.Debug(r.Context(), "handling GetFilter request")
+	query := r.URL.Query()
+	req := TaskListRequest{
+		Statuses:       listParam(query, "status"),
+		Names:          listParam(query, "name"),
+		Queues:         listParam(query, "queue"),
+		CreatedAfter:   query.Get("created_after"),
+		CreatedBefore:  query.Get("created_before"),
+		FinishedAfter:  query.Get("finished_after"),
+		FinishedBefore: query.Get("finished_before"),
+		Error:          query.Get("error"),
+		Order:          query.Get("order"),
+		Cursor:         query.Get("cursor"),
+	}
+	for _, label := range query["label"] {
+		key, value, ok := strings.Cut(label, ":")
+		if !ok || key == "" {
+			logger.Warn(r.Context(), "invalid label filter", zap.String("label", label))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "label filter must be key:value"})
+			return
+		}
+		if req.Labels == nil {
+			req.Labels = make(map[string]string)
+		}
+		req.Labels[key] = value
+	}
+	if limit := query.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			logger.Warn(r.Context(), "invalid limit", zap.String("limit", limit))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "invalid limit"})
+			return
+		}
+		req.Limit = l
+	}
+
+	logger.Debug(r.Context(), "listing tasks", zap.Any("filter", req))
+
+	page, err := h.taskService.GetTasks(r.Context(), req)
+	if err != nil {
+		logger.Warn(r.Context(), "failed to list tasks", zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	meta := map[string]interface{}{
+		"limit": page.Limit,
+		"order": page.Order,
+		"total": page.Total,
+	}
+	if page.TotalApproximate {
+		meta["total_approximate"] = true
+	}
+	if page.NextCursor != "" {
+		meta["next_cursor"] = page.NextCursor
+	}
+	if depth, err := h.taskService.GetQueueDepth(r.Context()); err != nil {
+		logger.Error(r.Context(), "failed to get queue depth", zap.Error(err))
+	} else {
+		meta["queue_depth"] = depth
+	}
+
+	logger.Debug(r.Context(), "tasks listed", zap.Int("count", len(page.Tasks)), zap.Int("total", page.Total))
+	render.JSON(w, r, map[string]interface{}{
+		"tasks": page.Tasks,
+		"meta":  meta,
+	})
+}
+
+func (h *Handler) GetTaskTypes(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling GetTaskTypes request")
+
+	taskTypes, err := h.taskService.GetTaskTypes(r.Context())
+	if err != nil {
+		logger.Error(r.Context(), "failed to get task types", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+		return
+	}
+
+	logger.Debug(r.Context(), "task types listed", zap.Int("count", len(taskTypes)))
+	render.JSON(w, r, map[string]interface{}{
+		"task_types": taskTypes,
+	})
+}
+
+func (h *Handler) CancelTask(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling CancelTask request")
+	taskID := chi.URLParam(r, "id")
+
+	task, err := h.taskService.CancelTask(r.Context(), taskID)
+	if err != nil {
+		logger.Error(r.Context(), "failed to cancel task", zap.String("task_id", taskID), zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Info(r.Context(), "task cancelled", zap.String("task_id", taskID))
+	render.JSON(w, r, task)
+}
+
+func (h *Handler) RescheduleTask(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling RescheduleTask request")
+	taskID := chi.URLParam(r, "id")
+
+	var req RescheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Warn(r.Context(), "failed to decode RescheduleTask request", zap.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "invalid request"})
+		return
+	}
+
+	task, err := h.taskService.RescheduleTask(r.Context(), taskID, req)
+	if err != nil {
+		logger.Error(r.Context(), "failed to reschedule task", zap.String("task_id", taskID), zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Info(r.Context(), "task rescheduled", zap.String("task_id", task.ID), zap.String("scheduled_at", task.ScheduledAt))
+	render.JSON(w, r, task)
+}
+
+func (h *Handler) PostWorkflow(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling PostWorkflow request")
+	var req WorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Warn(r.Context(), "failed to decode PostWorkflow request", zap.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "invalid request"})
+		return
+	}
+
+	workflow, err := h.taskService.SendWorkflow(r.Context(), req)
+	if err != nil {
+		logger.Error(r.Context(), "failed to send workflow", zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Info(r.Context(), "workflow created", zap.String("workflow_id", workflow.ID), zap.String("type", workflow.Type), zap.Int("steps", len(workflow.Steps)))
+	render.JSON(w, r, workflow)
+}
+
+func (h *Handler) GetWorkflow(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling GetWorkflow request")
+	workflowID := chi.URLParam(r, "id")
+
+	workflow, err := h.taskService.GetWorkflow(r.Context(), workflowID)
+	if err != nil {
+		logger.Warn(r.Context(), "failed to get workflow", zap.String("workflow_id", workflowID), zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Debug(r.Context(), "workflow fetched", zap.String("workflow_id", workflow.ID), zap.String("status", workflow.Status))
+	render.JSON(w, r, workflow)
+}
+
+func (h *Handler) PostSchedule(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling PostSchedule request")
+	var req ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Warn(r.Context(), "failed to decode PostSchedule request", zap.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "invalid request"})
+		return
+	}
+
+	if req.Name == "" {
+		logger.Warn(r.Context(), "task name is missing")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "missing task name"})
+		return
+	}
+
+	schedule, err := h.taskService.CreateSchedule(r.Context(), req)
+	if err != nil {
+		logger.Error(r.Context(), "failed to create schedule", zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Info(r.Context(), "schedule created", zap.String("schedule_id", schedule.ID), zap.String("spec", schedule.Spec))
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, schedule)
+}
+
+func (h *Handler) GetSchedules(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling GetSchedules request")
+
+	schedules, err := h.taskService.GetSchedules(r.Context())
+	if err != nil {
+		logger.Error(r.Context(), "failed to list schedules", zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Debug(r.Context(), "schedules listed", zap.Int("count", len(schedules)))
+	render.JSON(w, r, map[string]interface{}{
+		"schedules": schedules,
+	})
+}
+
+func (h *Handler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling GetSchedule request")
+	scheduleID := chi.URLParam(r, "id")
+
+	schedule, err := h.taskService.GetSchedule(r.Context(), scheduleID)
+	if err != nil {
+		logger.Warn(r.Context(), "failed to get schedule", zap.String("schedule_id", scheduleID), zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Debug(r.Context(), "schedule fetched", zap.String("schedule_id", schedule.ID), zap.String("status", schedule.Status))
+	render.JSON(w, r, schedule)
+}
+
+func (h *Handler) PauseSchedule(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling PauseSchedule request")
+	scheduleID := chi.URLParam(r, "id")
+
+	schedule, err := h.taskService.PauseSchedule(r.Context(), scheduleID)
+	if err != nil {
+		logger.Error(r.Context(), "failed to pause schedule", zap.String("schedule_id", scheduleID), zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Info(r.Context(), "schedule paused", zap.String("schedule_id", schedule.ID))
+	render.JSON(w, r, schedule)
+}
+
+func (h *Handler) ResumeSchedule(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling ResumeSchedule request")
+	scheduleID := chi.URLParam(r, "id")
+
+	schedule, err := h.taskService.ResumeSchedule(r.Context(), scheduleID)
+	if err != nil {
+		logger.Error(r.Context(), "failed to resume schedule", zap.String("schedule_id", scheduleID), zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Info(r.Context(), "schedule resumed", zap.String("schedule_id", schedule.ID))
+	render.JSON(w, r, schedule)
+}
+
+func (h *Handler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling DeleteSchedule request")
+	scheduleID := chi.URLParam(r, "id")
+
+	if err := h.taskService.DeleteSchedule(r.Context(), scheduleID); err != nil {
+		logger.Error(r.Context(), "failed to delete schedule", zap.String("schedule_id", scheduleID), zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Info(r.Context(), "schedule deleted", zap.String("schedule_id", scheduleID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling GetDeadLetters request")
+	name := r.URL.Query().Get("name")
 	limitStr := r.URL.Query().Get("limit")
 	offsetStr := r.URL.Query().Get("offset")
 
@@ -96,23 +439,115 @@ func (h *Handler) GetFilter(w http.ResponseWriter, r *http.Request) {
 		offset = o
 	}
 
-	logger.Infof("Получение списка задач: статус=%s, лимит=%d, смещение=%d", status, limit, offset)
-
-	tasks, err := h.taskService.GetTasks(r.Context(), status, limit, offset)
+	letters, total, err := h.taskService.GetDeadLetters(r.Context(), name, limit, offset)
 	if err != nil {
-		logger.Errorf("Ошибка получения списка задач: %v", err)
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": err.Error()})
+		logger.Error(r.Context(), "failed to list dead letters", zap.Error(err))
+		renderError(w, r, err)
 		return
 	}
 
-	logger.Infof("Список задач получен: количество=%d", len(tasks))
+	logger.Debug(r.Context(), "dead letters listed", zap.Int("count", len(letters)), zap.Int("total", total))
 	render.JSON(w, r, map[string]interface{}{
-		"tasks": tasks,
+		"dead_letters": letters,
 		"meta": map[string]int{
 			"limit":  limit,
 			"offset": offset,
-			"total":  len(tasks),
+			"total":  total,
 		},
 	})
 }
+
+func (h *Handler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling GetDeadLetter request")
+	taskID := chi.URLParam(r, "id")
+
+	letter, err := h.taskService.GetDeadLetter(r.Context(), taskID)
+	if err != nil {
+		logger.Warn(r.Context(), "failed to get dead letter", zap.String("task_id", taskID), zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Debug(r.Context(), "dead letter fetched", zap.String("task_id", letter.ID))
+	render.JSON(w, r, letter)
+}
+
+func (h *Handler) RequeueDeadLetter(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling RequeueDeadLetter request")
+	taskID := chi.URLParam(r, "id")
+
+	task, err := h.taskService.RequeueDeadLetter(r.Context(), taskID)
+	if err != nil {
+		logger.Error(r.Context(), "failed to requeue dead letter", zap.String("task_id", taskID), zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Info(r.Context(), "dead letter requeued", zap.String("task_id", task.ID))
+	render.JSON(w, r, task)
+}
+
+func (h *Handler) DeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling DeleteDeadLetter request")
+	taskID := chi.URLParam(r, "id")
+
+	if err := h.taskService.DeleteDeadLetter(r.Context(), taskID); err != nil {
+		logger.Error(r.Context(), "failed to delete dead letter", zap.String("task_id", taskID), zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Info(r.Context(), "dead letter deleted", zap.String("task_id", taskID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) PurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	logger.Debug(r.Context(), "handling PurgeDeadLetters request")
+	name := r.URL.Query().Get("name")
+
+	purged, err := h.taskService.PurgeDeadLetters(r.Context(), name)
+	if err != nil {
+		logger.Error(r.Context(), "failed to purge dead letters", zap.Error(err))
+		renderError(w, r, err)
+		return
+	}
+
+	logger.Info(r.Context(), "dead letters purged", zap.Int("purged", purged), zap.String("name", name))
+	render.JSON(w, r, map[string]int{"purged": purged})
+}
+
+// listParam returns the values of a query parameter given repeatedly or as a
+// comma-separated list.
+func listParam(query url.Values, name string) []string {
+	var values []string
+	for _, param := range query[name] {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func renderError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		render.Status(r, domain.InvalidEntry.Code)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   domain.InvalidEntry.Message,
+			"details": validationErr.Errors,
+		})
+		return
+	}
+
+	var httpErr *domain.HttpError
+	if errors.As(err, &httpErr) {
+		render.Status(r, httpErr.Code)
+		render.JSON(w, r, map[string]string{"error": httpErr.Message})
+		return
+	}
+
+	render.Status(r, domain.InternalServerError.Code)
+	render.JSON(w, r, map[string]string{"error": err.Error()})
+}
@@ -27,8 +27,77 @@ func (m *MockHandler) GetFilter(w http.ResponseWriter, r *http.Request) {
 	m.Called(w, r)
 }
 
+func (m *MockHandler) GetTaskTypes(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) CancelTask(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) RescheduleTask(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) PostWorkflow(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) GetWorkflow(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
 func (m *MockHandler) Initialize() {
 	m.On("PostInQueue", mock.Anything, mock.Anything).Return(nil)
 	m.On("GetStatus", mock.Anything, mock.Anything).Return(nil)
 	m.On("GetFilter", mock.Anything, mock.Anything).Return(nil)
+	m.On("GetTaskTypes", mock.Anything, mock.Anything).Return(nil)
+	m.On("CancelTask", mock.Anything, mock.Anything).Return(nil)
+	m.On("RescheduleTask", mock.Anything, mock.Anything).Return(nil)
+	m.On("PostWorkflow", mock.Anything, mock.Anything).Return(nil)
+	m.On("GetWorkflow", mock.Anything, mock.Anything).Return(nil)
+}
+
+func (m *MockHandler) PostSchedule(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) GetSchedules(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) PauseSchedule(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) ResumeSchedule(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) RequeueDeadLetter(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) DeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
+func (m *MockHandler) PurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
 }
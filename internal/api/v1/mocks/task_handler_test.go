@@ -2,12 +2,14 @@ package v1_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	v1 "task-runner-service/internal/api/v1"
+	"task-runner-service/internal/domain"
 	"task-runner-service/internal/service/mocks"
 
 	"github.com/RichardKnop/machinery/v1/tasks"
@@ -23,21 +25,20 @@ func TestPostInQueue_Success(t *testing.T) {
 	expectedArgs := []tasks.Arg{{Type: "string", Value: "test_value"}}
 	expectedTaskID := "generated_task_id_123"
 
+	requestBody := v1.TaskRequest{
+		Name: expectedTaskName,
+		Args: expectedArgs,
+	}
+
 	mockTaskService.On("SendTask",
 		mock.Anything,
-		expectedTaskName,
-		expectedArgs,
-		"",
-	).Return(expectedTaskID, nil)
+		requestBody,
+	).Return(&v1.TaskResponse{ID: expectedTaskID, Status: tasks.StatePending}, nil)
 
 	handler := v1.NewHandler(mockTaskService)
 	router := chi.NewRouter()
 	handler.RegisterRoutes(router)
 
-	requestBody := v1.TaskRequest{
-		Name: expectedTaskName,
-		Args: expectedArgs,
-	}
 	body, _ := json.Marshal(requestBody)
 
 	req := httptest.NewRequest("POST", "/api/v1/tasks", bytes.NewReader(body))
@@ -99,6 +100,96 @@ func TestPostInQueue_InvalidRequest(t *testing.T) {
 	}
 }
 
+func TestPostInQueue_ValidationError(t *testing.T) {
+	mockTaskService := new(mocks.MockTaskService)
+
+	validationErr := &domain.ValidationError{}
+	validationErr.Add("args[0]", "argument is required")
+	mockTaskService.On("SendTask", mock.Anything, v1.TaskRequest{Name: "echo"}).
+		Return((*v1.TaskResponse)(nil), validationErr)
+
+	handler := v1.NewHandler(mockTaskService)
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req := httptest.NewRequest("POST", "/api/v1/tasks", bytes.NewReader([]byte(`{"name": "echo"}`)))
+	req.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+	var response struct {
+		Error   string              `json:"error"`
+		Details []domain.FieldError `json:"details"`
+	}
+	err := json.NewDecoder(recorder.Body).Decode(&response)
+	assert.NoError(t, err)
+
+	assert.Equal(t, domain.InvalidEntry.Message, response.Error)
+	assert.Equal(t, validationErr.Errors, response.Details)
+	mockTaskService.AssertExpectations(t)
+}
+
+func TestPostInQueue_Scheduled(t *testing.T) {
+	mockTaskService := new(mocks.MockTaskService)
+
+	requestBody := v1.TaskRequest{Name: "echo", Args: []tasks.Arg{{Type: "string", Value: "hi"}}, Delay: "5m"}
+	expected := v1.TaskResponse{ID: "task_1", Status: "SCHEDULED", ScheduledAt: "2025-04-21T16:00:00Z"}
+	mockTaskService.On("SendTask", mock.Anything, requestBody).Return(&expected, nil)
+
+	handler := v1.NewHandler(mockTaskService)
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/api/v1/tasks", bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response v1.TaskResponse
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, expected, response)
+	mockTaskService.AssertExpectations(t)
+}
+
+func TestRescheduleTask(t *testing.T) {
+	testCases := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{"Success", nil, http.StatusOK},
+		{"NotScheduled", domain.TaskNotScheduled, http.StatusConflict},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTaskService := new(mocks.MockTaskService)
+			var response *v1.TaskResponse
+			if tc.serviceErr == nil {
+				response = &v1.TaskResponse{ID: "task_1", Status: "SCHEDULED", ScheduledAt: "2025-04-21T16:00:00Z"}
+			}
+			mockTaskService.On("RescheduleTask", mock.Anything, "task_1", v1.RescheduleRequest{ETA: "2025-04-21T16:00:00Z"}).
+				Return(response, tc.serviceErr)
+
+			handler := v1.NewHandler(mockTaskService)
+			router := chi.NewRouter()
+			handler.RegisterRoutes(router)
+
+			req := httptest.NewRequest("POST", "/api/v1/tasks/task_1/reschedule", bytes.NewReader([]byte(`{"eta": "2025-04-21T16:00:00Z"}`)))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code)
+			mockTaskService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestGetTaskStatus_Success(t *testing.T) {
 	mockTaskService := new(mocks.MockTaskService)
 	taskID := "task_id_123"
@@ -142,16 +233,23 @@ func TestGetTasks_Success(t *testing.T) {
 
 	mockTaskService.On("GetTasks",
 		mock.Anything,
-		"",
-		10,
-		0,
-	).Return(expectedTasks, nil)
+		v1.TaskListRequest{Statuses: []string{"PENDING"}, Limit: 1, Order: "asc", Cursor: "abc"},
+	).Return(&v1.TaskListResponse{
+		Tasks:            expectedTasks,
+		NextCursor:       "def",
+		Limit:            1,
+		Order:            "asc",
+		Total:            7,
+		TotalApproximate: true,
+	}, nil)
+	expectedDepth := map[string]int{"high": 2, "normal": 5, "low": 40}
+	mockTaskService.On("GetQueueDepth", mock.Anything).Return(expectedDepth, nil)
 
 	handler := v1.NewHandler(mockTaskService)
 	router := chi.NewRouter()
 	handler.RegisterRoutes(router)
 
-	req := httptest.NewRequest("GET", "/api/v1/tasks?limit=10", nil)
+	req := httptest.NewRequest("GET", "/api/v1/tasks?status=PENDING&limit=1&order=asc&cursor=abc", nil)
 	recorder := httptest.NewRecorder()
 
 	router.ServeHTTP(recorder, req)
@@ -160,10 +258,424 @@ func TestGetTasks_Success(t *testing.T) {
 
 	var response struct {
 		Tasks []v1.TaskResponse `json:"tasks"`
+		Meta  struct {
+			NextCursor       string         `json:"next_cursor"`
+			Total            int            `json:"total"`
+			TotalApproximate bool           `json:"total_approximate"`
+			QueueDepth       map[string]int `json:"queue_depth"`
+		} `json:"meta"`
 	}
 	err := json.NewDecoder(recorder.Body).Decode(&response)
 	assert.NoError(t, err)
 
 	assert.Equal(t, expectedTasks, response.Tasks)
+	assert.Equal(t, "def", response.Meta.NextCursor)
+	assert.Equal(t, 7, response.Meta.Total)
+	assert.True(t, response.Meta.TotalApproximate)
+	assert.Equal(t, expectedDepth, response.Meta.QueueDepth)
+	mockTaskService.AssertExpectations(t)
+}
+
+func TestGetTasks_Filters(t *testing.T) {
+	mockTaskService := new(mocks.MockTaskService)
+	mockTaskService.On("GetTasks", mock.Anything, v1.TaskListRequest{
+		Statuses:      []string{"FAILURE", "RETRY", "PENDING"},
+		Names:         []string{"echo"},
+		Queues:        []string{"default"},
+		Labels:        map[string]string{"tenant": "acme", "env": "prod"},
+		CreatedAfter:  "2025-04-21T00:00:00Z",
+		FinishedAfter: "2025-04-21T12:00:00Z",
+		Error:         "timeout",
+	}).Return(&v1.TaskListResponse{Tasks: []v1.TaskResponse{}}, nil)
+	mockTaskService.On("GetQueueDepth", mock.Anything).Return(map[string]int{}, nil)
+
+	handler := v1.NewHandler(mockTaskService)
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/api/v1/tasks?status=FAILURE,RETRY&status=PENDING&name=echo&queue=default"+
+		"&label=tenant:acme&label=env:prod&created_after=2025-04-21T00:00:00Z&finished_after=2025-04-21T12:00:00Z&error=timeout", nil)
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
 	mockTaskService.AssertExpectations(t)
 }
+
+func TestGetTasks_InvalidLabel(t *testing.T) {
+	mockTaskService := new(mocks.MockTaskService)
+
+	handler := v1.NewHandler(mockTaskService)
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/api/v1/tasks?label=tenant", nil)
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	mockTaskService.AssertNotCalled(t, "GetTasks", mock.Anything, mock.Anything)
+}
+
+func TestGetTaskTypes_Success(t *testing.T) {
+	mockTaskService := new(mocks.MockTaskService)
+
+	expectedTypes := []v1.TaskTypeResponse{
+		{
+			Name: "echo",
+			Args: []v1.TaskArgSpec{{Name: "message", Type: "string"}},
+		},
+	}
+
+	mockTaskService.On("GetTaskTypes", mock.Anything).Return(expectedTypes, nil)
+
+	handler := v1.NewHandler(mockTaskService)
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/api/v1/task-types", nil)
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response struct {
+		TaskTypes []v1.TaskTypeResponse `json:"task_types"`
+	}
+	err := json.NewDecoder(recorder.Body).Decode(&response)
+	assert.NoError(t, err)
+
+	assert.Equal(t, expectedTypes, response.TaskTypes)
+	mockTaskService.AssertExpectations(t)
+}
+
+func TestCancelTask(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{"Delete", "DELETE", "/api/v1/tasks/task_1", nil, http.StatusOK},
+		{"PostCancel", "POST", "/api/v1/tasks/task_1/cancel", nil, http.StatusOK},
+		{"NotFound", "DELETE", "/api/v1/tasks/task_1", domain.TaskNotFound, http.StatusNotFound},
+		{"AlreadyFinished", "DELETE", "/api/v1/tasks/task_1", domain.TaskFinished, http.StatusConflict},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTaskService := new(mocks.MockTaskService)
+			var response *v1.TaskResponse
+			if tc.serviceErr == nil {
+				response = &v1.TaskResponse{ID: "task_1", Status: "CANCELLED"}
+			}
+			mockTaskService.On("CancelTask", mock.Anything, "task_1").Return(response, tc.serviceErr)
+
+			handler := v1.NewHandler(mockTaskService)
+			router := chi.NewRouter()
+			handler.RegisterRoutes(router)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code)
+			mockTaskService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostWorkflow(t *testing.T) {
+	mockTaskService := new(mocks.MockTaskService)
+
+	requestBody := v1.WorkflowRequest{
+		Type: "chain",
+		Tasks: []v1.WorkflowStep{
+			{Name: "echo", Args: []tasks.Arg{{Type: "string", Value: "hi"}}},
+			{Name: "echo", PassResults: true},
+		},
+	}
+	expected := &v1.WorkflowResponse{
+		ID:     "workflow_1",
+		Type:   "chain",
+		Status: tasks.StatePending,
+		Steps:  []v1.TaskResponse{{ID: "t1", Status: tasks.StatePending}, {ID: "t2", Status: tasks.StatePending}},
+	}
+	mockTaskService.On("SendWorkflow", mock.Anything, requestBody).Return(expected, nil)
+
+	handler := v1.NewHandler(mockTaskService)
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/api/v1/workflows", bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response v1.WorkflowResponse
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, *expected, response)
+	mockTaskService.AssertExpectations(t)
+}
+
+func TestGetWorkflow_NotFound(t *testing.T) {
+	mockTaskService := new(mocks.MockTaskService)
+	mockTaskService.On("GetWorkflow", mock.Anything, "missing").
+		Return((*v1.WorkflowResponse)(nil), domain.WorkflowNotFound)
+
+	handler := v1.NewHandler(mockTaskService)
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/api/v1/workflows/missing", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	mockTaskService.AssertExpectations(t)
+}
+
+func TestPostSchedule(t *testing.T) {
+	mockTaskService := new(mocks.MockTaskService)
+
+	requestBody := v1.ScheduleRequest{
+		Spec: "*/5 * * * *",
+		Name: "echo",
+		Args: []tasks.Arg{{Type: "string", Value: "hi"}},
+	}
+	expected := &v1.ScheduleResponse{
+		ID:     "schedule_1",
+		Spec:   "*/5 * * * *",
+		Name:   "echo",
+		Args:   requestBody.Args,
+		Status: "active",
+	}
+	mockTaskService.On("CreateSchedule", mock.Anything, requestBody).Return(expected, nil)
+
+	handler := v1.NewHandler(mockTaskService)
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/api/v1/schedules", bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+
+	var response v1.ScheduleResponse
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, *expected, response)
+	mockTaskService.AssertExpectations(t)
+}
+
+func TestScheduleActions(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		serviceCall  string
+		serviceErr   error
+		expectedCode int
+	}{
+		{"Get", "GET", "/api/v1/schedules/schedule_1", "GetSchedule", nil, http.StatusOK},
+		{"Pause", "POST", "/api/v1/schedules/schedule_1/pause", "PauseSchedule", nil, http.StatusOK},
+		{"Resume", "POST", "/api/v1/schedules/schedule_1/resume", "ResumeSchedule", nil, http.StatusOK},
+		{"Delete", "DELETE", "/api/v1/schedules/schedule_1", "DeleteSchedule", nil, http.StatusNoContent},
+		{"NotFound", "POST", "/api/v1/schedules/schedule_1/pause", "PauseSchedule", domain.ScheduleNotFound, http.StatusNotFound},
+		{"DeleteNotFound", "DELETE", "/api/v1/schedules/schedule_1", "DeleteSchedule", domain.ScheduleNotFound, http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTaskService := new(mocks.MockTaskService)
+			if tc.serviceCall == "DeleteSchedule" {
+				mockTaskService.On(tc.serviceCall, mock.Anything, "schedule_1").Return(tc.serviceErr)
+			} else {
+				var response *v1.ScheduleResponse
+				if tc.serviceErr == nil {
+					response = &v1.ScheduleResponse{ID: "schedule_1", Status: "active"}
+				}
+				mockTaskService.On(tc.serviceCall, mock.Anything, "schedule_1").Return(response, tc.serviceErr)
+			}
+
+			handler := v1.NewHandler(mockTaskService)
+			router := chi.NewRouter()
+			handler.RegisterRoutes(router)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code)
+			mockTaskService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetDeadLetters(t *testing.T) {
+	mockTaskService := new(mocks.MockTaskService)
+	letters := []v1.DeadLetterResponse{{ID: "task_1", Name: "echo", Error: "boom"}}
+	mockTaskService.On("GetDeadLetters", mock.Anything, "echo", 5, 10).Return(letters, 11, nil)
+
+	handler := v1.NewHandler(mockTaskService)
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/api/v1/dlq?name=echo&limit=5&offset=10", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response struct {
+		DeadLetters []v1.DeadLetterResponse `json:"dead_letters"`
+		Meta        map[string]int          `json:"meta"`
+	}
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Len(t, response.DeadLetters, 1)
+	assert.Equal(t, 11, response.Meta["total"])
+	mockTaskService.AssertExpectations(t)
+}
+
+func TestDeadLetterActions(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		setup        func(m *mocks.MockTaskService)
+		expectedCode int
+	}{
+		{"Requeue", "POST", "/api/v1/dlq/task_1/requeue", func(m *mocks.MockTaskService) {
+			m.On("RequeueDeadLetter", mock.Anything, "task_1").Return(&v1.TaskResponse{ID: "task_1", Status: tasks.StatePending}, nil)
+		}, http.StatusOK},
+		{"RequeueNotFound", "POST", "/api/v1/dlq/task_1/requeue", func(m *mocks.MockTaskService) {
+			m.On("RequeueDeadLetter", mock.Anything, "task_1").Return((*v1.TaskResponse)(nil), domain.DeadLetterNotFound)
+		}, http.StatusNotFound},
+		{"Delete", "DELETE", "/api/v1/dlq/task_1", func(m *mocks.MockTaskService) {
+			m.On("DeleteDeadLetter", mock.Anything, "task_1").Return(nil)
+		}, http.StatusNoContent},
+		{"Purge", "DELETE", "/api/v1/dlq?name=echo", func(m *mocks.MockTaskService) {
+			m.On("PurgeDeadLetters", mock.Anything, "echo").Return(3, nil)
+		}, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTaskService := new(mocks.MockTaskService)
+			tc.setup(mockTaskService)
+
+			handler := v1.NewHandler(mockTaskService)
+			router := chi.NewRouter()
+			handler.RegisterRoutes(router)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code)
+			mockTaskService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostInQueue_IdempotencyKey(t *testing.T) {
+	testCases := []struct {
+		name         string
+		header       string
+		bodyKey      string
+		serviceErr   error
+		expectedCode int
+	}{
+		{"Header", "key-1", "", nil, http.StatusOK},
+		{"Body", "", "key-1", nil, http.StatusOK},
+		{"SameInBoth", "key-1", "key-1", nil, http.StatusOK},
+		{"ConflictingKeys", "key-1", "key-2", nil, http.StatusBadRequest},
+		{"ReusedWithOtherPayload", "key-1", "", domain.IdempotencyConflict, http.StatusConflict},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTaskService := new(mocks.MockTaskService)
+			if tc.expectedCode != http.StatusBadRequest {
+				var response *v1.TaskResponse
+				if tc.serviceErr == nil {
+					response = &v1.TaskResponse{ID: "task_1", Status: tasks.StatePending}
+				}
+				mockTaskService.On("SendTask", mock.Anything, v1.TaskRequest{Name: "echo", IdempotencyKey: "key-1"}).
+					Return(response, tc.serviceErr)
+			}
+
+			handler := v1.NewHandler(mockTaskService)
+			router := chi.NewRouter()
+			handler.RegisterRoutes(router)
+
+			body, _ := json.Marshal(v1.TaskRequest{Name: "echo", IdempotencyKey: tc.bodyKey})
+			req := httptest.NewRequest("POST", "/api/v1/tasks", bytes.NewReader(body))
+			if tc.header != "" {
+				req.Header.Set(v1.IdempotencyKeyHeader, tc.header)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code)
+			mockTaskService.AssertExpectations(t)
+		})
+	}
+}
+
+type fakeHealthChecker struct{ ready v1.HealthReport }
+
+func (c fakeHealthChecker) Live(ctx context.Context) v1.HealthReport {
+	return v1.HealthReport{Status: "ok"}
+}
+
+func (c fakeHealthChecker) Ready(ctx context.Context) v1.HealthReport { return c.ready }
+
+func TestHealthProbes(t *testing.T) {
+	failing := v1.HealthReport{
+		Status: "fail",
+		Components: map[string]v1.ComponentHealth{
+			"redis":   {Status: "fail", Error: "connection refused"},
+			"workers": {Status: "ok", Details: map[string]interface{}{"fresh": 1.0}},
+		},
+	}
+
+	cases := []struct {
+		name       string
+		checker    v1.HealthChecker
+		path       string
+		wantStatus int
+		wantReport v1.HealthReport
+	}{
+		{"NotConfigured", nil, "/api/v1/health/ready", http.StatusOK, v1.HealthReport{Status: "ok"}},
+		{"Ready", fakeHealthChecker{ready: v1.HealthReport{Status: "degraded"}}, "/api/v1/health/ready", http.StatusOK, v1.HealthReport{Status: "degraded"}},
+		{"NotReady", fakeHealthChecker{ready: failing}, "/api/v1/health/ready", http.StatusServiceUnavailable, failing},
+		{"LiveWhileNotReady", fakeHealthChecker{ready: failing}, "/api/v1/health/live", http.StatusOK, v1.HealthReport{Status: "ok"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			handler := v1.NewHandler(new(mocks.MockTaskService))
+			if c.checker != nil {
+				handler.SetHealthChecker(c.checker)
+			}
+			router := chi.NewRouter()
+			handler.RegisterHealthRoutes(router)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", c.path, nil))
+
+			assert.Equal(t, c.wantStatus, recorder.Code)
+			var report v1.HealthReport
+			assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&report))
+			assert.Equal(t, c.wantReport, report)
+		})
+	}
+}
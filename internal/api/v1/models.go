@@ -2,31 +2,253 @@ package v1
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
 
 	"github.com/RichardKnop/machinery/v1/tasks"
 )
 
 type Handler struct {
 	taskService TaskService
+	health      HealthChecker
+}
+
+type HealthChecker interface {
+	Live(ctx context.Context) HealthReport
+	Ready(ctx context.Context) HealthReport
 }
 
 type TaskRequest struct {
-	Name  string      `json:"name"`
-	Args  []tasks.Arg `json:"args"`
-	Queue string      `json:"queue,omitempty"`
+	Name     string       `json:"name"`
+	Args     []tasks.Arg  `json:"args"`
+	Queue    string       `json:"queue,omitempty"`
+	Priority Priority     `json:"priority,omitempty"`
+	ETA      string       `json:"eta,omitempty"`
+	Delay    string       `json:"delay,omitempty"`
+	Retry    *RetryPolicy `json:"retry,omitempty"`
+	// Labels are free-form key/value pairs the task list can be filtered by.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// Priority is a level name such as "high" or a number from 0 to 9. Both
+// JSON strings and numbers are accepted.
+type Priority string
+
+func (p *Priority) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		*p = ""
+	case string:
+		*p = Priority(v)
+	case float64:
+		*p = Priority(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("priority must be a string or a number")
+	}
+	return nil
+}
+
+// RetryPolicy overrides the retry policy of a task type when submitted and
+// describes it in the task type listing. Intervals are Go durations such as
+// "500ms" or "1m".
+type RetryPolicy struct {
+	MaxAttempts int      `json:"max_attempts,omitempty"`
+	Backoff     string   `json:"backoff,omitempty"`
+	Interval    string   `json:"interval,omitempty"`
+	MaxInterval string   `json:"max_interval,omitempty"`
+	Jitter      *float64 `json:"jitter,omitempty"`
+	RetryOn     []string `json:"retry_on,omitempty"`
+}
+
+type RescheduleRequest struct {
+	ETA   string `json:"eta,omitempty"`
+	Delay string `json:"delay,omitempty"`
+}
+
+// TaskListRequest selects a page of the task list. Cursor is the NextCursor
+// of the previous page, empty for the first one. Tasks must match every
+// filter that is set, and any of the values of a list filter. Times are
+// RFC 3339; the After bounds are inclusive and the Before bounds exclusive.
+type TaskListRequest struct {
+	Statuses       []string
+	Names          []string
+	Queues         []string
+	Labels         map[string]string
+	CreatedAfter   string
+	CreatedBefore  string
+	FinishedAfter  string
+	FinishedBefore string
+	Error          string
+
+	Limit  int
+	Order  string
+	Cursor string
+}
+
+type TaskListResponse struct {
+	Tasks            []TaskResponse
+	NextCursor       string
+	Limit            int
+	Order            string
+	Total            int
+	TotalApproximate bool
 }
 
 type TaskService interface {
-	SendTask(ctx context.Context, name string, args []tasks.Arg, queue string) (string, error)
+	SendTask(ctx context.Context, req TaskRequest) (*TaskResponse, error)
 	GetTaskStatus(ctx context.Context, id string) (*TaskResponse, error)
-	GetTasks(ctx context.Context, status string, limit, offset int) ([]TaskResponse, error)
+	GetTasks(ctx context.Context, req TaskListRequest) (*TaskListResponse, error)
+	GetTaskTypes(ctx context.Context) ([]TaskTypeResponse, error)
+	GetQueueDepth(ctx context.Context) (map[string]int, error)
+	CancelTask(ctx context.Context, id string) (*TaskResponse, error)
+	RescheduleTask(ctx context.Context, id string, req RescheduleRequest) (*TaskResponse, error)
+	SendWorkflow(ctx context.Context, req WorkflowRequest) (*WorkflowResponse, error)
+	GetWorkflow(ctx context.Context, id string) (*WorkflowResponse, error)
+	CreateSchedule(ctx context.Context, req ScheduleRequest) (*ScheduleResponse, error)
+	GetSchedules(ctx context.Context) ([]ScheduleResponse, error)
+	GetSchedule(ctx context.Context, id string) (*ScheduleResponse, error)
+	PauseSchedule(ctx context.Context, id string) (*ScheduleResponse, error)
+	ResumeSchedule(ctx context.Context, id string) (*ScheduleResponse, error)
+	DeleteSchedule(ctx context.Context, id string) error
+	GetDeadLetters(ctx context.Context, name string, limit, offset int) ([]DeadLetterResponse, int, error)
+	GetDeadLetter(ctx context.Context, id string) (*DeadLetterResponse, error)
+	RequeueDeadLetter(ctx context.Context, id string) (*TaskResponse, error)
+	DeleteDeadLetter(ctx context.Context, id string) error
+	PurgeDeadLetters(ctx context.Context, name string) (int, error)
 }
 
 type TaskResponse struct {
-	ID        string      `json:"id"`
-	Name      string      `json:"name,omitempty"`
-	Status    string      `json:"status"`
-	Result    interface{} `json:"result,omitempty"`
-	Error     string      `json:"error,omitempty"`
-	CreatedAt string      `json:"created_at,omitempty"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name,omitempty"`
+	Queue       string            `json:"queue,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Status      string            `json:"status"`
+	Result      interface{}       `json:"result,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	CreatedAt   string            `json:"created_at,omitempty"`
+	ScheduledAt string            `json:"scheduled_at,omitempty"`
+	StartedAt   string            `json:"started_at,omitempty"`
+	FinishedAt  string            `json:"finished_at,omitempty"`
+	WorkflowID  string            `json:"workflow_id,omitempty"`
+	Attempt     int               `json:"attempt,omitempty"`
+	MaxAttempts int               `json:"max_attempts,omitempty"`
+	NextRetryAt string            `json:"next_retry_at,omitempty"`
+	Attempts    []AttemptResponse `json:"attempts,omitempty"`
+}
+
+type AttemptResponse struct {
+	Attempt    int    `json:"attempt"`
+	Error      string `json:"error"`
+	ErrorClass string `json:"error_class,omitempty"`
+	FailedAt   string `json:"failed_at"`
+}
+
+type TaskArgSpec struct {
+	Name     string      `json:"name,omitempty"`
+	Type     string      `json:"type"`
+	Required bool        `json:"required"`
+	Default  interface{} `json:"default,omitempty"`
+	Min      *float64    `json:"min,omitempty"`
+	Max      *float64    `json:"max,omitempty"`
+}
+
+type TaskTypeResponse struct {
+	Name  string        `json:"name"`
+	Args  []TaskArgSpec `json:"args"`
+	Retry RetryPolicy   `json:"retry"`
+}
+
+type WorkflowStep struct {
+	Name        string      `json:"name"`
+	Args        []tasks.Arg `json:"args"`
+	PassResults bool        `json:"pass_results,omitempty"`
+}
+
+type WorkflowRequest struct {
+	Type     string         `json:"type"`
+	Tasks    []WorkflowStep `json:"tasks"`
+	Callback *WorkflowStep  `json:"callback,omitempty"`
+	Queue    string         `json:"queue,omitempty"`
+}
+
+type WorkflowProgress struct {
+	Total     int     `json:"total"`
+	Pending   int     `json:"pending"`
+	Running   int     `json:"running"`
+	Succeeded int     `json:"succeeded"`
+	Failed    int     `json:"failed"`
+	Cancelled int     `json:"cancelled"`
+	Percent   float64 `json:"percent"`
+}
+
+type WorkflowResponse struct {
+	ID        string           `json:"id"`
+	Type      string           `json:"type"`
+	Status    string           `json:"status"`
+	Progress  WorkflowProgress `json:"progress"`
+	Steps     []TaskResponse   `json:"steps"`
+	CreatedAt string           `json:"created_at,omitempty"`
+}
+
+type ScheduleRequest struct {
+	Spec   string      `json:"spec"`
+	Name   string      `json:"name"`
+	Args   []tasks.Arg `json:"args"`
+	Queue  string      `json:"queue,omitempty"`
+	Paused bool        `json:"paused,omitempty"`
+}
+
+type ScheduleRunResponse struct {
+	TaskID  string `json:"task_id,omitempty"`
+	FiredAt string `json:"fired_at"`
+	Error   string `json:"error,omitempty"`
+}
+
+type ScheduleResponse struct {
+	ID        string                `json:"id"`
+	Spec      string                `json:"spec"`
+	Name      string                `json:"name"`
+	Args      []tasks.Arg           `json:"args"`
+	Queue     string                `json:"queue,omitempty"`
+	Status    string                `json:"status"`
+	NextRunAt string                `json:"next_run_at,omitempty"`
+	LastRunAt string                `json:"last_run_at,omitempty"`
+	CreatedAt string                `json:"created_at"`
+	UpdatedAt string                `json:"updated_at"`
+	History   []ScheduleRunResponse `json:"history,omitempty"`
+}
+
+type DeadLetterResponse struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Args       []tasks.Arg       `json:"args"`
+	Queue      string            `json:"queue,omitempty"`
+	Error      string            `json:"error"`
+	Attempts   []AttemptResponse `json:"attempts,omitempty"`
+	WorkflowID string            `json:"workflow_id,omitempty"`
+	FailedAt   string            `json:"failed_at"`
+	Signature  tasks.Signature   `json:"signature"`
+}
+
+// HealthReport status is "ok", "degraded" when a non-critical component
+// fails, or "fail".
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
+
+type ComponentHealth struct {
+	Status    string                 `json:"status"`
+	LatencyMs float64                `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
 }
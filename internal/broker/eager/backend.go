@@ -0,0 +1,171 @@
+package eager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/RichardKnop/machinery/v1/common"
+	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// Backend is a machinery result backend kept in memory. States go through
+// JSON as they would through Redis, so results are read back with the same
+// types.
+type Backend struct {
+	common.Backend
+	mu     sync.Mutex
+	states map[string][]byte
+	groups map[string]*group
+}
+
+type group struct {
+	taskUUIDs []string
+	triggered bool
+}
+
+func newBackend() *Backend {
+	return &Backend{
+		Backend: common.NewBackend(new(config.Config)),
+		states:  make(map[string][]byte),
+		groups:  make(map[string]*group),
+	}
+}
+
+func (b *Backend) InitGroup(groupUUID string, taskUUIDs []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.groups[groupUUID] = &group{taskUUIDs: append([]string(nil), taskUUIDs...)}
+	return nil
+}
+
+func (b *Backend) GroupCompleted(groupUUID string, groupTaskCount int) (bool, error) {
+	states, err := b.GroupTaskStates(groupUUID, groupTaskCount)
+	if err != nil {
+		return false, err
+	}
+
+	completed := 0
+	for _, state := range states {
+		if state.IsCompleted() {
+			completed++
+		}
+	}
+	return completed == groupTaskCount, nil
+}
+
+func (b *Backend) GroupTaskStates(groupUUID string, groupTaskCount int) ([]*tasks.TaskState, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g, ok := b.groups[groupUUID]
+	if !ok {
+		return nil, fmt.Errorf("group %s not found", groupUUID)
+	}
+
+	states := make([]*tasks.TaskState, 0, len(g.taskUUIDs))
+	for _, taskUUID := range g.taskUUIDs {
+		state, err := b.getState(taskUUID)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// TriggerChord returns true only to the first caller for the group, so the
+// chord callback is sent once.
+func (b *Backend) TriggerChord(groupUUID string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g, ok := b.groups[groupUUID]
+	if !ok {
+		return false, fmt.Errorf("group %s not found", groupUUID)
+	}
+	if g.triggered {
+		return false, nil
+	}
+	g.triggered = true
+	return true, nil
+}
+
+func (b *Backend) SetStatePending(signature *tasks.Signature) error {
+	return b.setState(tasks.NewPendingTaskState(signature), false)
+}
+
+func (b *Backend) SetStateReceived(signature *tasks.Signature) error {
+	return b.setState(tasks.NewReceivedTaskState(signature), true)
+}
+
+func (b *Backend) SetStateStarted(signature *tasks.Signature) error {
+	return b.setState(tasks.NewStartedTaskState(signature), true)
+}
+
+func (b *Backend) SetStateRetry(signature *tasks.Signature) error {
+	return b.setState(tasks.NewRetryTaskState(signature), true)
+}
+
+func (b *Backend) SetStateSuccess(signature *tasks.Signature, results []*tasks.TaskResult) error {
+	return b.setState(tasks.NewSuccessTaskState(signature, results), true)
+}
+
+func (b *Backend) SetStateFailure(signature *tasks.Signature, err string) error {
+	return b.setState(tasks.NewFailureTaskState(signature, err), true)
+}
+
+func (b *Backend) GetState(taskUUID string) (*tasks.TaskState, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.getState(taskUUID)
+}
+
+func (b *Backend) getState(taskUUID string) (*tasks.TaskState, error) {
+	data, ok := b.states[taskUUID]
+	if !ok {
+		return nil, fmt.Errorf("task %s not found", taskUUID)
+	}
+
+	state := new(tasks.TaskState)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task state: %w", err)
+	}
+	return state, nil
+}
+
+// setState stores state. With merge, the creation time and task name of the
+// previous state are kept, as the Redis backend does past PENDING.
+func (b *Backend) setState(state *tasks.TaskState, merge bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if merge {
+		if previous, err := b.getState(state.TaskUUID); err == nil {
+			state.CreatedAt = previous.CreatedAt
+			state.TaskName = previous.TaskName
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task state: %w", err)
+	}
+	b.states[state.TaskUUID] = data
+	return nil
+}
+
+func (b *Backend) PurgeState(taskUUID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.states, taskUUID)
+	return nil
+}
+
+func (b *Backend) PurgeGroupMeta(groupUUID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.groups, groupUUID)
+	return nil
+}
@@ -0,0 +1,126 @@
+package eager
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/brokers/errs"
+	"github.com/RichardKnop/machinery/v1/brokers/iface"
+	"github.com/RichardKnop/machinery/v1/common"
+	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/log"
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// requeueDelay spaces out the deliveries of a task no consumer of its queue
+// has registered, which would otherwise go round in a busy loop.
+const requeueDelay = time.Second
+
+// Broker is a machinery broker on a Hub.
+type Broker struct {
+	common.Broker
+	hub        *Hub
+	mu         sync.Mutex
+	stopped    bool
+	consuming  sync.WaitGroup
+	processing sync.WaitGroup
+}
+
+// Broker returns a new broker on the hub for a machinery server configured
+// with cnf.
+func (h *Hub) Broker(cnf *config.Config) iface.Broker {
+	return &Broker{Broker: common.NewBroker(cnf), hub: h}
+}
+
+// StartConsuming runs the tasks of the queue of taskProcessor, up to
+// concurrency at a time, until StopConsuming is called. It returns once the
+// running tasks have finished.
+func (b *Broker) StartConsuming(consumerTag string, concurrency int, taskProcessor iface.TaskProcessor) (bool, error) {
+	// A worker may be told to quit before it starts consuming.
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return false, nil
+	}
+	b.consuming.Add(1)
+	b.mu.Unlock()
+	defer b.consuming.Done()
+
+	if concurrency < 1 {
+		concurrency = runtime.NumCPU() * 2
+	}
+	b.Broker.StartConsuming(consumerTag, concurrency, taskProcessor)
+
+	name := taskProcessor.CustomQueue()
+	if name == "" {
+		name = b.GetConfig().DefaultQueue
+	}
+	q := b.hub.queue(name)
+
+	slots := make(chan struct{}, concurrency)
+	errorsChan := make(chan error, 1)
+	for {
+		select {
+		case <-b.GetStopChan():
+			b.processing.Wait()
+			return b.GetRetry(), nil
+		case err := <-errorsChan:
+			b.processing.Wait()
+			return b.GetRetry(), err
+		case slots <- struct{}{}:
+		}
+
+		select {
+		case <-b.GetStopChan():
+			b.processing.Wait()
+			return b.GetRetry(), nil
+		case message := <-q.out:
+			b.processing.Add(1)
+			go func() {
+				defer b.processing.Done()
+				defer func() { <-slots }()
+				if err := b.consumeOne(q, message, taskProcessor); err != nil {
+					select {
+					case errorsChan <- err:
+					default:
+					}
+				}
+			}()
+		}
+	}
+}
+
+func (b *Broker) consumeOne(q *queue, message []byte, taskProcessor iface.TaskProcessor) error {
+	signature, err := decodeSignature(message)
+	if err != nil {
+		return errs.NewErrCouldNotUnmarshalTaskSignature(message, err)
+	}
+
+	// Another consumer of the queue may have registered the task.
+	if !b.IsTaskRegistered(signature.Name) {
+		if signature.IgnoreWhenTaskNotRegistered {
+			return nil
+		}
+		log.INFO.Printf("Task not registered with this worker. Requeuing message: %s", message)
+		time.AfterFunc(requeueDelay, func() { q.push(message) })
+		return nil
+	}
+	return taskProcessor.Process(signature)
+}
+
+// StopConsuming stops taking tasks and waits for the running ones.
+func (b *Broker) StopConsuming() {
+	b.mu.Lock()
+	b.stopped = true
+	b.mu.Unlock()
+	b.Broker.StopConsuming()
+	b.consuming.Wait()
+}
+
+// Publish queues the task on the hub.
+func (b *Broker) Publish(ctx context.Context, signature *tasks.Signature) error {
+	b.AdjustRoutingKey(signature)
+	return b.hub.publish(signature)
+}
@@ -0,0 +1,109 @@
+// Package eager passes tasks between the machinery servers of one process,
+// without a broker or result backend server. Unlike the eager broker of
+// machinery, which runs a task inside the call that sends it, tasks are
+// queued and picked up by the workers consuming their queue, so worker
+// concurrency, delays, retries and the task lifecycle behave as with Redis.
+package eager
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// Hub holds the queues and the task states shared by the machinery servers
+// of the process. Every server gets a broker of its own on the hub, since
+// machinery stops a broker for good when its worker quits.
+type Hub struct {
+	mu      sync.Mutex
+	queues  map[string]*queue
+	backend *Backend
+}
+
+func NewHub() *Hub {
+	return &Hub{queues: make(map[string]*queue), backend: newBackend()}
+}
+
+// Backend returns the result backend of the hub.
+func (h *Hub) Backend() *Backend {
+	return h.backend
+}
+
+func (h *Hub) queue(name string) *queue {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	q, ok := h.queues[name]
+	if !ok {
+		q = newQueue()
+		h.queues[name] = q
+	}
+	return q
+}
+
+// publish queues the signature under its routing key, once its ETA has
+// passed. The signature goes through JSON as it would through Redis, so
+// workers see the same argument types and never share it with the sender.
+func (h *Hub) publish(signature *tasks.Signature) error {
+	message, err := json.Marshal(signature)
+	if err != nil {
+		return err
+	}
+
+	q := h.queue(signature.RoutingKey)
+	if signature.ETA != nil {
+		if delay := time.Until(*signature.ETA); delay > 0 {
+			time.AfterFunc(delay, func() { q.push(message) })
+			return nil
+		}
+	}
+	q.push(message)
+	return nil
+}
+
+func decodeSignature(message []byte) (*tasks.Signature, error) {
+	signature := new(tasks.Signature)
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+	if err := decoder.Decode(signature); err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
+
+// queue is an unbounded FIFO of messages. Consumers receive from out, which
+// hands each message to exactly one of them.
+type queue struct {
+	in  chan []byte
+	out chan []byte
+}
+
+func newQueue() *queue {
+	q := &queue{in: make(chan []byte), out: make(chan []byte)}
+	go q.run()
+	return q
+}
+
+func (q *queue) push(message []byte) {
+	q.in <- message
+}
+
+// run moves messages from in to out for the lifetime of the process.
+func (q *queue) run() {
+	var pending [][]byte
+	for {
+		if len(pending) == 0 {
+			pending = append(pending, <-q.in)
+			continue
+		}
+		select {
+		case message := <-q.in:
+			pending = append(pending, message)
+		case q.out <- pending[0]:
+			pending[0] = nil
+			pending = pending[1:]
+		}
+	}
+}
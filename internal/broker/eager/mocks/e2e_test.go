@@ -0,0 +1,195 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v1 "task-runner-service/internal/api/v1"
+	"task-runner-service/internal/broker/eager"
+	"task-runner-service/internal/registry"
+	"task-runner-service/internal/service"
+	"task-runner-service/internal/storage/memory"
+	"task-runner-service/internal/worker"
+
+	"github.com/RichardKnop/machinery/v1"
+	machineryConfig "github.com/RichardKnop/machinery/v1/config"
+	eagerlock "github.com/RichardKnop/machinery/v1/locks/eager"
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const queueName = "tasks"
+
+// newRunner starts the API and the workers of every priority level on a
+// hub and the memory storage, as cmd/runner does with broker eager, and
+// returns the URL of the API.
+func newRunner(t *testing.T) string {
+	hub := eager.NewHub()
+	newServer := func(queue string) *machinery.Server {
+		cnf := &machineryConfig.Config{DefaultQueue: queue, NoUnixSignals: true}
+		return machinery.NewServerWithBrokerBackendLock(cnf, hub.Broker(cnf), hub.Backend(), eagerlock.New())
+	}
+
+	taskRegistry := registry.New()
+	require.NoError(t, registry.RegisterBuiltins(taskRegistry))
+	require.NoError(t, taskRegistry.Register("fail", func(ctx context.Context, message string) (string, error) {
+		return "", errors.New(message)
+	}, registry.ArgSpec{Name: "message"}))
+
+	store := memory.NewStorage()
+	runnerService := service.NewRunnerService(newServer(queueName), store, taskRegistry)
+	require.NoError(t, runnerService.SetQueues([]service.Queue{{Name: queueName, Concurrency: 3, Enabled: true}}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	executor := worker.NewExecutor(store)
+	go executor.Watch(ctx)
+	lifecycle := worker.NewLifecycle(store, hub.Backend())
+
+	pool := worker.NewPool()
+	handlers := executor.WrapQueue(queueName, taskRegistry.Tasks())
+	for _, level := range service.Priorities {
+		name := service.PriorityQueue(queueName, level)
+		server := newServer(name)
+		require.NoError(t, server.RegisterTasks(handlers))
+		taskWorker := server.NewCustomQueueWorker("worker_"+level, 1, name)
+		lifecycle.Attach(taskWorker)
+		pool.Launch(queueName, taskWorker)
+	}
+
+	router := chi.NewRouter()
+	v1.NewHandler(runnerService).RegisterRoutes(router)
+	api := httptest.NewServer(router)
+
+	t.Cleanup(func() {
+		api.Close()
+		stopCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+		defer stop()
+		assert.NoError(t, pool.Stop(stopCtx))
+		cancel()
+	})
+	return api.URL
+}
+
+func call(t *testing.T, method, url string, body, response interface{}) {
+	var payload bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&payload).Encode(body))
+	}
+	req, err := http.NewRequest(method, url, &payload)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Less(t, resp.StatusCode, 300, "%s %s", method, url)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(response))
+}
+
+func submit(t *testing.T, url string, req v1.TaskRequest) v1.TaskResponse {
+	var task v1.TaskResponse
+	call(t, http.MethodPost, url+"/api/v1/tasks", req, &task)
+	require.NotEmpty(t, task.ID)
+	return task
+}
+
+// waitFor polls the task until it reaches status.
+func waitFor(t *testing.T, url, id, status string) v1.TaskResponse {
+	var task v1.TaskResponse
+	require.Eventually(t, func() bool {
+		call(t, http.MethodGet, url+"/api/v1/tasks/"+id, nil, &task)
+		return task.Status == status
+	}, 5*time.Second, 10*time.Millisecond, "task %s is %s, want %s", id, task.Status, status)
+	return task
+}
+
+func TestEndToEnd_Task(t *testing.T) {
+	url := newRunner(t)
+
+	submitted := submit(t, url, v1.TaskRequest{
+		Name: "sum",
+		Args: []tasks.Arg{{Name: "numbers", Type: "[]int64", Value: []int64{1, 2, 3}}},
+	})
+	assert.Equal(t, tasks.StatePending, submitted.Status)
+
+	task := waitFor(t, url, submitted.ID, tasks.StateSuccess)
+	assert.Equal(t, 6.0, task.Result)
+	assert.NotEmpty(t, task.StartedAt)
+	assert.NotEmpty(t, task.FinishedAt)
+}
+
+func TestEndToEnd_Priorities(t *testing.T) {
+	url := newRunner(t)
+
+	for _, priority := range service.Priorities {
+		submitted := submit(t, url, v1.TaskRequest{
+			Name:     "echo",
+			Args:     []tasks.Arg{{Name: "message", Type: "string", Value: priority}},
+			Priority: v1.Priority(priority),
+		})
+
+		task := waitFor(t, url, submitted.ID, tasks.StateSuccess)
+		assert.Equal(t, priority, task.Result)
+		assert.Equal(t, priority, task.Priority)
+	}
+}
+
+func TestEndToEnd_Delay(t *testing.T) {
+	url := newRunner(t)
+
+	submitted := submit(t, url, v1.TaskRequest{
+		Name:  "echo",
+		Args:  []tasks.Arg{{Name: "message", Type: "string", Value: "later"}},
+		Delay: "300ms",
+	})
+	assert.Equal(t, service.StateScheduled, submitted.Status)
+
+	task := waitFor(t, url, submitted.ID, tasks.StateSuccess)
+	assert.Equal(t, "later", task.Result)
+}
+
+func TestEndToEnd_Failure(t *testing.T) {
+	url := newRunner(t)
+
+	submitted := submit(t, url, v1.TaskRequest{
+		Name: "fail",
+		Args: []tasks.Arg{{Name: "message", Type: "string", Value: "boom"}},
+	})
+
+	task := waitFor(t, url, submitted.ID, tasks.StateFailure)
+	assert.Equal(t, "boom", task.Error)
+
+	var letter v1.DeadLetterResponse
+	call(t, http.MethodGet, url+"/api/v1/dlq/"+submitted.ID, nil, &letter)
+	assert.Equal(t, "fail", letter.Name)
+	assert.Equal(t, "boom", letter.Error)
+}
+
+func TestEndToEnd_Workflow(t *testing.T) {
+	url := newRunner(t)
+
+	step := func(message string) v1.WorkflowStep {
+		return v1.WorkflowStep{Name: "echo", Args: []tasks.Arg{{Name: "message", Type: "string", Value: message}}}
+	}
+	var workflow v1.WorkflowResponse
+	call(t, http.MethodPost, url+"/api/v1/workflows", v1.WorkflowRequest{
+		Type:     "chord",
+		Tasks:    []v1.WorkflowStep{step("a"), step("b")},
+		Callback: &v1.WorkflowStep{Name: "echo", Args: []tasks.Arg{{Name: "message", Type: "string", Value: "done"}}},
+	}, &workflow)
+
+	require.Eventually(t, func() bool {
+		call(t, http.MethodGet, fmt.Sprintf("%s/api/v1/workflows/%s", url, workflow.ID), nil, &workflow)
+		return workflow.Status == tasks.StateSuccess
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, workflow.Steps, 3)
+	assert.Equal(t, "done", workflow.Steps[2].Result)
+}
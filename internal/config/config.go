@@ -1,12 +1,26 @@
 package config
 
 import (
-	"os"
+	"fmt"
 	"time"
+)
 
-	"gopkg.in/yaml.v3"
+// Run modes: the API tier serves HTTP and fires schedules, the worker tier
+// executes tasks, and "all" does both in one process.
+const (
+	ModeAPI    = "api"
+	ModeWorker = "worker"
+	ModeAll    = "all"
 )
 
+func ValidateMode(mode string) error {
+	switch mode {
+	case ModeAPI, ModeWorker, ModeAll:
+		return nil
+	}
+	return fmt.Errorf("invalid mode %q, must be one of %s, %s, %s", mode, ModeAPI, ModeWorker, ModeAll)
+}
+
 type ServerConfig struct {
 	Host         string        `yaml:"host"`
 	Port         string        `yaml:"port"`
@@ -14,35 +28,181 @@ type ServerConfig struct {
 	WriteTimeout time.Duration `yaml:"write_timeout"`
 }
 
+// Redis deployment kinds, see RedisConfig.Mode.
+const (
+	RedisStandalone = "standalone"
+	RedisSentinel   = "sentinel"
+	RedisCluster    = "cluster"
+)
+
+// RedisConfig describes the Redis deployment of the storage, which machinery
+// also uses unless the broker section names its own servers. URL is a
+// redis:// or rediss:// URL or a plain host:port of a single server; it is
+// ignored when Sentinel or Cluster is set. Password and DB override the ones
+// in the URL.
 type RedisConfig struct {
-	URL      string `yaml:"url"`
-	Password string `yaml:"password"`
-	DB       int    `yaml:"db"`
+	URL          string               `yaml:"url"`
+	Password     string               `yaml:"password"`
+	DB           int                  `yaml:"db"`
+	Sentinel     *RedisSentinelConfig `yaml:"sentinel"`
+	Cluster      *RedisClusterConfig  `yaml:"cluster"`
+	TLS          *RedisTLSConfig      `yaml:"tls"`
+	PoolSize     int                  `yaml:"pool_size"`
+	MinIdleConns int                  `yaml:"min_idle_conns"`
+	DialTimeout  time.Duration        `yaml:"dial_timeout"`
+	ReadTimeout  time.Duration        `yaml:"read_timeout"`
+	WriteTimeout time.Duration        `yaml:"write_timeout"`
+	PoolTimeout  time.Duration        `yaml:"pool_timeout"`
 }
 
-type BrokerConfig struct {
-	Broker        string `yaml:"broker"`
-	DefaultQueue  string `yaml:"default_queue"`
-	ResultBackend string `yaml:"result_backend"`
+type RedisSentinelConfig struct {
+	MasterName string   `yaml:"master_name"`
+	Addrs      []string `yaml:"addrs"`
+	Password   string   `yaml:"password"`
 }
 
-type Config struct {
-	Server *ServerConfig `yaml:"server"`
-	Redis  *RedisConfig  `yaml:"redis"`
-	Broker *BrokerConfig `yaml:"broker"`
+type RedisClusterConfig struct {
+	Addrs []string `yaml:"addrs"`
+}
+
+// RedisTLSConfig enables TLS; CertFile and KeyFile are only needed when the
+// servers require client certificates.
+type RedisTLSConfig struct {
+	Enabled            bool   `yaml:"enabled"`
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
 }
 
-func ParseConfig(path string) (*Config, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
+// Mode tells which kind of deployment the config describes.
+func (c RedisConfig) Mode() string {
+	switch {
+	case c.Cluster != nil && len(c.Cluster.Addrs) > 0:
+		return RedisCluster
+	case c.Sentinel != nil && c.Sentinel.MasterName != "":
+		return RedisSentinel
 	}
-	defer f.Close()
+	return RedisStandalone
+}
+
+// Storage backends for task metadata, see Config.Storage. The memory
+// storage lives and dies with the process and suits local development and
+// tests.
+const (
+	StorageRedis    = "redis"
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+// BrokerEager is the broker.broker value that passes tasks to the workers
+// of the same process instead of through a broker server.
+const BrokerEager = "eager"
+
+// PostgresConfig connects the PostgreSQL storage. DSN is a postgres:// URL or
+// a key=value connection string; Schema, when set, holds the tables instead
+// of the default search path.
+type PostgresConfig struct {
+	DSN      string `yaml:"dsn"`
+	Schema   string `yaml:"schema"`
+	MaxConns int    `yaml:"max_conns"`
+	MinConns int    `yaml:"min_conns"`
+}
+
+type BrokerConfig struct {
+	Broker          string         `yaml:"broker"`
+	DefaultQueue    string         `yaml:"default_queue"`
+	ResultBackend   string         `yaml:"result_backend"`
+	Concurrency     int            `yaml:"concurrency"`
+	PriorityWeights map[string]int `yaml:"priority_weights"`
+	DrainTimeout    time.Duration  `yaml:"drain_timeout"`
+}
 
-	target := &Config{}
-	if err := yaml.NewDecoder(f).Decode(target); err != nil {
-		return nil, err
+// DefaultPriorityWeights are the weights of the priority levels that
+// priority_weights leaves out. Every level needs a positive weight, as tasks
+// of a level without worker slots would never run.
+var DefaultPriorityWeights = map[string]int{
+	"high":   6,
+	"normal": 3,
+	"low":    1,
+}
+
+// Weights returns PriorityWeights completed with DefaultPriorityWeights.
+func (b *BrokerConfig) Weights() map[string]int {
+	weights := make(map[string]int, len(DefaultPriorityWeights))
+	for level, weight := range DefaultPriorityWeights {
+		weights[level] = weight
+	}
+	for level, weight := range b.PriorityWeights {
+		weights[level] = weight
 	}
+	return weights
+}
+
+// QueueConfig declares a queue workers consume. Tasks limits the task types
+// that may be sent to it; Enabled defaults to true. RateLimit caps the tasks
+// started per second by each worker process, zero means no limit.
+type QueueConfig struct {
+	Name        string   `yaml:"name"`
+	Concurrency int      `yaml:"concurrency"`
+	RateLimit   float64  `yaml:"rate_limit"`
+	Tasks       []string `yaml:"tasks"`
+	Enabled     *bool    `yaml:"enabled"`
+}
+
+func (q QueueConfig) IsEnabled() bool {
+	return q.Enabled == nil || *q.Enabled
+}
+
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     string        `yaml:"backoff"`
+	Interval    time.Duration `yaml:"interval"`
+	MaxInterval time.Duration `yaml:"max_interval"`
+	Jitter      float64       `yaml:"jitter"`
+	RetryOn     []string      `yaml:"retry_on"`
+}
+
+// TracingConfig selects where spans are exported: "none" (the default),
+// "stdout" or "otlp" over HTTP to Endpoint.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// LogConfig sets the minimum level (debug, info, warn or error) and the
+// output format, "json" (the default) or "console".
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
 
-	return target, nil
+// Config is the configuration of the service. Storage selects where task
+// metadata is kept: "redis" (the default), "postgres" or "memory".
+type Config struct {
+	Mode     string                 `yaml:"mode"`
+	Server   *ServerConfig          `yaml:"server"`
+	Storage  string                 `yaml:"storage"`
+	Redis    *RedisConfig           `yaml:"redis"`
+	Postgres *PostgresConfig        `yaml:"postgres"`
+	Broker   *BrokerConfig          `yaml:"broker"`
+	Queues   []QueueConfig          `yaml:"queues"`
+	Retries  map[string]RetryConfig `yaml:"retries"`
+	Tracing  *TracingConfig         `yaml:"tracing"`
+	Log      *LogConfig             `yaml:"log"`
+}
+
+// EagerBroker tells whether machinery runs in process, without Redis: when
+// broker.broker is eager, or with the memory storage unless broker.broker
+// names a server. The result backend is in memory too unless
+// broker.result_backend names a server.
+func (c *Config) EagerBroker() bool {
+	if c.Broker == nil {
+		return false
+	}
+	return c.Broker.Broker == BrokerEager || (c.Broker.Broker == "" && c.Storage == StorageMemory)
 }
@@ -0,0 +1,517 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultPath is read when no --config flag is given; unlike an explicit
+	// path it may be missing.
+	DefaultPath = "./internal/config/config.yaml"
+
+	EnvPrefix = "TASK_RUNNER_"
+)
+
+// Default returns the configuration used for every key that the file, the
+// environment and the flags leave unset.
+func Default() *Config {
+	return &Config{
+		Mode:    ModeAll,
+		Storage: StorageRedis,
+		Server: &ServerConfig{
+			Host:         "0.0.0.0",
+			Port:         "8080",
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		},
+		Redis: &RedisConfig{
+			URL: "redis://localhost:6379/0",
+		},
+		Broker: &BrokerConfig{
+			DefaultQueue: "machinery_tasks",
+			Concurrency:  10,
+			DrainTimeout: 30 * time.Second,
+		},
+		Log: &LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
+
+// Load builds the configuration from, in increasing precedence, Default,
+// the YAML file given by --config, TASK_RUNNER_* environment variables and
+// the command line flags in args. Every scalar key has a variable and a
+// flag named after its path: server.port is TASK_RUNNER_SERVER_PORT and
+// --server.port. Lists are comma-separated and maps are key=value pairs.
+func Load(name string, args []string) (*Config, error) {
+	parsed, err := parseArgs(name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if err := decodeFile(cfg, parsed.path, parsed.explicit); err != nil {
+		return nil, err
+	}
+
+	keys := Keys()
+
+	for _, key := range keys {
+		env := EnvName(key)
+		if value, ok := os.LookupEnv(env); ok {
+			if err := Set(cfg, key, value); err != nil {
+				return nil, fmt.Errorf("%s: %w", env, err)
+			}
+		}
+	}
+	for _, key := range keys {
+		if value, ok := parsed.overrides[key]; ok {
+			if err := Set(cfg, key, value); err != nil {
+				return nil, fmt.Errorf("--%s: %w", key, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+type arguments struct {
+	path      string
+	explicit  bool
+	overrides map[string]string
+}
+
+func parseArgs(name string, args []string) (*arguments, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	path := fs.String("config", DefaultPath, "path of the YAML config file")
+
+	parsed := &arguments{overrides: make(map[string]string)}
+	for _, key := range Keys() {
+		fs.Func(key, "overrides "+key+" of the config file", func(value string) error {
+			parsed.overrides[key] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	parsed.path = *path
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			parsed.explicit = true
+		}
+	})
+	return parsed, nil
+}
+
+func decodeFile(cfg *Config, path string, explicit bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// EnvName returns the environment variable overriding key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// Keys returns the paths of all keys that can be set from the environment
+// and flags, in the order of the config file. Lists of sections such as
+// queues are left to the file.
+func Keys() []string {
+	var keys []string
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key := prefix + yamlName(field)
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr && fieldType.Elem().Kind() == reflect.Struct {
+				walk(fieldType.Elem(), key+".")
+				continue
+			}
+			if settable(fieldType) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+	return keys
+}
+
+// Set parses value into the key of cfg, allocating the sections on the way.
+func Set(cfg *Config, key, value string) error {
+	field, err := lookup(cfg, key)
+	if err != nil {
+		return err
+	}
+	if err := parseValue(field, value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+func lookup(cfg *Config, key string) (reflect.Value, error) {
+	value := reflect.ValueOf(cfg).Elem()
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		field, ok := fieldByYAMLName(value, part)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("unknown config key %q", key)
+		}
+		if i == len(parts)-1 {
+			if !settable(field.Type()) {
+				return reflect.Value{}, fmt.Errorf("config key %q can only be set in the config file", key)
+			}
+			return field, nil
+		}
+		if field.Kind() != reflect.Ptr || field.Type().Elem().Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("unknown config key %q", key)
+		}
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		value = field.Elem()
+	}
+	return reflect.Value{}, fmt.Errorf("unknown config key %q", key)
+}
+
+func fieldByYAMLName(value reflect.Value, name string) (reflect.Value, bool) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if yamlName(t.Field(i)) == name {
+			return value.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func settable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Float64:
+		return true
+	case reflect.Int64:
+		return t == durationType
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.Int
+	}
+	return false
+}
+
+func parseValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(parsed)
+	case reflect.Int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(int64(parsed))
+	case reflect.Int64:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		field.SetInt(int64(parsed))
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(parsed)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		items := make(map[string]int)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(item, "=")
+			parsed, err := strconv.Atoi(strings.TrimSpace(v))
+			if !ok || err != nil {
+				return fmt.Errorf("invalid entry %q, must be key=number", item)
+			}
+			items[strings.TrimSpace(k)] = parsed
+		}
+		field.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration that is safe to log, with
+// passwords removed from the credentials and from URLs.
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	if c.Redis != nil {
+		redis := *c.Redis
+		redis.URL = redactURL(redis.URL)
+		redis.Password = redact(redis.Password)
+		if redis.Sentinel != nil {
+			sentinel := *redis.Sentinel
+			sentinel.Password = redact(sentinel.Password)
+			redis.Sentinel = &sentinel
+		}
+		redacted.Redis = &redis
+	}
+	if c.Postgres != nil {
+		postgres := *c.Postgres
+		postgres.DSN = redactDSN(postgres.DSN)
+		redacted.Postgres = &postgres
+	}
+	if c.Broker != nil {
+		broker := *c.Broker
+		broker.Broker = redactURL(broker.Broker)
+		broker.ResultBackend = redactURL(broker.ResultBackend)
+		redacted.Broker = &broker
+	}
+	return &redacted
+}
+
+const redactedValue = "REDACTED"
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactURL hides the password of a URL, or the user part of a redis URL,
+// which machinery takes for the password when there is no other. Lists of
+// addresses that do not parse as a URL are redacted as a whole.
+func redactURL(raw string) string {
+	if raw == "" || !strings.Contains(raw, "@") {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return redactedValue
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redactedValue)
+	} else {
+		u.User = url.User(redactedValue)
+	}
+	return u.String()
+}
+
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactDSN hides the password of a postgres:// URL or of a key=value
+// connection string.
+func redactDSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		return redactURL(dsn)
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+redactedValue)
+}
+
+// Validate checks the values of the configuration and reports every invalid
+// key at once.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if err := ValidateMode(c.Mode); err != nil {
+		invalid("mode", "must be one of %s, %s, %s", ModeAPI, ModeWorker, ModeAll)
+	}
+
+	if c.Server == nil {
+		invalid("server", "is required")
+	} else {
+		if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+			invalid("server.port", "must be a port number, got %q", c.Server.Port)
+		}
+		if c.Server.ReadTimeout < 0 {
+			invalid("server.read_timeout", "must not be negative")
+		}
+		if c.Server.WriteTimeout < 0 {
+			invalid("server.write_timeout", "must not be negative")
+		}
+	}
+
+	if c.Redis == nil {
+		invalid("redis", "is required")
+	} else {
+		r := c.Redis
+		if r.DB < 0 {
+			invalid("redis.db", "must not be negative")
+		}
+		if r.PoolSize < 0 {
+			invalid("redis.pool_size", "must not be negative")
+		}
+		if r.MinIdleConns < 0 {
+			invalid("redis.min_idle_conns", "must not be negative")
+		}
+		if r.DialTimeout < 0 || r.ReadTimeout < 0 || r.WriteTimeout < 0 || r.PoolTimeout < 0 {
+			invalid("redis.*_timeout", "must not be negative")
+		}
+		if r.Mode() == RedisStandalone && strings.Contains(r.URL, "://") {
+			if u, err := url.Parse(r.URL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+				invalid("redis.url", "must be a redis:// or rediss:// URL or host:port")
+			}
+		}
+		if r.Sentinel != nil && r.Sentinel.MasterName != "" {
+			if len(r.Sentinel.Addrs) == 0 {
+				invalid("redis.sentinel.addrs", "is required with redis.sentinel.master_name")
+			}
+			if r.Cluster != nil && len(r.Cluster.Addrs) > 0 {
+				invalid("redis.cluster.addrs", "cannot be combined with redis.sentinel")
+			}
+		}
+		if r.TLS != nil && (r.TLS.CertFile == "") != (r.TLS.KeyFile == "") {
+			invalid("redis.tls.key_file", "must be set together with redis.tls.cert_file")
+		}
+	}
+
+	switch c.Storage {
+	case StorageRedis:
+	case StoragePostgres:
+		if c.Postgres == nil || c.Postgres.DSN == "" {
+			invalid("postgres.dsn", "is required with storage %s", StoragePostgres)
+		}
+	case StorageMemory:
+		// Another process would see neither the tasks nor the workers.
+		if c.Mode != ModeAll {
+			invalid("storage", "%s requires mode %s", StorageMemory, ModeAll)
+		}
+	default:
+		invalid("storage", "must be %s, %s or %s", StorageRedis, StoragePostgres, StorageMemory)
+	}
+	if c.Postgres != nil {
+		if c.Postgres.MaxConns < 0 {
+			invalid("postgres.max_conns", "must not be negative")
+		}
+		if c.Postgres.MinConns < 0 {
+			invalid("postgres.min_conns", "must not be negative")
+		}
+	}
+
+	if c.Broker == nil {
+		invalid("broker", "is required")
+	} else {
+		if c.Broker.DefaultQueue == "" {
+			invalid("broker.default_queue", "is required")
+		}
+		if c.Broker.Broker == BrokerEager && c.Mode != ModeAll {
+			invalid("broker.broker", "%s requires mode %s", BrokerEager, ModeAll)
+		}
+		if c.Broker.Concurrency <= 0 {
+			invalid("broker.concurrency", "must be positive")
+		}
+		if c.Broker.DrainTimeout <= 0 {
+			invalid("broker.drain_timeout", "must be positive")
+		}
+		levels := make([]string, 0, len(c.Broker.PriorityWeights))
+		for level := range c.Broker.PriorityWeights {
+			levels = append(levels, level)
+		}
+		sort.Strings(levels)
+		for _, level := range levels {
+			if _, ok := DefaultPriorityWeights[level]; !ok {
+				invalid("broker.priority_weights."+level, "unknown priority level, must be high, normal or low")
+			} else if c.Broker.PriorityWeights[level] <= 0 {
+				invalid("broker.priority_weights."+level, "must be positive")
+			}
+		}
+	}
+
+	for i, queue := range c.Queues {
+		if queue.Name == "" {
+			invalid(fmt.Sprintf("queues[%d].name", i), "is required")
+		}
+		if queue.Concurrency < 0 {
+			invalid(fmt.Sprintf("queues[%d].concurrency", i), "must not be negative")
+		}
+		if queue.RateLimit < 0 {
+			invalid(fmt.Sprintf("queues[%d].rate_limit", i), "must not be negative")
+		}
+	}
+
+	if c.Tracing != nil {
+		switch c.Tracing.Exporter {
+		case "", "none", "stdout", "otlp":
+		default:
+			invalid("tracing.exporter", "must be none, stdout or otlp")
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			invalid("tracing.sample_ratio", "must be between 0 and 1")
+		}
+	}
+
+	if c.Log != nil {
+		switch c.Log.Level {
+		case "", "debug", "info", "warn", "error":
+		default:
+			invalid("log.level", "must be debug, info, warn or error")
+		}
+		switch c.Log.Format {
+		case "", "json", "console":
+		default:
+			invalid("log.format", "must be json or console")
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
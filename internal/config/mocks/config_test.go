@@ -0,0 +1,209 @@
+package mocks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Layers(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  port: "9090"
+redis:
+  url: "redis://file:6379/0"
+  db: 1
+broker:
+  concurrency: 4
+`)
+	t.Setenv("TASK_RUNNER_REDIS_URL", "redis://env:6379/0")
+	t.Setenv("TASK_RUNNER_BROKER_CONCURRENCY", "8")
+	t.Setenv("TASK_RUNNER_REDIS_SENTINEL_ADDRS", "s1:26379, s2:26379")
+
+	cfg, err := config.Load("runner", []string{"--config", path, "--broker.concurrency=16", "--mode", "worker"})
+	assert.NoError(t, err)
+
+	// Defaults fill what nothing else sets.
+	assert.Equal(t, "0.0.0.0", cfg.Server.Host)
+	assert.Equal(t, 30*time.Second, cfg.Broker.DrainTimeout)
+	// The file overrides the defaults.
+	assert.Equal(t, "9090", cfg.Server.Port)
+	assert.Equal(t, 1, cfg.Redis.DB)
+	// The environment overrides the file.
+	assert.Equal(t, "redis://env:6379/0", cfg.Redis.URL)
+	assert.Equal(t, []string{"s1:26379", "s2:26379"}, cfg.Redis.Sentinel.Addrs)
+	// Flags override the environment.
+	assert.Equal(t, 16, cfg.Broker.Concurrency)
+	assert.Equal(t, config.ModeWorker, cfg.Mode)
+}
+
+func TestLoad_DefaultPathMayBeMissing(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	cfg, err := config.Load("runner", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+
+	_, err = config.Load("runner", []string{"--config", "missing.yaml"})
+	assert.Error(t, err)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	cases := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		wantErr []string
+	}{
+		{
+			name:    "UnknownFileKey",
+			file:    "server:\n  prot: \"8080\"\n",
+			wantErr: []string{"prot"},
+		},
+		{
+			name:    "InvalidEnv",
+			env:     map[string]string{"TASK_RUNNER_REDIS_DB": "one"},
+			wantErr: []string{"TASK_RUNNER_REDIS_DB", "redis.db"},
+		},
+		{
+			name:    "InvalidFlag",
+			args:    []string{"--server.read_timeout=soon"},
+			wantErr: []string{"server.read_timeout", "invalid duration"},
+		},
+		{
+			name:    "UnknownFlag",
+			args:    []string{"--server.prot=8080"},
+			wantErr: []string{"server.prot"},
+		},
+		{
+			name:    "Validation",
+			file:    "mode: both\nserver:\n  port: \"http\"\nbroker:\n  concurrency: 0\n",
+			wantErr: []string{"mode:", "server.port:", "broker.concurrency:"},
+		},
+		{
+			name:    "PostgresWithoutDSN",
+			file:    "storage: postgres\n",
+			wantErr: []string{"postgres.dsn:"},
+		},
+		{
+			name:    "PriorityWeights",
+			file:    "broker:\n  priority_weights:\n    high: 1\n    normal: 0\n    urgent: 5\n",
+			wantErr: []string{"broker.priority_weights.normal: must be positive", "broker.priority_weights.urgent: unknown priority level"},
+		},
+		{
+			name:    "InProcessWithSplitMode",
+			args:    []string{"--mode=api", "--storage=memory", "--broker.broker=eager"},
+			wantErr: []string{"storage:", "broker.broker:"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for key, value := range c.env {
+				t.Setenv(key, value)
+			}
+			args := append([]string{"--config", writeConfig(t, c.file)}, c.args...)
+
+			_, err := config.Load("runner", args)
+			if assert.Error(t, err) {
+				for _, want := range c.wantErr {
+					assert.Contains(t, err.Error(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_PartialPriorityWeights(t *testing.T) {
+	path := writeConfig(t, "broker:\n  priority_weights:\n    high: 2\n    normal: 2\n    low: 2\n")
+	t.Setenv("TASK_RUNNER_BROKER_PRIORITY_WEIGHTS", "high=8")
+
+	cfg, err := config.Load("runner", []string{"--config", path})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"high": 8}, cfg.Broker.PriorityWeights)
+	// Levels the override leaves out keep their default weight.
+	assert.Equal(t, map[string]int{"high": 8, "normal": 3, "low": 1}, cfg.Broker.Weights())
+}
+
+func TestKeys(t *testing.T) {
+	keys := config.Keys()
+	assert.Contains(t, keys, "server.port")
+	assert.Contains(t, keys, "redis.tls.ca_file")
+	assert.Contains(t, keys, "broker.priority_weights")
+	assert.NotContains(t, keys, "queues")
+	assert.Equal(t, "TASK_RUNNER_REDIS_TLS_CA_FILE", config.EnvName("redis.tls.ca_file"))
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := config.Default()
+	cfg.Redis.URL = "redis://:url-secret@redis:6379/0"
+	cfg.Redis.Password = "secret"
+	cfg.Redis.Sentinel = &config.RedisSentinelConfig{MasterName: "mymaster", Password: "sentinel-secret"}
+	cfg.Broker.Broker = "redis://broker-secret@redis:6379"
+	cfg.Broker.ResultBackend = "redis://redis:6379"
+
+	redacted := cfg.Redacted()
+	assert.Equal(t, "redis://:REDACTED@redis:6379/0", redacted.Redis.URL)
+	assert.Equal(t, "REDACTED", redacted.Redis.Password)
+	assert.Equal(t, "REDACTED", redacted.Redis.Sentinel.Password)
+	assert.Equal(t, "redis://REDACTED@redis:6379", redacted.Broker.Broker)
+	assert.Equal(t, "redis://redis:6379", redacted.Broker.ResultBackend)
+
+	// The loaded configuration keeps the secrets.
+	assert.Equal(t, "secret", cfg.Redis.Password)
+	assert.Equal(t, "sentinel-secret", cfg.Redis.Sentinel.Password)
+}
+
+func TestRedacted_PostgresDSN(t *testing.T) {
+	cases := map[string]string{
+		"postgres://runner:secret@db:5432/tasks":           "postgres://runner:REDACTED@db:5432/tasks",
+		"host=db user=runner password=secret dbname=tasks": "host=db user=runner password=REDACTED dbname=tasks",
+		"host=db password = 'se cret' dbname=tasks":        "host=db password = REDACTED dbname=tasks",
+		"host=db user=runner dbname=tasks":                 "host=db user=runner dbname=tasks",
+	}
+	for dsn, want := range cases {
+		cfg := config.Default()
+		cfg.Postgres = &config.PostgresConfig{DSN: dsn}
+		assert.Equal(t, want, cfg.Redacted().Postgres.DSN)
+		assert.Equal(t, dsn, cfg.Postgres.DSN)
+	}
+}
+
+func TestEagerBroker(t *testing.T) {
+	cases := []struct {
+		name    string
+		storage string
+		broker  string
+		want    bool
+	}{
+		{"Redis", config.StorageRedis, "", false},
+		{"Eager", config.StorageRedis, config.BrokerEager, true},
+		{"Memory", config.StorageMemory, "", true},
+		{"MemoryWithBroker", config.StorageMemory, "redis://redis:6379", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Storage = c.storage
+			cfg.Broker.Broker = c.broker
+			assert.Equal(t, c.want, cfg.EagerBroker())
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package mocks
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const reloadConfig = `
+server:
+  port: "8080"
+broker:
+  concurrency: 4
+queues:
+  - name: "default"
+    concurrency: 4
+  - name: "slow"
+    concurrency: 1
+    tasks: ["sleep"]
+log:
+  level: "info"
+`
+
+func TestReconcile(t *testing.T) {
+	current, err := config.Load("runner", []string{"--config", writeConfig(t, reloadConfig)})
+	assert.NoError(t, err)
+
+	loaded, err := config.Load("runner", []string{"--config", writeConfig(t, `
+server:
+  port: "9090"
+broker:
+  concurrency: 8
+queues:
+  - name: "default"
+    concurrency: 6
+    rate_limit: 2.5
+  - name: "slow"
+    concurrency: 1
+    tasks: ["sleep", "echo"]
+retries:
+  sleep:
+    max_attempts: 5
+log:
+  level: "debug"
+`)})
+	assert.NoError(t, err)
+
+	next, applied, rejected := config.Reconcile(current, loaded)
+	assert.ElementsMatch(t, []string{
+		"broker.concurrency",
+		"log.level",
+		"retries",
+		"queues.default.concurrency",
+		"queues.default.rate_limit",
+	}, applied)
+	assert.ElementsMatch(t, []string{"server.port", "queues"}, rejected)
+
+	assert.Equal(t, "debug", next.Log.Level)
+	assert.Equal(t, 8, next.Broker.Concurrency)
+	assert.Equal(t, 5, next.Retries["sleep"].MaxAttempts)
+	assert.Equal(t, 6, next.Queues[0].Concurrency)
+	assert.Equal(t, 2.5, next.Queues[0].RateLimit)
+	// Rejected changes keep the running values.
+	assert.Equal(t, "8080", next.Server.Port)
+	assert.Equal(t, []string{"sleep"}, next.Queues[1].Tasks)
+
+	// The running configuration is left alone.
+	assert.Equal(t, "info", current.Log.Level)
+	assert.Equal(t, 4, current.Broker.Concurrency)
+	assert.Equal(t, 4, current.Queues[0].Concurrency)
+}
+
+func TestReconcile_Unchanged(t *testing.T) {
+	args := []string{"--config", writeConfig(t, reloadConfig)}
+	current, err := config.Load("runner", args)
+	assert.NoError(t, err)
+	loaded, err := config.Load("runner", args)
+	assert.NoError(t, err)
+
+	_, applied, rejected := config.Reconcile(current, loaded)
+	assert.Empty(t, applied)
+	assert.Empty(t, rejected)
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	path := writeConfig(t, reloadConfig)
+	args := []string{"--config", path}
+	current, err := config.Load("runner", args)
+	assert.NoError(t, err)
+
+	applied := make(chan *config.Config, 1)
+	watcher, err := config.NewWatcher("runner", args, current, func(_ context.Context, cfg *config.Config, _ []string) {
+		applied <- cfg
+	})
+	assert.NoError(t, err)
+
+	// An invalid file keeps the running configuration.
+	assert.NoError(t, os.WriteFile(path, []byte("broker:\n  concurrency: -1\n"), 0o600))
+	assert.Error(t, watcher.Reload(context.Background()))
+	assert.Same(t, current, watcher.Current())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+	// Give the watcher time to subscribe before the file changes.
+	time.Sleep(100 * time.Millisecond)
+
+	changed := []byte(reloadConfig[:len(reloadConfig)-len("  level: \"info\"\n")] + "  level: \"warn\"\n")
+	assert.NoError(t, os.WriteFile(path, changed, 0o600))
+
+	select {
+	case cfg := <-applied:
+		assert.Equal(t, "warn", cfg.Log.Level)
+		assert.Same(t, cfg, watcher.Current())
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change was not applied")
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// liveKeys can change while the process runs. Of the queues section only the
+// concurrency and rate_limit of existing queues can, and the retries section
+// is replaced as a whole; every other change needs a restart.
+var liveKeys = map[string]bool{
+	"log.level":               true,
+	"broker.concurrency":      true,
+	"broker.priority_weights": true,
+}
+
+// Reconcile compares a freshly loaded configuration with the running one and
+// returns the configuration to run with: current, with the keys that can
+// change live taken from loaded. applied lists the keys taken, rejected the
+// keys that changed but only take effect after a restart.
+func Reconcile(current, loaded *Config) (next *Config, applied, rejected []string) {
+	next = current.clone()
+
+	for _, key := range Keys() {
+		value := get(loaded, key)
+		if reflect.DeepEqual(get(current, key).Interface(), value.Interface()) {
+			continue
+		}
+		if !liveKeys[key] {
+			rejected = append(rejected, key)
+			continue
+		}
+		field, _ := lookup(next, key)
+		field.Set(value)
+		applied = append(applied, key)
+	}
+
+	if (len(current.Retries) > 0 || len(loaded.Retries) > 0) && !reflect.DeepEqual(current.Retries, loaded.Retries) {
+		next.Retries = loaded.Retries
+		applied = append(applied, "retries")
+	}
+
+	if !sameQueues(current.Queues, loaded.Queues) {
+		rejected = append(rejected, "queues")
+	}
+	for i, queue := range next.Queues {
+		for _, loadedQueue := range loaded.Queues {
+			if loadedQueue.Name != queue.Name {
+				continue
+			}
+			if loadedQueue.Concurrency != queue.Concurrency {
+				next.Queues[i].Concurrency = loadedQueue.Concurrency
+				applied = append(applied, "queues."+queue.Name+".concurrency")
+			}
+			if loadedQueue.RateLimit != queue.RateLimit {
+				next.Queues[i].RateLimit = loadedQueue.RateLimit
+				applied = append(applied, "queues."+queue.Name+".rate_limit")
+			}
+			break
+		}
+	}
+	return next, applied, rejected
+}
+
+// clone copies the sections of c so that setting keys on the copy leaves c
+// unchanged.
+func (c *Config) clone() *Config {
+	cloned := *c
+	value := reflect.ValueOf(&cloned).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if field.Kind() == reflect.Ptr && !field.IsNil() {
+			section := reflect.New(field.Type().Elem())
+			section.Elem().Set(field.Elem())
+			field.Set(section)
+		}
+	}
+	cloned.Queues = append([]QueueConfig(nil), c.Queues...)
+	return &cloned
+}
+
+// get returns the value of key, treating missing sections as empty.
+func get(cfg *Config, key string) reflect.Value {
+	value := reflect.ValueOf(cfg).Elem()
+	for _, part := range strings.Split(key, ".") {
+		field, _ := fieldByYAMLName(value, part)
+		if field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct {
+			if field.IsNil() {
+				field = reflect.New(field.Type().Elem())
+			}
+			field = field.Elem()
+		}
+		value = field
+	}
+	return value
+}
+
+// sameQueues reports whether the queues are declared in the same order with
+// the same tasks, ignoring what can change live.
+func sameQueues(a, b []QueueConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].IsEnabled() != b[i].IsEnabled() || !reflect.DeepEqual(a[i].Tasks, b[i].Tasks) {
+			return false
+		}
+	}
+	return true
+}
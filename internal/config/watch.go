@@ -0,0 +1,134 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"task-runner-service/pkg/logger"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// reloadDelay lets editors finish writing the file before it is read.
+const reloadDelay = 200 * time.Millisecond
+
+// Watcher loads the configuration again when its file changes or the process
+// receives SIGHUP, and passes what may change live to apply. Changes to other
+// keys are logged and otherwise ignored until the next restart.
+type Watcher struct {
+	name  string
+	args  []string
+	path  string
+	apply func(ctx context.Context, cfg *Config, applied []string)
+
+	mu      sync.Mutex
+	current *Config
+}
+
+// NewWatcher watches the configuration that Load built from the same name and
+// args, current being the result.
+func NewWatcher(name string, args []string, current *Config, apply func(ctx context.Context, cfg *Config, applied []string)) (*Watcher, error) {
+	parsed, err := parseArgs(name, args)
+	if err != nil {
+		return nil, err
+	}
+	path, err := filepath.Abs(parsed.path)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{name: name, args: args, path: path, apply: apply, current: current}, nil
+}
+
+// Current returns the configuration in effect.
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Reload loads the configuration and applies the keys that can change live.
+// When it fails the running configuration is kept.
+func (w *Watcher) Reload(ctx context.Context) error {
+	loaded, err := Load(w.name, w.args)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	next, applied, rejected := Reconcile(w.current, loaded)
+	for _, key := range rejected {
+		logger.Warn(ctx, "config change ignored, the key cannot change while running and needs a restart", zap.String("key", key))
+	}
+	if len(applied) == 0 {
+		logger.Info(ctx, "config reloaded, nothing to apply")
+		return nil
+	}
+
+	w.current = next
+	w.apply(ctx, next, applied)
+	logger.Info(ctx, "config reloaded", zap.Strings("applied", applied))
+	return nil
+}
+
+// Run reloads the configuration on changes until ctx is done. Without a
+// watchable file it still reloads on SIGHUP.
+func (w *Watcher) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+	)
+	// The directory is watched because editors replace the file rather than
+	// write to it, and a mounted ConfigMap swaps its ..data link instead.
+	fileWatcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		if err = fileWatcher.Add(filepath.Dir(w.path)); err != nil {
+			fileWatcher.Close()
+		}
+	}
+	if err != nil {
+		logger.Warn(ctx, "not watching the config file, send SIGHUP to reload it", zap.String("path", w.path), zap.Error(err))
+	} else {
+		defer fileWatcher.Close()
+		events, errs = fileWatcher.Events, fileWatcher.Errors
+	}
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logger.Info(ctx, "received SIGHUP, reloading config")
+			w.reload(ctx)
+		case event := <-events:
+			name := filepath.Clean(event.Name)
+			if (name == w.path || filepath.Base(name) == "..data") && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				pending = time.After(reloadDelay)
+			}
+		case err := <-errs:
+			logger.Warn(ctx, "config file watch failed", zap.Error(err))
+		case <-pending:
+			pending = nil
+			logger.Info(ctx, "config file changed, reloading", zap.String("path", w.path))
+			w.reload(ctx)
+		}
+	}
+}
+
+func (w *Watcher) reload(ctx context.Context) {
+	if err := w.Reload(ctx); err != nil {
+		logger.Error(ctx, "failed to reload config, keeping the running one", zap.Error(err))
+	}
+}
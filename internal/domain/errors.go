@@ -1,6 +1,9 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 type HttpError struct {
 	Message string
@@ -15,8 +18,53 @@ var (
 	InternalServerError = &HttpError{"internal server error", 500}
 	InvalidEntry        = &HttpError{"Invalid entry", 422}
 	UrlNotFound         = &HttpError{"Original url not found", 404}
+	TaskNotFound        = &HttpError{"task not found", 404}
+	TaskFinished        = &HttpError{"task already finished", 409}
+	TaskNotScheduled    = &HttpError{"task is not scheduled", 409}
+	WorkflowNotFound    = &HttpError{"workflow not found", 404}
+	ScheduleNotFound    = &HttpError{"schedule not found", 404}
+	DeadLetterNotFound  = &HttpError{"dead letter not found", 404}
+	IdempotencyConflict = &HttpError{"idempotency key was already used with a different request", 409}
 )
 
 var (
 	ErrAlreadyExist = errors.New("row already exist")
 )
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every rejected field of a request. It unwraps to
+// InvalidEntry so callers can map it to the same HTTP status.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		messages = append(messages, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return InvalidEntry
+}
+
+func (e *ValidationError) Add(field, message string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: message})
+}
+
+// Merge adds the errors of other with their fields nested under prefix.
+func (e *ValidationError) Merge(prefix string, other *ValidationError) {
+	for _, fieldErr := range other.Errors {
+		e.Add(prefix+"."+fieldErr.Field, fieldErr.Message)
+	}
+}
+
+func (e *ValidationError) HasErrors() bool {
+	return len(e.Errors) > 0
+}
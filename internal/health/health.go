@@ -0,0 +1,130 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	v1 "task-runner-service/internal/api/v1"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+
+	checkTimeout = 2 * time.Second
+)
+
+// CheckFunc checks one dependency. Details are included in the report even
+// when the check passes.
+type CheckFunc func(ctx context.Context) (map[string]interface{}, error)
+
+// Ping adapts a plain connectivity check.
+func Ping(ping func(ctx context.Context) error) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, ping(ctx)
+	}
+}
+
+type component struct {
+	name     string
+	check    CheckFunc
+	critical bool
+}
+
+// Checker runs the dependency checks behind the readiness probe. A failing
+// critical component makes the process not ready; other failures only
+// degrade the report.
+type Checker struct {
+	components []component
+
+	mu     sync.RWMutex
+	reason string
+}
+
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+func (c *Checker) Add(name string, critical bool, check CheckFunc) {
+	c.components = append(c.components, component{name: name, check: check, critical: critical})
+}
+
+// SetNotReady makes every following readiness check fail with reason, e.g.
+// while the process is shutting down. The reason can be updated to report
+// progress.
+func (c *Checker) SetNotReady(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reason = reason
+}
+
+// Live reports that the process is running. It does not check dependencies,
+// so an outage of Redis does not get the process restarted.
+func (c *Checker) Live(ctx context.Context) v1.HealthReport {
+	return v1.HealthReport{Status: StatusOK}
+}
+
+// Ready runs all checks concurrently and aggregates them.
+func (c *Checker) Ready(ctx context.Context) v1.HealthReport {
+	report := v1.HealthReport{
+		Status:     StatusOK,
+		Components: make(map[string]v1.ComponentHealth, len(c.components)+1),
+	}
+
+	c.mu.RLock()
+	reason := c.reason
+	c.mu.RUnlock()
+	if reason != "" {
+		report.Status = StatusFail
+		report.Components["process"] = v1.ComponentHealth{Status: StatusFail, Error: reason}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, comp := range c.components {
+		wg.Add(1)
+		go func(comp component) {
+			defer wg.Done()
+			health := run(ctx, comp)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[comp.name] = health
+			if health.Status == StatusOK {
+				return
+			}
+			if comp.critical {
+				report.Status = StatusFail
+			} else if report.Status == StatusOK {
+				report.Status = StatusDegraded
+			}
+		}(comp)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func run(ctx context.Context, comp component) v1.ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	started := time.Now()
+	details, err := comp.check(ctx)
+	health := v1.ComponentHealth{
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(started).Microseconds()) / 1000,
+		Details:   details,
+	}
+	if err != nil {
+		health.Status = StatusFail
+		health.Error = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			health.Error = "timed out after " + checkTimeout.String()
+		}
+	}
+	return health
+}
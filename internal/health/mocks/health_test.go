@@ -0,0 +1,80 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"task-runner-service/internal/health"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func passing(ctx context.Context) (map[string]interface{}, error) {
+	return map[string]interface{}{"fresh": 1}, nil
+}
+
+func failing(ctx context.Context) (map[string]interface{}, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestChecker_Ready(t *testing.T) {
+	cases := []struct {
+		name       string
+		critical   health.CheckFunc
+		optional   health.CheckFunc
+		notReady   string
+		wantStatus string
+	}{
+		{"AllPassing", passing, passing, "", health.StatusOK},
+		{"OptionalFailing", passing, failing, "", health.StatusDegraded},
+		{"CriticalFailing", failing, passing, "", health.StatusFail},
+		{"ShuttingDown", passing, passing, "draining, 2 tasks running", health.StatusFail},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checker := health.NewChecker()
+			checker.Add("redis", true, c.critical)
+			checker.Add("workers", false, c.optional)
+			if c.notReady != "" {
+				checker.SetNotReady(c.notReady)
+			}
+
+			report := checker.Ready(context.Background())
+
+			assert.Equal(t, c.wantStatus, report.Status)
+			assert.Contains(t, report.Components, "redis")
+			assert.Contains(t, report.Components, "workers")
+			if c.notReady != "" {
+				assert.Equal(t, c.notReady, report.Components["process"].Error)
+			}
+			assert.Equal(t, health.StatusOK, checker.Live(context.Background()).Status)
+		})
+	}
+}
+
+func TestChecker_ReportsDetailsAndErrors(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Add("redis", true, failing)
+	checker.Add("workers", true, passing)
+
+	report := checker.Ready(context.Background())
+
+	assert.Equal(t, health.StatusFail, report.Components["redis"].Status)
+	assert.Equal(t, "connection refused", report.Components["redis"].Error)
+	assert.Equal(t, health.StatusOK, report.Components["workers"].Status)
+	assert.Equal(t, 1, report.Components["workers"].Details["fresh"])
+	assert.GreaterOrEqual(t, report.Components["workers"].LatencyMs, 0.0)
+}
+
+func TestRedisURL_Invalid(t *testing.T) {
+	_, err := health.RedisURL("amqp://guest@localhost:5672/")
+	assert.Error(t, err)
+
+	_, err = health.RedisURL("redis://localhost:6379/x")
+	assert.Error(t, err)
+
+	_, err = health.RedisURL("redis://secret@localhost/1")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisURL returns a check that pings the Redis server machinery uses for its
+// broker or result backend. The URL uses machinery's format,
+// redis://[password@]host[:port][/db], where a lone user part is the password.
+func RedisURL(rawURL string) (CheckFunc, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	options := &redis.Options{Addr: u.Host}
+	if !strings.Contains(u.Host, ":") {
+		options.Addr = u.Host + ":6379"
+	}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			options.Username = u.User.Username()
+			options.Password = password
+		} else {
+			options.Password = u.User.Username()
+		}
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if options.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis db %q", db)
+		}
+	}
+
+	if u.Scheme == "rediss" {
+		options.TLSConfig = &tls.Config{ServerName: u.Hostname()}
+	}
+
+	client := redis.NewClient(options)
+	return Ping(func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}), nil
+}
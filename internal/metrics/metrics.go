@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-runner-service/pkg/logger"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const namespace = "task_runner"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	tasksSubmitted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_submitted_total",
+		Help:      "Tasks published to the broker.",
+	}, []string{"task", "queue"})
+
+	tasksSucceeded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_succeeded_total",
+		Help:      "Task runs that succeeded.",
+	}, []string{"task", "queue"})
+
+	tasksFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_failed_total",
+		Help:      "Tasks that failed after exhausting their retries.",
+	}, []string{"task", "queue"})
+
+	tasksRetried = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_retried_total",
+		Help:      "Failed task runs that were scheduled for another attempt.",
+	}, []string{"task", "queue"})
+
+	taskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_execution_duration_seconds",
+		Help:      "Task execution time by outcome.",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300, 900, 3600},
+	}, []string{"task", "queue", "status"})
+
+	queueDepth = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "queue_depth"),
+		"Tasks waiting to be picked up by priority.",
+		[]string{"priority"}, nil,
+	)
+
+	storageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Latency of storage operations.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation"})
+
+	storageErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_errors_total",
+		Help:      "Storage operations that failed.",
+	}, []string{"operation"})
+)
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware records requests by chi route pattern rather than by path, so
+// task IDs do not end up in label values.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(route, r.Method).Observe(time.Since(started).Seconds())
+	})
+}
+
+func TaskSubmitted(task, queue string) {
+	tasksSubmitted.WithLabelValues(task, queueLabel(queue)).Inc()
+}
+
+func TaskSucceeded(task, queue string, duration time.Duration) {
+	tasksSucceeded.WithLabelValues(task, queueLabel(queue)).Inc()
+	taskDuration.WithLabelValues(task, queueLabel(queue), "success").Observe(duration.Seconds())
+}
+
+func TaskFailed(task, queue string, duration time.Duration) {
+	tasksFailed.WithLabelValues(task, queueLabel(queue)).Inc()
+	taskDuration.WithLabelValues(task, queueLabel(queue), "failure").Observe(duration.Seconds())
+}
+
+func TaskRetried(task, queue string, duration time.Duration) {
+	tasksRetried.WithLabelValues(task, queueLabel(queue)).Inc()
+	taskDuration.WithLabelValues(task, queueLabel(queue), "retry").Observe(duration.Seconds())
+}
+
+// queueLabel names the default queue, which tasks are submitted to without a
+// queue name.
+func queueLabel(queue string) string {
+	if queue == "" {
+		return "default"
+	}
+	return queue
+}
+
+func ObserveStorage(operation string, duration time.Duration, err error) {
+	storageDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		storageErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// RegisterQueueDepth reports the queue depth read from depth on every scrape.
+func RegisterQueueDepth(depth func(ctx context.Context) (map[string]int, error)) error {
+	return prometheus.Register(queueDepthCollector(depth))
+}
+
+type queueDepthCollector func(ctx context.Context) (map[string]int, error)
+
+func (c queueDepthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepth
+}
+
+func (c queueDepthCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	depth, err := c(ctx)
+	if err != nil {
+		logger.Error(ctx, "failed to collect queue depth", zap.Error(err))
+		return
+	}
+	for priority, count := range depth {
+		ch <- prometheus.MustNewConstMetric(queueDepth, prometheus.GaugeValue, float64(count), priority)
+	}
+}
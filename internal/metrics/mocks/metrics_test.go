@@ -0,0 +1,52 @@
+package mocks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T) string {
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(recorder.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestMiddleware_UsesRoutePattern(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(metrics.Middleware)
+	router.Route("/api/v1", func(r chi.Router) {
+		r.Get("/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/tasks/task_123", nil))
+
+	body := scrape(t)
+	assert.Contains(t, body, `task_runner_http_requests_total{method="GET",route="/api/v1/tasks/{id}",status="404"} 1`)
+	assert.NotContains(t, body, "task_123")
+}
+
+func TestTaskMetrics(t *testing.T) {
+	metrics.TaskSubmitted("echo", "")
+	metrics.TaskSucceeded("echo", "", 20*time.Millisecond)
+	metrics.TaskRetried("sleep", "slow", time.Second)
+	metrics.TaskFailed("sleep", "slow", time.Second)
+
+	body := scrape(t)
+	assert.Contains(t, body, `task_runner_tasks_submitted_total{queue="default",task="echo"} 1`)
+	assert.Contains(t, body, `task_runner_tasks_succeeded_total{queue="default",task="echo"} 1`)
+	assert.Contains(t, body, `task_runner_tasks_retried_total{queue="slow",task="sleep"} 1`)
+	assert.Contains(t, body, `task_runner_tasks_failed_total{queue="slow",task="sleep"} 1`)
+	assert.Contains(t, body, `task_runner_task_execution_duration_seconds_count{queue="slow",status="failure",task="sleep"} 1`)
+}
@@ -0,0 +1,47 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func RegisterBuiltins(r *TaskRegistry) error {
+	if err := r.Register("echo", echo,
+		ArgSpec{Name: "message", Min: Bound(1)},
+	); err != nil {
+		return err
+	}
+	if err := r.Register("sum", sum,
+		ArgSpec{Name: "numbers", Min: Bound(1)},
+	); err != nil {
+		return err
+	}
+	if err := r.Register("sleep", sleep,
+		ArgSpec{Name: "seconds", Optional: true, Default: int64(1), Min: Bound(0), Max: Bound(3600)},
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func echo(ctx context.Context, message string) (string, error) {
+	return message, nil
+}
+
+func sum(ctx context.Context, numbers []int64) (int64, error) {
+	var total int64
+	for _, n := range numbers {
+		total += n
+	}
+	return total, nil
+}
+
+func sleep(ctx context.Context, seconds int64) (string, error) {
+	select {
+	case <-time.After(time.Duration(seconds) * time.Second):
+		return fmt.Sprintf("slept %ds", seconds), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
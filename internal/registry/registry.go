@@ -0,0 +1,213 @@
+package registry
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"task-runner-service/internal/domain"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+type TaskType struct {
+	Name  string
+	Args  []ArgSpec
+	Retry RetryPolicy
+}
+
+type entry struct {
+	fn   interface{}
+	spec TaskType
+}
+
+type TaskRegistry struct {
+	mu      sync.RWMutex
+	entries map[string]*entry
+}
+
+func New() *TaskRegistry {
+	return &TaskRegistry{
+		entries: make(map[string]*entry),
+	}
+}
+
+// Register adds fn under name. A leading context.Context parameter is not
+// counted as a task argument. When args are given they must describe every
+// remaining parameter in order; an empty Type is taken from the function.
+func (r *TaskRegistry) Register(name string, fn interface{}, args ...ArgSpec) error {
+	if name == "" {
+		return fmt.Errorf("task name is empty")
+	}
+	if err := tasks.ValidateTask(fn); err != nil {
+		return fmt.Errorf("invalid task %q: %w", name, err)
+	}
+
+	fnType := reflect.TypeOf(fn)
+	first := 0
+	if fnType.NumIn() > 0 && tasks.IsContextType(fnType.In(0)) {
+		first = 1
+	}
+
+	numArgs := fnType.NumIn() - first
+	if len(args) != 0 && len(args) != numArgs {
+		return fmt.Errorf("invalid task %q: schema describes %d arguments, function takes %d", name, len(args), numArgs)
+	}
+
+	specs := make([]ArgSpec, numArgs)
+	copy(specs, args)
+	for i := range specs {
+		typeName := fnType.In(first + i).String()
+		if specs[i].Type != "" && specs[i].Type != typeName {
+			return fmt.Errorf("invalid task %q: argument %d is declared as %s, function takes %s", name, i, specs[i].Type, typeName)
+		}
+		specs[i].Type = typeName
+
+		if err := specs[i].check(); err != nil {
+			return fmt.Errorf("invalid task %q: argument %d: %w", name, i, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.entries[name]; ok {
+		return fmt.Errorf("task %q is already registered", name)
+	}
+	r.entries[name] = &entry{
+		fn:   fn,
+		spec: TaskType{Name: name, Args: specs, Retry: DefaultRetryPolicy},
+	}
+
+	return nil
+}
+
+// SetRetryPolicy sets the retry policy used for tasks of the given type
+// unless a submission overrides it.
+func (r *TaskRegistry) SetRetryPolicy(name string, policy RetryPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("invalid retry policy for task %q: %w", name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[name]
+	if !ok {
+		return fmt.Errorf("task %q is not registered", name)
+	}
+	e.spec.Retry = policy
+	return nil
+}
+
+func (r *TaskRegistry) RetryPolicy(name string) RetryPolicy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if e, ok := r.entries[name]; ok {
+		return e.spec.Retry
+	}
+	return DefaultRetryPolicy
+}
+
+func (r *TaskRegistry) IsRegistered(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.entries[name]
+	return ok
+}
+
+// Validate checks args against the schema of the task registered under name
+// and returns them with omitted optional arguments filled with defaults.
+// Every problem found is reported in a *domain.ValidationError.
+func (r *TaskRegistry) Validate(name string, args []tasks.Arg) ([]tasks.Arg, error) {
+	return r.validate(name, args, false)
+}
+
+// ValidatePrefix is like Validate but only checks the given leading
+// arguments, for tasks whose remaining arguments are appended by machinery
+// from the results of previous workflow steps.
+func (r *TaskRegistry) ValidatePrefix(name string, args []tasks.Arg) ([]tasks.Arg, error) {
+	return r.validate(name, args, true)
+}
+
+func (r *TaskRegistry) validate(name string, args []tasks.Arg, prefix bool) ([]tasks.Arg, error) {
+	r.mu.RLock()
+	e, ok := r.entries[name]
+	r.mu.RUnlock()
+
+	validationErr := &domain.ValidationError{}
+	if !ok {
+		validationErr.Add("name", fmt.Sprintf("unknown task type %q", name))
+		return nil, validationErr
+	}
+
+	specs := e.spec.Args
+	if len(args) > len(specs) {
+		validationErr.Add("args", fmt.Sprintf("expected at most %d arguments, got %d", len(specs), len(args)))
+		return nil, validationErr
+	}
+
+	prepared := make([]tasks.Arg, 0, len(specs))
+	for i, spec := range specs {
+		field := fmt.Sprintf("args[%d]", i)
+
+		if i >= len(args) {
+			if prefix {
+				break
+			}
+			if !spec.Optional {
+				validationErr.Add(field, "argument is required")
+				continue
+			}
+			prepared = append(prepared, tasks.Arg{Name: spec.Name, Type: spec.Type, Value: spec.Default})
+			continue
+		}
+
+		arg := args[i]
+		if arg.Type == "" {
+			arg.Type = spec.Type
+		}
+		if arg.Name == "" {
+			arg.Name = spec.Name
+		}
+
+		if err := spec.validate(arg); err != nil {
+			validationErr.Add(field, err.Error())
+			continue
+		}
+		prepared = append(prepared, arg)
+	}
+
+	if validationErr.HasErrors() {
+		return nil, validationErr
+	}
+	return prepared, nil
+}
+
+func (r *TaskRegistry) Tasks() map[string]interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	named := make(map[string]interface{}, len(r.entries))
+	for name, e := range r.entries {
+		named[name] = e.fn
+	}
+	return named
+}
+
+func (r *TaskRegistry) Types() []TaskType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]TaskType, 0, len(r.entries))
+	for _, e := range r.entries {
+		types = append(types, e.spec)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Name < types[j].Name
+	})
+	return types
+}
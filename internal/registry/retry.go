@@ -0,0 +1,161 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"time"
+
+	"task-runner-service/internal/domain"
+)
+
+const (
+	BackoffFixed       = "fixed"
+	BackoffExponential = "exponential"
+)
+
+// Error classes a RetryPolicy can be limited to. Task functions can mark
+// their own errors with WithClass.
+const (
+	ErrorClassTimeout   = "timeout"
+	ErrorClassTemporary = "temporary"
+	ErrorClassDefault   = "error"
+)
+
+// RetryPolicy describes how a failed task is attempted again. MaxAttempts
+// counts the first run, so 1 means no retries. With exponential backoff the
+// interval doubles after every attempt up to MaxInterval. Jitter randomizes
+// each interval by up to the given fraction. An empty RetryOn retries errors
+// of any class.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     string
+	Interval    time.Duration
+	MaxInterval time.Duration
+	Jitter      float64
+	RetryOn     []string
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 1,
+	Backoff:     BackoffFixed,
+	Interval:    time.Second,
+}
+
+// Validate reports every invalid field in a *domain.ValidationError.
+func (p RetryPolicy) Validate() error {
+	validationErr := &domain.ValidationError{}
+	if p.MaxAttempts < 1 {
+		validationErr.Add("max_attempts", "must be at least 1")
+	}
+	if p.Backoff != BackoffFixed && p.Backoff != BackoffExponential {
+		validationErr.Add("backoff", fmt.Sprintf("must be %q or %q", BackoffFixed, BackoffExponential))
+	}
+	if p.Interval <= 0 {
+		validationErr.Add("interval", "must be positive")
+	}
+	if p.MaxInterval < 0 {
+		validationErr.Add("max_interval", "must not be negative")
+	} else if p.MaxInterval != 0 && p.MaxInterval < p.Interval {
+		validationErr.Add("max_interval", "must not be less than interval")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		validationErr.Add("jitter", "must be between 0 and 1")
+	}
+	for i, class := range p.RetryOn {
+		if class == "" {
+			validationErr.Add(fmt.Sprintf("retry_on[%d]", i), "error class is empty")
+		}
+	}
+
+	if validationErr.HasErrors() {
+		return validationErr
+	}
+	return nil
+}
+
+// ShouldRetry reports whether a task that failed with err on the given
+// attempt (starting at 1) should run again.
+func (p RetryPolicy) ShouldRetry(err error, attempt int) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	if len(p.RetryOn) == 0 {
+		return true
+	}
+
+	class := ErrorClass(err)
+	for _, retryable := range p.RetryOn {
+		if retryable == class {
+			return true
+		}
+	}
+	return false
+}
+
+// NextInterval returns how long to wait before the attempt that follows
+// the given one.
+func (p RetryPolicy) NextInterval(attempt int) time.Duration {
+	interval := p.Interval
+	if p.Backoff == BackoffExponential && attempt > 1 {
+		interval = time.Duration(float64(p.Interval) * math.Pow(2, float64(attempt-1)))
+	}
+	if p.MaxInterval > 0 && (interval > p.MaxInterval || interval <= 0) {
+		interval = p.MaxInterval
+	}
+
+	if p.Jitter > 0 {
+		delta := float64(interval) * p.Jitter
+		interval += time.Duration(delta * (2*rand.Float64() - 1))
+	}
+	if interval < 0 {
+		interval = 0
+	}
+	return interval
+}
+
+type classError struct {
+	class string
+	err   error
+}
+
+func (e *classError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classError) Unwrap() error {
+	return e.err
+}
+
+// WithClass marks err as belonging to an error class that retry policies
+// can refer to in RetryOn.
+func WithClass(class string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classError{class: class, err: err}
+}
+
+func ErrorClass(err error) string {
+	var classErr *classError
+	if errors.As(err, &classErr) {
+		return classErr.class
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassTimeout
+	}
+
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) && temporary.Temporary() {
+		return ErrorClassTemporary
+	}
+	return ErrorClassDefault
+}
@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// ArgSpec describes one positional task argument. Min and Max bound numeric
+// values, or the length of strings and slices. Optional arguments that are
+// omitted are sent with Default.
+type ArgSpec struct {
+	Name     string
+	Type     string
+	Optional bool
+	Default  interface{}
+	Min      *float64
+	Max      *float64
+}
+
+func Bound(v float64) *float64 {
+	return &v
+}
+
+func (s ArgSpec) check() error {
+	if _, err := tasks.ReflectValue(s.Type, nil); err != nil {
+		if _, ok := err.(tasks.ErrUnsupportedType); ok {
+			return err
+		}
+	}
+	if s.Min != nil && s.Max != nil && *s.Min > *s.Max {
+		return fmt.Errorf("min %v is greater than max %v", *s.Min, *s.Max)
+	}
+	if s.Optional {
+		if err := s.validate(tasks.Arg{Type: s.Type, Value: s.Default}); err != nil {
+			return fmt.Errorf("invalid default: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s ArgSpec) validate(arg tasks.Arg) error {
+	if arg.Type != s.Type {
+		return fmt.Errorf("expected type %s, got %s", s.Type, arg.Type)
+	}
+
+	value, err := tasks.ReflectValue(arg.Type, normalizeValue(arg.Type, arg.Value))
+	if err != nil {
+		return err
+	}
+
+	var measured float64
+	var what string
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		measured, what = float64(value.Int()), "value"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		measured, what = float64(value.Uint()), "value"
+	case reflect.Float32, reflect.Float64:
+		measured, what = value.Float(), "value"
+	case reflect.String, reflect.Slice:
+		measured, what = float64(value.Len()), "length"
+	default:
+		return nil
+	}
+
+	if s.Min != nil && measured < *s.Min {
+		return fmt.Errorf("%s must be at least %v", what, *s.Min)
+	}
+	if s.Max != nil && measured > *s.Max {
+		return fmt.Errorf("%s must be at most %v", what, *s.Max)
+	}
+	return nil
+}
+
+// normalizeValue converts numbers decoded by encoding/json into json.Number,
+// which is what machinery expects for integer and float arguments.
+func normalizeValue(typeName string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if typeName == "string" || typeName == "bool" {
+			return v
+		}
+		return json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+	case []interface{}:
+		elemType := strings.TrimPrefix(typeName, "[]")
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = normalizeValue(elemType, item)
+		}
+		return out
+	}
+	return value
+}
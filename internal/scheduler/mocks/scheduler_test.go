@@ -0,0 +1,92 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/scheduler"
+	"task-runner-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStore struct {
+	schedules []service.Schedule
+	err       error
+}
+
+func (s *fakeStore) GetSchedules(ctx context.Context) ([]service.Schedule, error) {
+	return s.schedules, s.err
+}
+
+type fakeRunner struct {
+	mu  sync.Mutex
+	ran []string
+}
+
+func (r *fakeRunner) RunSchedule(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ran = append(r.ran, id)
+	return nil
+}
+
+type fakeElector struct {
+	leader bool
+	err    error
+}
+
+func (e *fakeElector) AcquireLeadership(ctx context.Context, instanceID string, ttl time.Duration) (bool, error) {
+	return e.leader, e.err
+}
+
+func TestSchedulerSync(t *testing.T) {
+	store := &fakeStore{schedules: []service.Schedule{
+		{ID: "a", Spec: "* * * * *"},
+		{ID: "b", Spec: "@hourly"},
+		{ID: "c", Spec: "@daily", Paused: true},
+		{ID: "d", Spec: "not a spec"},
+	}}
+	s := scheduler.New(store, &fakeRunner{}, &fakeElector{})
+
+	assert.NoError(t, s.Sync(context.Background()))
+	assert.Equal(t, 2, s.Len())
+
+	store.schedules = []service.Schedule{
+		{ID: "a", Spec: "*/5 * * * *"},
+		{ID: "b", Spec: "@hourly", Paused: true},
+	}
+	assert.NoError(t, s.Sync(context.Background()))
+	assert.Equal(t, 1, s.Len())
+
+	store.err = errors.New("redis down")
+	assert.Error(t, s.Sync(context.Background()))
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestSchedulerTrigger(t *testing.T) {
+	cases := []struct {
+		name    string
+		elector *fakeElector
+		wantRan []string
+	}{
+		{name: "Leader", elector: &fakeElector{leader: true}, wantRan: []string{"a"}},
+		{name: "Follower", elector: &fakeElector{leader: false}},
+		{name: "ElectionError", elector: &fakeElector{leader: true, err: errors.New("redis down")}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			runner := &fakeRunner{}
+			s := scheduler.New(&fakeStore{}, runner, c.elector)
+
+			s.Campaign(context.Background())
+			s.Trigger(context.Background(), "a")
+
+			assert.Equal(t, c.wantRan, runner.ran)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"task-runner-service/internal/service"
+	"task-runner-service/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	leaderTTL    = 15 * time.Second
+	syncInterval = 10 * time.Second
+	runTimeout   = 30 * time.Second
+)
+
+type Store interface {
+	GetSchedules(ctx context.Context) ([]service.Schedule, error)
+}
+
+type Runner interface {
+	RunSchedule(ctx context.Context, id string) error
+}
+
+type Elector interface {
+	AcquireLeadership(ctx context.Context, instanceID string, ttl time.Duration) (bool, error)
+}
+
+type entry struct {
+	id   cron.EntryID
+	spec string
+}
+
+// Scheduler keeps a cron entry for every active schedule in the store. All
+// replicas run a Scheduler, but only the one holding the leader lease
+// submits tasks on a tick.
+type Scheduler struct {
+	store      Store
+	runner     Runner
+	elector    Elector
+	instanceID string
+	cron       *cron.Cron
+	leader     atomic.Bool
+
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+func New(store Store, runner Runner, elector Elector) *Scheduler {
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		store:      store,
+		runner:     runner,
+		elector:    elector,
+		instanceID: fmt.Sprintf("%s-%s", hostname, uuid.New().String()),
+		cron:       cron.New(cron.WithLocation(time.UTC)),
+		entries:    make(map[string]entry),
+	}
+}
+
+// Run campaigns for leadership and keeps cron entries in sync with the store
+// until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.cron.Start()
+	defer s.cron.Stop()
+
+	s.Campaign(ctx)
+	if err := s.Sync(ctx); err != nil {
+		logger.Error(ctx, "failed to sync schedules", zap.Error(err))
+	}
+
+	leaderTicker := time.NewTicker(leaderTTL / 3)
+	defer leaderTicker.Stop()
+	syncTicker := time.NewTicker(syncInterval)
+	defer syncTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-leaderTicker.C:
+			s.Campaign(ctx)
+		case <-syncTicker.C:
+			if err := s.Sync(ctx); err != nil {
+				logger.Error(ctx, "failed to sync schedules", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (s *Scheduler) IsLeader() bool {
+	return s.leader.Load()
+}
+
+// Sync adds cron entries for new or changed schedules and removes entries of
+// deleted and paused ones.
+func (s *Scheduler) Sync(ctx context.Context) error {
+	schedules, err := s.store.GetSchedules(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active := make(map[string]bool, len(schedules))
+	for _, schedule := range schedules {
+		if schedule.Paused {
+			continue
+		}
+		active[schedule.ID] = true
+
+		current, ok := s.entries[schedule.ID]
+		if ok && current.spec == schedule.Spec {
+			continue
+		}
+		if ok {
+			s.cron.Remove(current.id)
+		}
+
+		id := schedule.ID
+		entryID, err := s.cron.AddFunc(schedule.Spec, func() { s.Trigger(context.Background(), id) })
+		if err != nil {
+			logger.Error(ctx, "failed to add schedule", zap.String("schedule_id", id), zap.Error(err))
+			delete(s.entries, id)
+			continue
+		}
+		s.entries[id] = entry{id: entryID, spec: schedule.Spec}
+	}
+
+	for id, current := range s.entries {
+		if !active[id] {
+			s.cron.Remove(current.id)
+			delete(s.entries, id)
+		}
+	}
+
+	return nil
+}
+
+// Trigger runs a schedule once if this instance is the leader.
+func (s *Scheduler) Trigger(ctx context.Context, id string) {
+	if !s.IsLeader() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, runTimeout)
+	defer cancel()
+
+	if err := s.runner.RunSchedule(ctx, id); err != nil {
+		logger.Error(ctx, "failed to run schedule", zap.String("schedule_id", id), zap.Error(err))
+	}
+}
+
+// Len returns the number of schedules with a cron entry.
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Campaign acquires or renews the leader lease.
+func (s *Scheduler) Campaign(ctx context.Context) {
+	acquired, err := s.elector.AcquireLeadership(ctx, s.instanceID, leaderTTL)
+	if err != nil {
+		logger.Error(ctx, "failed to acquire scheduler leadership", zap.Error(err))
+		acquired = false
+	}
+
+	if s.leader.Swap(acquired) != acquired {
+		logger.Info(ctx, "scheduler leadership changed", zap.String("instance", s.instanceID), zap.Bool("leader", acquired))
+	}
+}
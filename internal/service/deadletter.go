@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "task-runner-service/internal/api/v1"
+	"task-runner-service/internal/metrics"
+	"task-runner-service/internal/registry"
+	"task-runner-service/internal/tracing"
+	"task-runner-service/pkg/logger"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"go.uber.org/zap"
+)
+
+// DeadLetter is a task that failed after exhausting its retries, kept with
+// the signature it last ran with so it can be inspected and replayed.
+type DeadLetter struct {
+	ID         string
+	Name       string
+	Args       []tasks.Arg
+	Queue      string
+	Signature  tasks.Signature
+	Error      string
+	Retry      registry.RetryPolicy
+	Attempts   []Attempt
+	WorkflowID string
+	FailedAt   time.Time
+}
+
+// NewDeadLetter builds the dead letter of a task that failed for good.
+func NewDeadLetter(task Task, signature *tasks.Signature) DeadLetter {
+	letter := DeadLetter{
+		ID:         task.ID,
+		Name:       task.Name,
+		Args:       task.Args,
+		Queue:      task.Queue,
+		Error:      task.Error,
+		Retry:      task.Retry,
+		Attempts:   task.Attempts,
+		WorkflowID: task.WorkflowID,
+		FailedAt:   time.Now(),
+	}
+	if task.FinishedAt != nil {
+		letter.FailedAt = *task.FinishedAt
+	}
+	if signature != nil {
+		letter.Signature = *signature
+	}
+	return letter
+}
+
+func (s *RunnerService) GetDeadLetters(ctx context.Context, name string, limit, offset int) ([]v1.DeadLetterResponse, int, error) {
+	letters, total, err := s.storage.GetDeadLetters(ctx, name, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get dead letters: %w", err)
+	}
+
+	responses := make([]v1.DeadLetterResponse, 0, len(letters))
+	for _, letter := range letters {
+		responses = append(responses, newDeadLetterResponse(letter))
+	}
+	return responses, total, nil
+}
+
+func (s *RunnerService) GetDeadLetter(ctx context.Context, id string) (*v1.DeadLetterResponse, error) {
+	letter, err := s.storage.GetDeadLetter(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+
+	response := newDeadLetterResponse(*letter)
+	return &response, nil
+}
+
+// RequeueDeadLetter publishes the task again under the same ID with a fresh
+// retry budget and removes it from the dead-letter queue. Workflow callbacks
+// in the stored signature are kept, so a replayed chain step continues the
+// chain.
+func (s *RunnerService) RequeueDeadLetter(ctx context.Context, id string) (*v1.TaskResponse, error) {
+	letter, err := s.storage.GetDeadLetter(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+
+	task, err := s.storage.GetTask(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	ctx = logger.WithTask(ctx, task.ID, task.Name, task.Queue)
+
+	signature := letter.Signature
+	if signature.UUID == "" {
+		signature = tasks.Signature{UUID: letter.ID, Name: letter.Name, Args: letter.Args, RoutingKey: s.routingKey(letter.Queue, task.Priority)}
+		if _, priority, err := ParsePriority(task.Priority); err == nil {
+			signature.Priority = priority
+		}
+	}
+	signature.ETA = nil
+	if signature.Headers != nil {
+		delete(signature.Headers, ScheduledAtHeader)
+	}
+
+	task.Status = tasks.StatePending
+	task.Error = ""
+	task.Result = nil
+	task.ScheduledAt = nil
+	task.StartedAt = nil
+	task.FinishedAt = nil
+	task.NextRetryAt = nil
+	task.Attempt = 0
+	task.Attempts = nil
+	applyRetryPolicy(&signature, task, letter.Retry)
+
+	if err := s.storage.SaveTask(ctx, *task); err != nil {
+		return nil, fmt.Errorf("failed to save task: %w", err)
+	}
+
+	_, span := tracing.StartEnqueue(ctx, task.Name, &signature)
+	_, err = s.server.SendTask(&signature)
+	tracing.End(span, err)
+	if err != nil {
+		finishedAt := time.Now()
+		task.Status = tasks.StateFailure
+		task.Error = letter.Error
+		task.FinishedAt = &finishedAt
+		if saveErr := s.storage.SaveTask(ctx, *task); saveErr != nil {
+			logger.Error(ctx, "failed to restore FAILURE state of task", zap.Error(saveErr))
+		}
+		return nil, fmt.Errorf("failed to send task: %w", err)
+	}
+	metrics.TaskSubmitted(task.Name, task.Queue)
+
+	if err := s.storage.DeleteDeadLetter(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+
+	response := newTaskResponse(*task)
+	return &response, nil
+}
+
+func (s *RunnerService) DeleteDeadLetter(ctx context.Context, id string) error {
+	if err := s.storage.DeleteDeadLetter(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	return nil
+}
+
+func (s *RunnerService) PurgeDeadLetters(ctx context.Context, name string) (int, error) {
+	purged, err := s.storage.PurgeDeadLetters(ctx, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge dead letters: %w", err)
+	}
+	return purged, nil
+}
+
+func newDeadLetterResponse(letter DeadLetter) v1.DeadLetterResponse {
+	return v1.DeadLetterResponse{
+		ID:         letter.ID,
+		Name:       letter.Name,
+		Args:       letter.Args,
+		Queue:      letter.Queue,
+		Error:      letter.Error,
+		Attempts:   newAttemptResponses(letter.Attempts),
+		WorkflowID: letter.WorkflowID,
+		FailedAt:   letter.FailedAt.Format(time.RFC3339),
+		Signature:  letter.Signature,
+	}
+}
@@ -0,0 +1,13 @@
+package service
+
+import "time"
+
+// Heartbeat is published periodically by every worker process so that other
+// processes can tell whether workers are alive.
+type Heartbeat struct {
+	WorkerID string
+	Hostname string
+	Queues   []string
+	Running  int
+	At       time.Time
+}
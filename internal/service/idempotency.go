@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1 "task-runner-service/internal/api/v1"
+	"task-runner-service/internal/domain"
+	"task-runner-service/pkg/logger"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"go.uber.org/zap"
+)
+
+// IdempotencyTTL is how long a submission can be repeated with the same
+// idempotency key and get the original task back.
+const IdempotencyTTL = 24 * time.Hour
+
+type IdempotencyRecord struct {
+	Key         string
+	Fingerprint string
+	TaskID      string
+	CreatedAt   time.Time
+}
+
+// reserveIdempotencyKey claims req.IdempotencyKey for taskID. If the key was
+// already used it returns the task submitted with it, or an error when the
+// earlier submission had a different payload.
+func (s *RunnerService) reserveIdempotencyKey(ctx context.Context, req v1.TaskRequest, taskID string) (*v1.TaskResponse, error) {
+	fingerprint, err := requestFingerprint(req)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.storage.ReserveIdempotencyKey(ctx, IdempotencyRecord{
+		Key:         req.IdempotencyKey,
+		Fingerprint: fingerprint,
+		TaskID:      taskID,
+		CreatedAt:   time.Now(),
+	}, IdempotencyTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	if existing.Fingerprint != fingerprint {
+		return nil, domain.IdempotencyConflict
+	}
+
+	task, err := s.storage.GetTask(ctx, existing.TaskID)
+	if err != nil {
+		// The original submission reserved the key but has not stored the
+		// task yet.
+		return &v1.TaskResponse{ID: existing.TaskID, Status: tasks.StatePending}, nil
+	}
+	response := newTaskResponse(*task)
+	return &response, nil
+}
+
+// releaseIdempotencyKey frees the key of a submission that failed, so that
+// the client can retry it.
+func (s *RunnerService) releaseIdempotencyKey(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	if err := s.storage.ReleaseIdempotencyKey(ctx, key); err != nil {
+		logger.Error(ctx, "failed to release idempotency key", zap.String("idempotency_key", key), zap.Error(err))
+	}
+}
+
+func requestFingerprint(req v1.TaskRequest) (string, error) {
+	req.IdempotencyKey = ""
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint request: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+
+	"task-runner-service/internal/domain"
+)
+
+const (
+	maxLabels          = 16
+	maxLabelValueBytes = 256
+)
+
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,63}$`)
+
+// validateLabels checks task labels. Keys are restricted so that a label
+// can be written as key:value in the task list filter.
+func validateLabels(labels map[string]string) error {
+	validationErr := &domain.ValidationError{}
+	if len(labels) > maxLabels {
+		validationErr.Add("labels", fmt.Sprintf("at most %d labels are allowed", maxLabels))
+	}
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) {
+			validationErr.Add("labels."+key, "key must be 1-63 letters, digits, '_', '.' or '-'")
+			continue
+		}
+		if value == "" || len(value) > maxLabelValueBytes {
+			validationErr.Add("labels."+key, fmt.Sprintf("value must be 1-%d bytes", maxLabelValueBytes))
+		}
+	}
+
+	if validationErr.HasErrors() {
+		return validationErr
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1 "task-runner-service/internal/api/v1"
+	"task-runner-service/internal/domain"
+)
+
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// TaskQuery selects a page of tasks ordered by creation time. Ties are
+// broken by task ID, so the order is stable. A task matches when it matches
+// every filter that is set and any value of the list filters. The From
+// bounds are inclusive, the To bounds exclusive.
+type TaskQuery struct {
+	Statuses      []string
+	Names         []string
+	Queues        []string
+	Labels        map[string]string
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	FinishedFrom  *time.Time
+	FinishedTo    *time.Time
+	ErrorContains string
+
+	Limit int
+	Order string
+	// After is the position of the last task of the previous page.
+	After *Cursor
+}
+
+// DefaultQueueName is how tasks submitted without a queue are filtered and
+// indexed.
+const DefaultQueueName = "default"
+
+// QueueName returns the name tasks of queue are indexed by.
+func QueueName(queue string) string {
+	if queue == "" {
+		return DefaultQueueName
+	}
+	return queue
+}
+
+// TaskPage is a page of tasks and the number of tasks matching the query
+// across all pages. A storage that cannot count the matches cheaply estimates
+// Total and sets TotalApproximate.
+type TaskPage struct {
+	Tasks            []Task
+	Total            int
+	TotalApproximate bool
+	HasMore          bool
+}
+
+// Cursor is a position in the task list. Clients receive it as an opaque
+// token.
+type Cursor struct {
+	CreatedAt int64  `json:"t"`
+	ID        string `json:"id"`
+}
+
+func CursorOf(task Task) Cursor {
+	return Cursor{CreatedAt: CursorTime(task.CreatedAt), ID: task.ID}
+}
+
+// CursorTime is the precision creation times are ordered with.
+func CursorTime(t time.Time) int64 {
+	return t.UnixMilli()
+}
+
+func EncodeCursor(cursor Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	return &cursor, nil
+}
+
+func (s *RunnerService) GetTasks(ctx context.Context, req v1.TaskListRequest) (*v1.TaskListResponse, error) {
+	query, err := newTaskQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.storage.GetTasks(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	response := &v1.TaskListResponse{
+		Tasks:            make([]v1.TaskResponse, 0, len(page.Tasks)),
+		Limit:            query.Limit,
+		Order:            query.Order,
+		Total:            page.Total,
+		TotalApproximate: page.TotalApproximate,
+	}
+	for _, task := range page.Tasks {
+		response.Tasks = append(response.Tasks, newTaskResponse(task))
+	}
+	if page.HasMore && len(page.Tasks) > 0 {
+		response.NextCursor = EncodeCursor(CursorOf(page.Tasks[len(page.Tasks)-1]))
+	}
+	return response, nil
+}
+
+func newTaskQuery(req v1.TaskListRequest) (TaskQuery, error) {
+	validationErr := &domain.ValidationError{}
+	query := TaskQuery{
+		Statuses:      req.Statuses,
+		Names:         req.Names,
+		Queues:        req.Queues,
+		Labels:        req.Labels,
+		ErrorContains: req.Error,
+		Limit:         req.Limit,
+		Order:         req.Order,
+	}
+	query.CreatedFrom = parseFilterTime(validationErr, "created_after", req.CreatedAfter)
+	query.CreatedTo = parseFilterTime(validationErr, "created_before", req.CreatedBefore)
+	query.FinishedFrom = parseFilterTime(validationErr, "finished_after", req.FinishedAfter)
+	query.FinishedTo = parseFilterTime(validationErr, "finished_before", req.FinishedBefore)
+
+	switch {
+	case query.Limit <= 0:
+		query.Limit = DefaultPageSize
+	case query.Limit > MaxPageSize:
+		validationErr.Add("limit", fmt.Sprintf("must not exceed %d", MaxPageSize))
+	}
+
+	switch query.Order {
+	case "":
+		query.Order = OrderDesc
+	case OrderAsc, OrderDesc:
+	default:
+		validationErr.Add("order", "must be asc or desc")
+	}
+
+	if req.Cursor != "" {
+		cursor, err := DecodeCursor(req.Cursor)
+		if err != nil {
+			validationErr.Add("cursor", err.Error())
+		}
+		query.After = cursor
+	}
+
+	if validationErr.HasErrors() {
+		return TaskQuery{}, validationErr
+	}
+	return query, nil
+}
+
+func parseFilterTime(validationErr *domain.ValidationError, field, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		validationErr.Add(field, "must be an RFC 3339 time")
+		return nil
+	}
+	return &t
+}
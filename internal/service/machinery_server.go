@@ -3,12 +3,17 @@ package service
 import (
 	"github.com/RichardKnop/machinery/v1/backends/iface"
 	"github.com/RichardKnop/machinery/v1/backends/result"
+	"github.com/RichardKnop/machinery/v1/config"
 	"github.com/RichardKnop/machinery/v1/tasks"
 )
 
 type MachineryServer interface {
 	SendTask(signature *tasks.Signature) (*result.AsyncResult, error)
+	SendChain(chain *tasks.Chain) (*result.ChainAsyncResult, error)
+	SendGroup(group *tasks.Group, sendConcurrency int) ([]*result.AsyncResult, error)
+	SendChord(chord *tasks.Chord, sendConcurrency int) (*result.ChordAsyncResult, error)
 	GetBackend() iface.Backend
+	GetConfig() *config.Config
 }
 
 type MachineryBackend interface {
@@ -5,11 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
+	v1 "task-runner-service/internal/api/v1"
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/registry"
 	"task-runner-service/internal/service"
 
 	"github.com/RichardKnop/machinery/v1/backends/iface"
 	"github.com/RichardKnop/machinery/v1/backends/result"
+	"github.com/RichardKnop/machinery/v1/config"
 	"github.com/RichardKnop/machinery/v1/tasks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -24,9 +29,90 @@ func (m *MockStorage) GetTask(ctx context.Context, id string) (*service.Task, er
 	args := m.Called(ctx, id)
 	return args.Get(0).(*service.Task), args.Error(1)
 }
-func (m *MockStorage) GetTasks(ctx context.Context, status string, limit, offset int) ([]service.Task, error) {
-	args := m.Called(ctx, status, limit, offset)
-	return args.Get(0).([]service.Task), args.Error(1)
+
+// UpdateTask applies update to the task of GetTask and saves it with SaveTask,
+// as a storage without concurrent writers would.
+func (m *MockStorage) UpdateTask(ctx context.Context, id string, update func(task *service.Task) error) (*service.Task, error) {
+	task, err := m.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	updated := *task
+	if err := update(&updated); err != nil {
+		return nil, err
+	}
+	if err := m.SaveTask(ctx, updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+func (m *MockStorage) GetTasks(ctx context.Context, query service.TaskQuery) (*service.TaskPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(*service.TaskPage), args.Error(1)
+}
+func (m *MockStorage) PublishCancellation(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+func (m *MockStorage) SubscribeCancellations(ctx context.Context) (<-chan string, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(<-chan string), args.Error(1)
+}
+func (m *MockStorage) SaveWorkflow(ctx context.Context, workflow service.Workflow) error {
+	return m.Called(ctx, workflow).Error(0)
+}
+func (m *MockStorage) GetWorkflow(ctx context.Context, id string) (*service.Workflow, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*service.Workflow), args.Error(1)
+}
+func (m *MockStorage) SaveSchedule(ctx context.Context, schedule service.Schedule) error {
+	return m.Called(ctx, schedule).Error(0)
+}
+func (m *MockStorage) GetSchedule(ctx context.Context, id string) (*service.Schedule, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*service.Schedule), args.Error(1)
+}
+func (m *MockStorage) GetSchedules(ctx context.Context) ([]service.Schedule, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]service.Schedule), args.Error(1)
+}
+func (m *MockStorage) DeleteSchedule(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+func (m *MockStorage) AddScheduleRun(ctx context.Context, id string, run service.ScheduleRun, limit int) error {
+	return m.Called(ctx, id, run, limit).Error(0)
+}
+func (m *MockStorage) GetScheduleRuns(ctx context.Context, id string, limit int) ([]service.ScheduleRun, error) {
+	args := m.Called(ctx, id, limit)
+	return args.Get(0).([]service.ScheduleRun), args.Error(1)
+}
+func (m *MockStorage) SaveDeadLetter(ctx context.Context, letter service.DeadLetter) error {
+	return m.Called(ctx, letter).Error(0)
+}
+func (m *MockStorage) GetDeadLetter(ctx context.Context, id string) (*service.DeadLetter, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*service.DeadLetter), args.Error(1)
+}
+func (m *MockStorage) GetDeadLetters(ctx context.Context, name string, limit, offset int) ([]service.DeadLetter, int, error) {
+	args := m.Called(ctx, name, limit, offset)
+	return args.Get(0).([]service.DeadLetter), args.Int(1), args.Error(2)
+}
+func (m *MockStorage) DeleteDeadLetter(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+func (m *MockStorage) PurgeDeadLetters(ctx context.Context, name string) (int, error) {
+	args := m.Called(ctx, name)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockStorage) ReserveIdempotencyKey(ctx context.Context, record service.IdempotencyRecord, ttl time.Duration) (*service.IdempotencyRecord, error) {
+	args := m.Called(ctx, record, ttl)
+	return args.Get(0).(*service.IdempotencyRecord), args.Error(1)
+}
+func (m *MockStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return m.Called(ctx, key).Error(0)
+}
+func (m *MockStorage) GetQueueDepth(ctx context.Context) (map[string]int, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[string]int), args.Error(1)
 }
 
 type MockBackend struct{ mock.Mock }
@@ -86,9 +172,24 @@ func (m *MockServer) SendTask(sig *tasks.Signature) (*result.AsyncResult, error)
 	args := m.Called(sig)
 	return args.Get(0).(*result.AsyncResult), args.Error(1)
 }
+func (m *MockServer) SendChain(chain *tasks.Chain) (*result.ChainAsyncResult, error) {
+	args := m.Called(chain)
+	return args.Get(0).(*result.ChainAsyncResult), args.Error(1)
+}
+func (m *MockServer) SendGroup(group *tasks.Group, sendConcurrency int) ([]*result.AsyncResult, error) {
+	args := m.Called(group, sendConcurrency)
+	return args.Get(0).([]*result.AsyncResult), args.Error(1)
+}
+func (m *MockServer) SendChord(chord *tasks.Chord, sendConcurrency int) (*result.ChordAsyncResult, error) {
+	args := m.Called(chord, sendConcurrency)
+	return args.Get(0).(*result.ChordAsyncResult), args.Error(1)
+}
 func (m *MockServer) GetBackend() iface.Backend {
 	return m.Called().Get(0).(iface.Backend)
 }
+func (m *MockServer) GetConfig() *config.Config {
+	return m.Called().Get(0).(*config.Config)
+}
 
 var _ service.MachineryServer = (*MockServer)(nil)
 
@@ -110,33 +211,37 @@ func TestSendTask(t *testing.T) {
 			srv := new(MockServer)
 			be := new(MockBackend)
 
-			if c.serverErr != nil {
-				srv.
-					On("SendTask", mock.AnythingOfType("*tasks.Signature")).
-					Return((*result.AsyncResult)(nil), c.serverErr)
-			} else {
-				sig := &tasks.Signature{Name: "n", UUID: "id-1"}
-				async := result.NewAsyncResult(sig, be)
-				srv.
-					On("SendTask", mock.AnythingOfType("*tasks.Signature")).
-					Return(async, nil)
-				be.
-					On("GetState", "id-1").
-					Return(&tasks.TaskState{TaskUUID: "id-1"}, nil)
-				st.
-					On("SaveTask", mock.Anything, mock.MatchedBy(func(task service.Task) bool {
-						return task.ID == "id-1"
-					})).
-					Return(c.storageErr)
+			isStatus := func(status string) interface{} {
+				return mock.MatchedBy(func(task service.Task) bool {
+					return task.Name == "n" && task.Status == status
+				})
+			}
+
+			st.On("SaveTask", mock.Anything, isStatus(tasks.StatePending)).Return(c.storageErr)
+			if c.storageErr == nil {
+				if c.serverErr != nil {
+					srv.
+						On("SendTask", mock.AnythingOfType("*tasks.Signature")).
+						Return((*result.AsyncResult)(nil), c.serverErr)
+					st.On("SaveTask", mock.Anything, isStatus(tasks.StateFailure)).Return(nil)
+				} else {
+					sig := &tasks.Signature{Name: "n", UUID: "id-1"}
+					async := result.NewAsyncResult(sig, be)
+					srv.
+						On("SendTask", mock.AnythingOfType("*tasks.Signature")).
+						Return(async, nil)
+				}
 			}
 
-			svc := service.NewRunnerService(srv, st)
-			_, err := svc.SendTask(context.Background(), "n", nil, "")
+			svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+			resp, err := svc.SendTask(context.Background(), v1.TaskRequest{Name: "n"})
 
 			if c.wantErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
+				assert.NotEmpty(t, resp.ID)
+				assert.Equal(t, tasks.StatePending, resp.Status)
 			}
 			srv.AssertExpectations(t)
 			be.AssertExpectations(t)
@@ -145,6 +250,264 @@ func TestSendTask(t *testing.T) {
 	}
 }
 
+func TestSendTask_ValidationError(t *testing.T) {
+	cases := []struct {
+		name       string
+		task       string
+		args       []tasks.Arg
+		wantFields []string
+	}{
+		{"UnknownTask", "missing", nil, []string{"name"}},
+		{"MissingRequired", "echo", nil, []string{"args[0]"}},
+		{"TooManyArgs", "echo", []tasks.Arg{{Type: "string", Value: "a"}, {Type: "string", Value: "b"}}, []string{"args"}},
+		{"OutOfRange", "sleep", []tasks.Arg{{Type: "int64", Value: 7200.0}}, []string{"args[0]"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := new(MockStorage)
+			srv := new(MockServer)
+			taskRegistry := registry.New()
+			assert.NoError(t, registry.RegisterBuiltins(taskRegistry))
+
+			svc := service.NewRunnerService(srv, st, taskRegistry)
+			_, err := svc.SendTask(context.Background(), v1.TaskRequest{Name: c.task, Args: c.args})
+
+			assert.ErrorIs(t, err, domain.InvalidEntry)
+			var validationErr *domain.ValidationError
+			if assert.ErrorAs(t, err, &validationErr) {
+				var fields []string
+				for _, fieldErr := range validationErr.Errors {
+					fields = append(fields, fieldErr.Field)
+				}
+				assert.Equal(t, c.wantFields, fields)
+			}
+			srv.AssertNotCalled(t, "SendTask", mock.Anything)
+			st.AssertNotCalled(t, "SaveTask", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestSendTask_Scheduled(t *testing.T) {
+	eta := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	cases := []struct {
+		name      string
+		req       v1.TaskRequest
+		wantETA   func(at time.Time) bool
+		wantField string
+	}{
+		{
+			name:    "ETA",
+			req:     v1.TaskRequest{Name: "n", ETA: eta.Format(time.RFC3339)},
+			wantETA: func(at time.Time) bool { return at.Equal(eta) },
+		},
+		{
+			name: "Delay",
+			req:  v1.TaskRequest{Name: "n", Delay: "10m"},
+			wantETA: func(at time.Time) bool {
+				return at.After(time.Now().Add(9*time.Minute)) && at.Before(time.Now().Add(11*time.Minute))
+			},
+		},
+		{name: "BadETA", req: v1.TaskRequest{Name: "n", ETA: "tomorrow"}, wantField: "eta"},
+		{name: "BadDelay", req: v1.TaskRequest{Name: "n", Delay: "-5m"}, wantField: "delay"},
+		{name: "Both", req: v1.TaskRequest{Name: "n", ETA: eta.Format(time.RFC3339), Delay: "5m"}, wantField: "eta"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := new(MockStorage)
+			srv := new(MockServer)
+			if c.wantField == "" {
+				st.On("SaveTask", mock.Anything, mock.MatchedBy(func(task service.Task) bool {
+					return task.Status == service.StateScheduled && task.ScheduledAt != nil && c.wantETA(*task.ScheduledAt)
+				})).Return(nil)
+				srv.On("SendTask", mock.MatchedBy(func(sig *tasks.Signature) bool {
+					return sig.ETA != nil && c.wantETA(*sig.ETA) &&
+						sig.Headers[service.ScheduledAtHeader] == sig.ETA.Format(time.RFC3339Nano)
+				})).Return((*result.AsyncResult)(nil), nil)
+			}
+
+			svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+			resp, err := svc.SendTask(context.Background(), c.req)
+
+			if c.wantField != "" {
+				var validationErr *domain.ValidationError
+				if assert.ErrorAs(t, err, &validationErr) {
+					assert.Equal(t, c.wantField, validationErr.Errors[0].Field)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, service.StateScheduled, resp.Status)
+				assert.NotEmpty(t, resp.ScheduledAt)
+			}
+			srv.AssertExpectations(t)
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSendTask_Priority(t *testing.T) {
+	cases := []struct {
+		name          string
+		req           v1.TaskRequest
+		wantPriority  string
+		wantSignature uint8
+		wantRouting   string
+		wantErr       bool
+	}{
+		{name: "Default", req: v1.TaskRequest{Name: "n"}, wantPriority: service.PriorityNormal, wantSignature: 5},
+		{name: "HighDefaultQueue", req: v1.TaskRequest{Name: "n", Priority: "high"}, wantPriority: service.PriorityHigh, wantSignature: 9, wantRouting: "machinery_tasks.high"},
+		{name: "NumberCustomQueue", req: v1.TaskRequest{Name: "n", Queue: "emails", Priority: "2"}, wantPriority: service.PriorityLow, wantSignature: 2, wantRouting: "emails.low"},
+		{name: "Unknown", req: v1.TaskRequest{Name: "n", Priority: "urgent"}, wantErr: true},
+		{name: "OutOfRange", req: v1.TaskRequest{Name: "n", Priority: "10"}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := new(MockStorage)
+			srv := new(MockServer)
+			if !c.wantErr {
+				srv.On("GetConfig").Return(&config.Config{DefaultQueue: "machinery_tasks"}).Maybe()
+				st.On("SaveTask", mock.Anything, mock.MatchedBy(func(task service.Task) bool {
+					return task.Priority == c.wantPriority
+				})).Return(nil)
+				srv.On("SendTask", mock.MatchedBy(func(sig *tasks.Signature) bool {
+					return sig.RoutingKey == c.wantRouting && sig.Priority == c.wantSignature
+				})).Return((*result.AsyncResult)(nil), nil)
+			}
+
+			svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+			resp, err := svc.SendTask(context.Background(), c.req)
+
+			if c.wantErr {
+				var validationErr *domain.ValidationError
+				if assert.ErrorAs(t, err, &validationErr) {
+					assert.Equal(t, "priority", validationErr.Errors[0].Field)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, c.wantPriority, resp.Priority)
+			}
+			srv.AssertExpectations(t)
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSendTask_Queues(t *testing.T) {
+	taskRegistry := registry.New()
+	assert.NoError(t, registry.RegisterBuiltins(taskRegistry))
+	sleepArgs := []tasks.Arg{{Type: "int64", Value: 1.0}}
+	echoArgs := []tasks.Arg{{Type: "string", Value: "hi"}}
+
+	cases := []struct {
+		name    string
+		req     v1.TaskRequest
+		wantErr bool
+	}{
+		{name: "DefaultQueue", req: v1.TaskRequest{Name: "echo", Args: echoArgs}},
+		{name: "AllowedTask", req: v1.TaskRequest{Name: "sleep", Args: sleepArgs, Queue: "slow"}},
+		{name: "DisallowedTask", req: v1.TaskRequest{Name: "echo", Args: echoArgs, Queue: "slow"}, wantErr: true},
+		{name: "UndeclaredQueue", req: v1.TaskRequest{Name: "echo", Args: echoArgs, Queue: "missing"}, wantErr: true},
+		{name: "DisabledQueue", req: v1.TaskRequest{Name: "echo", Args: echoArgs, Queue: "paused"}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := new(MockStorage)
+			srv := new(MockServer)
+			srv.On("GetConfig").Return(&config.Config{DefaultQueue: "machinery_tasks"}).Maybe()
+			if !c.wantErr {
+				st.On("SaveTask", mock.Anything, mock.Anything).Return(nil)
+				srv.On("SendTask", mock.AnythingOfType("*tasks.Signature")).Return((*result.AsyncResult)(nil), nil)
+			}
+
+			svc := service.NewRunnerService(srv, st, taskRegistry)
+			assert.NoError(t, svc.SetQueues([]service.Queue{
+				{Name: "machinery_tasks", Concurrency: 10, Enabled: true},
+				{Name: "slow", Concurrency: 2, Tasks: []string{"sleep"}, Enabled: true},
+				{Name: "paused", Concurrency: 2},
+			}))
+			_, err := svc.SendTask(context.Background(), c.req)
+
+			if c.wantErr {
+				var validationErr *domain.ValidationError
+				if assert.ErrorAs(t, err, &validationErr) {
+					assert.Equal(t, "queue", validationErr.Errors[0].Field)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			srv.AssertExpectations(t)
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSetQueues_Invalid(t *testing.T) {
+	svc := service.NewRunnerService(new(MockServer), new(MockStorage), newTestRegistry(t))
+
+	assert.Error(t, svc.SetQueues([]service.Queue{{Name: "a", Tasks: []string{"missing"}}}))
+	assert.Error(t, svc.SetQueues([]service.Queue{{Name: "a"}, {Name: "a"}}))
+	assert.Error(t, svc.SetQueues([]service.Queue{{Tasks: []string{"n"}}}))
+}
+
+func TestRescheduleTask(t *testing.T) {
+	oldETA := time.Now().Add(time.Hour).UTC()
+	newETA := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+
+	cases := []struct {
+		name    string
+		status  string
+		wantErr error
+	}{
+		{"Scheduled", service.StateScheduled, nil},
+		{"AlreadyRunning", tasks.StateStarted, domain.TaskNotScheduled},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := new(MockStorage)
+			srv := new(MockServer)
+			stored := &service.Task{ID: "tid", Name: "n", Status: c.status, ScheduledAt: &oldETA}
+			st.On("GetTask", mock.Anything, "tid").Return(stored, nil)
+			if c.wantErr == nil {
+				st.On("SaveTask", mock.Anything, mock.MatchedBy(func(task service.Task) bool {
+					return task.ScheduledAt.Equal(newETA)
+				})).Return(nil)
+				srv.On("SendTask", mock.MatchedBy(func(sig *tasks.Signature) bool {
+					return sig.UUID == "tid" && sig.ETA.Equal(newETA)
+				})).Return((*result.AsyncResult)(nil), nil)
+			}
+
+			svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+			resp, err := svc.RescheduleTask(context.Background(), "tid", v1.RescheduleRequest{ETA: newETA.Format(time.RFC3339)})
+
+			if c.wantErr != nil {
+				assert.ErrorIs(t, err, c.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, newETA.Format(time.RFC3339), resp.ScheduledAt)
+			}
+			srv.AssertExpectations(t)
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestIsStaleDelivery(t *testing.T) {
+	scheduledAt := time.Now().UTC()
+	task := service.Task{ID: "tid", ScheduledAt: &scheduledAt}
+
+	current := &tasks.Signature{Headers: tasks.Headers{service.ScheduledAtHeader: scheduledAt.Format(time.RFC3339Nano)}}
+	outdated := &tasks.Signature{Headers: tasks.Headers{service.ScheduledAtHeader: scheduledAt.Add(-time.Minute).Format(time.RFC3339Nano)}}
+
+	assert.False(t, service.IsStaleDelivery(task, current))
+	assert.True(t, service.IsStaleDelivery(task, outdated))
+	assert.False(t, service.IsStaleDelivery(service.Task{ID: "tid"}, &tasks.Signature{}))
+}
+
 func TestGetTaskStatus(t *testing.T) {
 	cases := []struct {
 		name        string
@@ -152,24 +515,36 @@ func TestGetTaskStatus(t *testing.T) {
 		storageErr  error
 		state       *tasks.TaskState
 		stateErr    error
+		noBackend   bool
 		wantErr     bool
 		wantStatus  string
 		wantResult  interface{}
 	}{
 		{
 			name:        "Success",
-			storageTask: &service.Task{ID: "tid", Status: "pending"},
-			storageErr:  nil,
+			storageTask: &service.Task{ID: "tid", Status: tasks.StateSuccess, Result: 123},
+			noBackend:   true,
+			wantStatus:  tasks.StateSuccess,
+			wantResult:  123,
+		},
+		{
+			name:        "ResultFromBackend",
+			storageTask: &service.Task{ID: "tid", Status: tasks.StateSuccess},
 			state: &tasks.TaskState{
 				TaskUUID: "tid",
 				State:    tasks.StateSuccess,
 				Results:  []*tasks.TaskResult{{Value: 123}},
 			},
-			stateErr:   nil,
-			wantErr:    false,
 			wantStatus: tasks.StateSuccess,
 			wantResult: 123,
 		},
+		{
+			name:        "StoredStatusWins",
+			storageTask: &service.Task{ID: "tid", Status: tasks.StateSuccess, Result: "ok"},
+			noBackend:   true,
+			wantStatus:  tasks.StateSuccess,
+			wantResult:  "ok",
+		},
 		{
 			name:        "StorageFail",
 			storageTask: nil,
@@ -177,12 +552,28 @@ func TestGetTaskStatus(t *testing.T) {
 			wantErr:     true,
 		},
 		{
-			name:        "StateFail",
-			storageTask: &service.Task{ID: "tid"},
-			storageErr:  nil,
-			state:       nil,
-			stateErr:    errors.New("backend error"),
-			wantErr:     true,
+			name:        "StateMissing",
+			storageTask: &service.Task{ID: "tid", Status: tasks.StateSuccess},
+			stateErr:    errors.New("state expired"),
+			wantStatus:  tasks.StateSuccess,
+		},
+		{
+			name:        "Pending",
+			storageTask: &service.Task{ID: "tid", Status: tasks.StatePending},
+			noBackend:   true,
+			wantStatus:  tasks.StatePending,
+		},
+		{
+			name:        "CancelledWithoutState",
+			storageTask: &service.Task{ID: "tid", Status: service.StateCancelled},
+			noBackend:   true,
+			wantStatus:  service.StateCancelled,
+		},
+		{
+			name:        "ScheduledWithoutState",
+			storageTask: &service.Task{ID: "tid", Status: service.StateScheduled},
+			noBackend:   true,
+			wantStatus:  service.StateScheduled,
 		},
 	}
 
@@ -192,12 +583,12 @@ func TestGetTaskStatus(t *testing.T) {
 			srv := new(MockServer)
 			be := new(MockBackend)
 			st.On("GetTask", mock.Anything, "tid").Return(c.storageTask, c.storageErr)
-			if c.storageErr == nil {
+			if c.storageErr == nil && !c.noBackend {
 				srv.On("GetBackend").Return(be)
 				be.On("GetState", "tid").Return(c.state, c.stateErr)
 			}
 
-			svc := service.NewRunnerService(srv, st)
+			svc := service.NewRunnerService(srv, st, newTestRegistry(t))
 			resp, err := svc.GetTaskStatus(context.Background(), "tid")
 
 			if c.wantErr {
@@ -215,15 +606,877 @@ func TestGetTaskStatus(t *testing.T) {
 	}
 }
 
+func TestGetTasks_Cursor(t *testing.T) {
+	srv := new(MockServer)
+	st := new(MockStorage)
+	svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+
+	createdAt := time.Date(2025, 4, 21, 15, 10, 0, 0, time.UTC)
+	first := service.Task{ID: "task_b", Status: tasks.StatePending, CreatedAt: createdAt}
+	second := service.Task{ID: "task_a", Status: tasks.StatePending, CreatedAt: createdAt}
+
+	st.On("GetTasks", mock.Anything, service.TaskQuery{Statuses: []string{tasks.StatePending}, Limit: 2, Order: service.OrderDesc}).
+		Return(&service.TaskPage{Tasks: []service.Task{first, second}, Total: 3, HasMore: true}, nil).Once()
+
+	page, err := svc.GetTasks(context.Background(), v1.TaskListRequest{Statuses: []string{tasks.StatePending}, Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, page.Tasks, 2)
+	assert.Equal(t, 3, page.Total)
+	assert.Equal(t, service.OrderDesc, page.Order)
+	assert.NotEmpty(t, page.NextCursor)
+
+	after := service.CursorOf(second)
+	st.On("GetTasks", mock.Anything, service.TaskQuery{Statuses: []string{tasks.StatePending}, Limit: 2, Order: service.OrderDesc, After: &after}).
+		Return(&service.TaskPage{Tasks: []service.Task{{ID: "task_c", CreatedAt: createdAt.Add(-time.Second)}}, Total: 3}, nil).Once()
+
+	page, err = svc.GetTasks(context.Background(), v1.TaskListRequest{Statuses: []string{tasks.StatePending}, Limit: 2, Cursor: page.NextCursor})
+	assert.NoError(t, err)
+	assert.Len(t, page.Tasks, 1)
+	assert.Empty(t, page.NextCursor)
+	st.AssertExpectations(t)
+}
+
+func TestGetTasks_InvalidQuery(t *testing.T) {
+	svc := service.NewRunnerService(new(MockServer), new(MockStorage), newTestRegistry(t))
+
+	_, err := svc.GetTasks(context.Background(), v1.TaskListRequest{Limit: 1000, Order: "sideways", Cursor: "%%%"})
+	var validationErr *domain.ValidationError
+	if assert.ErrorAs(t, err, &validationErr) {
+		var fields []string
+		for _, fieldErr := range validationErr.Errors {
+			fields = append(fields, fieldErr.Field)
+		}
+		assert.Equal(t, []string{"limit", "order", "cursor"}, fields)
+	}
+}
+
+func TestGetTasks_Filters(t *testing.T) {
+	st := new(MockStorage)
+	svc := service.NewRunnerService(new(MockServer), st, newTestRegistry(t))
+
+	from := time.Date(2025, 4, 21, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	st.On("GetTasks", mock.Anything, service.TaskQuery{
+		Statuses:      []string{tasks.StateFailure},
+		Labels:        map[string]string{"tenant": "acme"},
+		FinishedFrom:  &from,
+		FinishedTo:    &to,
+		ErrorContains: "timeout",
+		Limit:         service.DefaultPageSize,
+		Order:         service.OrderDesc,
+	}).Return(&service.TaskPage{Tasks: []service.Task{}}, nil).Once()
+
+	_, err := svc.GetTasks(context.Background(), v1.TaskListRequest{
+		Statuses:       []string{tasks.StateFailure},
+		Labels:         map[string]string{"tenant": "acme"},
+		FinishedAfter:  "2025-04-21T00:00:00Z",
+		FinishedBefore: "2025-04-22T00:00:00Z",
+		Error:          "timeout",
+	})
+	assert.NoError(t, err)
+	st.AssertExpectations(t)
+
+	_, err = svc.GetTasks(context.Background(), v1.TaskListRequest{CreatedAfter: "yesterday", FinishedBefore: "2025-04-22"})
+	var validationErr *domain.ValidationError
+	if assert.ErrorAs(t, err, &validationErr) {
+		var fields []string
+		for _, fieldErr := range validationErr.Errors {
+			fields = append(fields, fieldErr.Field)
+		}
+		assert.Equal(t, []string{"created_after", "finished_before"}, fields)
+	}
+}
+
+func TestSendTask_Labels(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i < 17; i++ {
+		tooMany[fmt.Sprintf("key%d", i)] = "value"
+	}
+
+	cases := []struct {
+		name      string
+		labels    map[string]string
+		wantField string
+	}{
+		{"Valid", map[string]string{"tenant": "acme", "team.name": "core"}, ""},
+		{"InvalidKey", map[string]string{"bad key": "value"}, "labels.bad key"},
+		{"EmptyValue", map[string]string{"tenant": ""}, "labels.tenant"},
+		{"TooMany", tooMany, "labels"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := new(MockStorage)
+			srv := new(MockServer)
+			svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+
+			if c.wantField == "" {
+				st.On("SaveTask", mock.Anything, mock.MatchedBy(func(task service.Task) bool {
+					return assert.ObjectsAreEqual(c.labels, task.Labels)
+				})).Return(nil)
+				srv.On("SendTask", mock.AnythingOfType("*tasks.Signature")).Return((*result.AsyncResult)(nil), nil)
+			}
+
+			_, err := svc.SendTask(context.Background(), v1.TaskRequest{Name: "n", Labels: c.labels})
+			if c.wantField == "" {
+				assert.NoError(t, err)
+				st.AssertExpectations(t)
+				return
+			}
+
+			var validationErr *domain.ValidationError
+			if assert.ErrorAs(t, err, &validationErr) {
+				assert.Equal(t, c.wantField, validationErr.Errors[0].Field)
+			}
+			st.AssertNotCalled(t, "SaveTask", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCancelTask(t *testing.T) {
+	cases := []struct {
+		name        string
+		storageTask *service.Task
+		storageErr  error
+		wantErr     error
+	}{
+		{
+			name:        "Pending",
+			storageTask: &service.Task{ID: "tid", Status: tasks.StatePending},
+		},
+		{
+			name:        "Started",
+			storageTask: &service.Task{ID: "tid", Status: tasks.StateStarted},
+		},
+		{
+			name:        "AlreadyFinished",
+			storageTask: &service.Task{ID: "tid", Status: tasks.StateSuccess},
+			wantErr:     domain.TaskFinished,
+		},
+		{
+			name:       "NotFound",
+			storageErr: domain.TaskNotFound,
+			wantErr:    domain.TaskNotFound,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := new(MockStorage)
+			srv := new(MockServer)
+			st.On("GetTask", mock.Anything, "tid").Return(c.storageTask, c.storageErr)
+			if c.wantErr == nil {
+				st.On("SaveTask", mock.Anything, mock.MatchedBy(func(task service.Task) bool {
+					return task.Status == service.StateCancelled && task.FinishedAt != nil
+				})).Return(nil)
+				st.On("PublishCancellation", mock.Anything, "tid").Return(nil)
+			}
+
+			svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+			resp, err := svc.CancelTask(context.Background(), "tid")
+
+			if c.wantErr != nil {
+				assert.ErrorIs(t, err, c.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, service.StateCancelled, resp.Status)
+			}
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSendWorkflow(t *testing.T) {
+	echo := v1.WorkflowStep{Name: "echo", Args: []tasks.Arg{{Type: "string", Value: "hi"}}}
+
+	cases := []struct {
+		name       string
+		req        v1.WorkflowRequest
+		expect     func(srv *MockServer)
+		wantSteps  int
+		wantQueue  string
+		wantFields []string
+	}{
+		{
+			name: "Chain",
+			req: v1.WorkflowRequest{Type: "chain", Tasks: []v1.WorkflowStep{
+				echo,
+				{Name: "echo", PassResults: true},
+			}},
+			expect: func(srv *MockServer) {
+				srv.On("SendChain", mock.MatchedBy(func(chain *tasks.Chain) bool {
+					return len(chain.Tasks) == 2 &&
+						chain.Tasks[0].OnSuccess[0] == chain.Tasks[1] &&
+						chain.Tasks[0].Immutable && !chain.Tasks[1].Immutable
+				})).Return((*result.ChainAsyncResult)(nil), nil)
+			},
+			wantSteps: 2,
+		},
+		{
+			name: "Group",
+			req:  v1.WorkflowRequest{Type: "group", Tasks: []v1.WorkflowStep{echo, echo, echo}},
+			expect: func(srv *MockServer) {
+				srv.On("SendGroup", mock.MatchedBy(func(group *tasks.Group) bool {
+					return len(group.Tasks) == 3 && group.Tasks[0].GroupTaskCount == 3
+				}), mock.Anything).Return([]*result.AsyncResult(nil), nil)
+			},
+			wantSteps: 3,
+		},
+		{
+			name: "Chord",
+			req: v1.WorkflowRequest{
+				Type:     "chord",
+				Tasks:    []v1.WorkflowStep{echo, echo},
+				Callback: &v1.WorkflowStep{Name: "sum", Args: []tasks.Arg{{Type: "[]int64", Value: []interface{}{1.0}}}},
+			},
+			expect: func(srv *MockServer) {
+				srv.On("SendChord", mock.MatchedBy(func(chord *tasks.Chord) bool {
+					return chord.Callback.Name == "sum" && chord.Group.Tasks[1].ChordCallback == chord.Callback
+				}), mock.Anything).Return((*result.ChordAsyncResult)(nil), nil)
+			},
+			wantSteps: 3,
+		},
+		{
+			name: "Queue",
+			req:  v1.WorkflowRequest{Type: "group", Tasks: []v1.WorkflowStep{echo, echo}, Queue: "fast"},
+			expect: func(srv *MockServer) {
+				srv.On("SendGroup", mock.MatchedBy(func(group *tasks.Group) bool {
+					return group.Tasks[0].RoutingKey == "fast" && group.Tasks[1].RoutingKey == "fast"
+				}), mock.Anything).Return([]*result.AsyncResult(nil), nil)
+			},
+			wantSteps: 2,
+			wantQueue: "fast",
+		},
+		{
+			name:       "UnknownType",
+			req:        v1.WorkflowRequest{Type: "pipeline", Tasks: []v1.WorkflowStep{echo}},
+			wantFields: []string{"type"},
+		},
+		{
+			name:       "ChordWithoutCallback",
+			req:        v1.WorkflowRequest{Type: "chord", Tasks: []v1.WorkflowStep{echo}},
+			wantFields: []string{"callback"},
+		},
+		{
+			name: "InvalidStep",
+			req: v1.WorkflowRequest{Type: "group", Tasks: []v1.WorkflowStep{
+				echo,
+				{Name: "missing"},
+				{Name: "echo"},
+			}},
+			wantFields: []string{"tasks[1].name", "tasks[2].args[0]"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := new(MockStorage)
+			srv := new(MockServer)
+			taskRegistry := registry.New()
+			assert.NoError(t, registry.RegisterBuiltins(taskRegistry))
+
+			if c.expect != nil {
+				c.expect(srv)
+				st.On("SaveTask", mock.Anything, mock.MatchedBy(func(task service.Task) bool {
+					return task.Status == tasks.StatePending && task.WorkflowID != "" &&
+						task.Queue == c.wantQueue && task.Priority == service.PriorityNormal
+				})).Return(nil).Times(c.wantSteps)
+				st.On("SaveWorkflow", mock.Anything, mock.MatchedBy(func(workflow service.Workflow) bool {
+					return len(workflow.TaskIDs) == c.wantSteps
+				})).Return(nil)
+			}
+
+			srv.On("GetConfig").Return(&config.Config{DefaultQueue: "machinery_tasks"}).Maybe()
+			svc := service.NewRunnerService(srv, st, taskRegistry)
+			assert.NoError(t, svc.SetQueues([]service.Queue{
+				{Name: "machinery_tasks", Concurrency: 10, Enabled: true},
+				{Name: "fast", Concurrency: 2, Enabled: true},
+			}))
+			resp, err := svc.SendWorkflow(context.Background(), c.req)
+
+			if c.wantFields != nil {
+				var validationErr *domain.ValidationError
+				if assert.ErrorAs(t, err, &validationErr) {
+					var fields []string
+					for _, fieldErr := range validationErr.Errors {
+						fields = append(fields, fieldErr.Field)
+					}
+					assert.Equal(t, c.wantFields, fields)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tasks.StatePending, resp.Status)
+				assert.Len(t, resp.Steps, c.wantSteps)
+				assert.Equal(t, c.wantSteps, resp.Progress.Pending)
+			}
+			srv.AssertExpectations(t)
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetWorkflow(t *testing.T) {
+	cases := []struct {
+		name        string
+		statuses    []string
+		wantStatus  string
+		wantPercent float64
+	}{
+		{"Pending", []string{tasks.StatePending, tasks.StatePending}, tasks.StatePending, 0},
+		{"Running", []string{tasks.StateSuccess, tasks.StateStarted}, tasks.StateStarted, 50},
+		{"Success", []string{tasks.StateSuccess, tasks.StateSuccess}, tasks.StateSuccess, 100},
+		{"Failure", []string{tasks.StateFailure, tasks.StatePending}, tasks.StateFailure, 50},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := new(MockStorage)
+			workflow := &service.Workflow{ID: "wf", Type: "chain"}
+			for i, status := range c.statuses {
+				id := fmt.Sprintf("t%d", i)
+				workflow.TaskIDs = append(workflow.TaskIDs, id)
+				st.On("GetTask", mock.Anything, id).Return(&service.Task{ID: id, Status: status}, nil)
+			}
+			st.On("GetWorkflow", mock.Anything, "wf").Return(workflow, nil)
+
+			svc := service.NewRunnerService(new(MockServer), st, newTestRegistry(t))
+			resp, err := svc.GetWorkflow(context.Background(), "wf")
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.wantStatus, resp.Status)
+			assert.Equal(t, c.wantPercent, resp.Progress.Percent)
+			assert.Len(t, resp.Steps, len(c.statuses))
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateSchedule(t *testing.T) {
+	cases := []struct {
+		name       string
+		req        v1.ScheduleRequest
+		wantFields []string
+	}{
+		{
+			name: "Valid",
+			req:  v1.ScheduleRequest{Spec: "*/5 * * * *", Name: "n"},
+		},
+		{
+			name:       "InvalidSpec",
+			req:        v1.ScheduleRequest{Spec: "every minute", Name: "n"},
+			wantFields: []string{"spec"},
+		},
+		{
+			name:       "UnknownTaskAndInvalidSpec",
+			req:        v1.ScheduleRequest{Spec: "* *", Name: "unknown"},
+			wantFields: []string{"spec", "task.name"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := new(MockStorage)
+			if c.wantFields == nil {
+				st.On("SaveSchedule", mock.Anything, mock.MatchedBy(func(schedule service.Schedule) bool {
+					return schedule.Spec == c.req.Spec && schedule.Name == c.req.Name && !schedule.Paused
+				})).Return(nil)
+			}
+
+			svc := service.NewRunnerService(new(MockServer), st, newTestRegistry(t))
+			resp, err := svc.CreateSchedule(context.Background(), c.req)
+
+			if c.wantFields != nil {
+				var validationErr *domain.ValidationError
+				assert.ErrorAs(t, err, &validationErr)
+				var fields []string
+				for _, fieldErr := range validationErr.Errors {
+					fields = append(fields, fieldErr.Field)
+				}
+				assert.Equal(t, c.wantFields, fields)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, service.ScheduleActive, resp.Status)
+				assert.NotEmpty(t, resp.NextRunAt)
+			}
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPauseResumeSchedule(t *testing.T) {
+	st := new(MockStorage)
+	st.On("GetSchedule", mock.Anything, "sid").Return(&service.Schedule{ID: "sid", Spec: "* * * * *", Name: "n"}, nil).Once()
+	st.On("SaveSchedule", mock.Anything, mock.MatchedBy(func(schedule service.Schedule) bool {
+		return schedule.Paused
+	})).Return(nil).Once()
+
+	svc := service.NewRunnerService(new(MockServer), st, newTestRegistry(t))
+	resp, err := svc.PauseSchedule(context.Background(), "sid")
+	assert.NoError(t, err)
+	assert.Equal(t, service.SchedulePaused, resp.Status)
+	assert.Empty(t, resp.NextRunAt)
+
+	st.On("GetSchedule", mock.Anything, "sid").Return(&service.Schedule{ID: "sid", Spec: "* * * * *", Name: "n", Paused: true}, nil).Once()
+	st.On("SaveSchedule", mock.Anything, mock.MatchedBy(func(schedule service.Schedule) bool {
+		return !schedule.Paused
+	})).Return(nil).Once()
+
+	resp, err = svc.ResumeSchedule(context.Background(), "sid")
+	assert.NoError(t, err)
+	assert.Equal(t, service.ScheduleActive, resp.Status)
+	st.AssertExpectations(t)
+}
+
+func TestRunSchedule(t *testing.T) {
+	cases := []struct {
+		name    string
+		paused  bool
+		sendErr error
+	}{
+		{name: "Active"},
+		{name: "Paused", paused: true},
+		{name: "SendFailed", sendErr: errors.New("broker down")},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := new(MockStorage)
+			srv := new(MockServer)
+			st.On("GetSchedule", mock.Anything, "sid").Return(&service.Schedule{
+				ID: "sid", Spec: "* * * * *", Name: "n", Paused: c.paused,
+			}, nil)
+			if !c.paused {
+				st.On("SaveTask", mock.Anything, mock.Anything).Return(nil)
+				srv.On("SendTask", mock.Anything).Return(&result.AsyncResult{}, c.sendErr)
+				st.On("AddScheduleRun", mock.Anything, "sid", mock.MatchedBy(func(run service.ScheduleRun) bool {
+					if c.sendErr != nil {
+						return run.TaskID == "" && run.Error != ""
+					}
+					return run.TaskID != "" && run.Error == ""
+				}), 100).Return(nil)
+			}
+
+			svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+			err := svc.RunSchedule(context.Background(), "sid")
+
+			if c.sendErr != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			st.AssertExpectations(t)
+			srv.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetSchedule(t *testing.T) {
+	firedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	st := new(MockStorage)
+	st.On("GetSchedule", mock.Anything, "sid").Return(&service.Schedule{ID: "sid", Spec: "@hourly", Name: "n"}, nil)
+	st.On("GetScheduleRuns", mock.Anything, "sid", 100).Return([]service.ScheduleRun{
+		{TaskID: "t2", FiredAt: firedAt},
+		{TaskID: "t1", FiredAt: firedAt.Add(-time.Hour)},
+	}, nil)
+
+	svc := service.NewRunnerService(new(MockServer), st, newTestRegistry(t))
+	resp, err := svc.GetSchedule(context.Background(), "sid")
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.History, 2)
+	assert.Equal(t, "t2", resp.History[0].TaskID)
+	assert.Equal(t, firedAt.Format(time.RFC3339), resp.LastRunAt)
+}
+
+func TestGetTaskTypes(t *testing.T) {
+	taskRegistry := registry.New()
+	assert.NoError(t, registry.RegisterBuiltins(taskRegistry))
+	assert.NoError(t, taskRegistry.SetRetryPolicy("sleep", registry.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     registry.BackoffExponential,
+		Interval:    2 * time.Second,
+		MaxInterval: 30 * time.Second,
+		RetryOn:     []string{registry.ErrorClassTimeout},
+	}))
+
+	svc := service.NewRunnerService(new(MockServer), new(MockStorage), taskRegistry)
+	types, err := svc.GetTaskTypes(context.Background())
+
+	defaultRetry := v1.RetryPolicy{MaxAttempts: 1, Backoff: "fixed", Interval: "1s"}
+	assert.NoError(t, err)
+	assert.Equal(t, []v1.TaskTypeResponse{
+		{Name: "echo", Args: []v1.TaskArgSpec{
+			{Name: "message", Type: "string", Required: true, Min: registry.Bound(1)},
+		}, Retry: defaultRetry},
+		{Name: "sleep", Args: []v1.TaskArgSpec{
+			{Name: "seconds", Type: "int64", Default: int64(1), Min: registry.Bound(0), Max: registry.Bound(3600)},
+		}, Retry: v1.RetryPolicy{
+			MaxAttempts: 3, Backoff: "exponential", Interval: "2s", MaxInterval: "30s", RetryOn: []string{"timeout"},
+		}},
+		{Name: "sum", Args: []v1.TaskArgSpec{
+			{Name: "numbers", Type: "[]int64", Required: true, Min: registry.Bound(1)},
+		}, Retry: defaultRetry},
+	}, types)
+}
+
+func TestSendTask_RetryPolicy(t *testing.T) {
+	cases := []struct {
+		name           string
+		override       *v1.RetryPolicy
+		wantRetryCount int
+		wantTimeout    int
+		wantFields     []string
+	}{
+		{
+			name:           "TypeDefault",
+			wantRetryCount: 2,
+			wantTimeout:    2,
+		},
+		{
+			name:           "Override",
+			override:       &v1.RetryPolicy{MaxAttempts: 5, Interval: "10s"},
+			wantRetryCount: 4,
+			wantTimeout:    10,
+		},
+		{
+			name:       "InvalidOverride",
+			override:   &v1.RetryPolicy{MaxAttempts: -1, Backoff: "linear", Interval: "soon"},
+			wantFields: []string{"retry.interval"},
+		},
+		{
+			name:       "InvalidPolicy",
+			override:   &v1.RetryPolicy{MaxAttempts: -1, Backoff: "linear"},
+			wantFields: []string{"retry.max_attempts", "retry.backoff"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			taskRegistry := newTestRegistry(t)
+			assert.NoError(t, taskRegistry.SetRetryPolicy("n", registry.RetryPolicy{
+				MaxAttempts: 3,
+				Backoff:     registry.BackoffFixed,
+				Interval:    2 * time.Second,
+			}))
+
+			st := new(MockStorage)
+			srv := new(MockServer)
+			if c.wantFields == nil {
+				st.On("SaveTask", mock.Anything, mock.MatchedBy(func(task service.Task) bool {
+					return task.Retry.MaxAttempts == c.wantRetryCount+1
+				})).Return(nil)
+				srv.On("SendTask", mock.MatchedBy(func(sig *tasks.Signature) bool {
+					return sig.RetryCount == c.wantRetryCount && sig.RetryTimeout == c.wantTimeout
+				})).Return(&result.AsyncResult{}, nil)
+			}
+
+			svc := service.NewRunnerService(srv, st, taskRegistry)
+			resp, err := svc.SendTask(context.Background(), v1.TaskRequest{Name: "n", Retry: c.override})
+
+			if c.wantFields != nil {
+				var validationErr *domain.ValidationError
+				assert.ErrorAs(t, err, &validationErr)
+				var fields []string
+				for _, fieldErr := range validationErr.Errors {
+					fields = append(fields, fieldErr.Field)
+				}
+				assert.Equal(t, c.wantFields, fields)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, c.wantRetryCount+1, resp.MaxAttempts)
+			}
+			st.AssertExpectations(t)
+			srv.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetTaskStatus_Retrying(t *testing.T) {
+	failedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	nextRetryAt := failedAt.Add(4 * time.Second)
+	st := new(MockStorage)
+	srv := new(MockServer)
+	st.On("GetTask", mock.Anything, "tid").Return(&service.Task{
+		ID:          "tid",
+		Status:      tasks.StateRetry,
+		Retry:       registry.RetryPolicy{MaxAttempts: 3},
+		Attempt:     2,
+		NextRetryAt: &nextRetryAt,
+		Attempts: []service.Attempt{
+			{Number: 1, Error: "connection refused", ErrorClass: "error", FailedAt: failedAt.Add(-time.Second)},
+			{Number: 2, Error: "i/o timeout", ErrorClass: "timeout", FailedAt: failedAt},
+		},
+	}, nil)
+
+	svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+	resp, err := svc.GetTaskStatus(context.Background(), "tid")
+
+	assert.NoError(t, err)
+	assert.Equal(t, tasks.StateRetry, resp.Status)
+	assert.Equal(t, 2, resp.Attempt)
+	assert.Equal(t, 3, resp.MaxAttempts)
+	assert.Equal(t, nextRetryAt.Format(time.RFC3339), resp.NextRetryAt)
+	assert.Equal(t, []v1.AttemptResponse{
+		{Attempt: 1, Error: "connection refused", ErrorClass: "error", FailedAt: "2024-01-01T11:59:59Z"},
+		{Attempt: 2, Error: "i/o timeout", ErrorClass: "timeout", FailedAt: "2024-01-01T12:00:00Z"},
+	}, resp.Attempts)
+}
+
+func TestRegistryValidate(t *testing.T) {
+	taskRegistry := registry.New()
+	assert.NoError(t, registry.RegisterBuiltins(taskRegistry))
+
+	cases := []struct {
+		name    string
+		task    string
+		args    []tasks.Arg
+		wantErr bool
+	}{
+		{"Valid", "sum", []tasks.Arg{{Type: "[]int64", Value: []interface{}{1.0, 2.0}}}, false},
+		{"TypeFromSchema", "echo", []tasks.Arg{{Value: "hi"}}, false},
+		{"DefaultApplied", "sleep", nil, false},
+		{"UnknownTask", "missing", nil, true},
+		{"WrongCount", "echo", nil, true},
+		{"WrongType", "echo", []tasks.Arg{{Type: "int64", Value: 1.0}}, true},
+		{"NotInteger", "sleep", []tasks.Arg{{Type: "int64", Value: 1.5}}, true},
+		{"EmptySlice", "sum", []tasks.Arg{{Type: "[]int64", Value: []interface{}{}}}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			args, err := taskRegistry.Validate(c.task, c.args)
+			if c.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, args, 1)
+			}
+		})
+	}
+}
+
+func TestSendTask_IdempotencyKey(t *testing.T) {
+	st := new(MockStorage)
+	srv := new(MockServer)
+
+	reserved := &service.IdempotencyRecord{}
+	st.On("ReserveIdempotencyKey", mock.Anything, mock.Anything, service.IdempotencyTTL).
+		Return((*service.IdempotencyRecord)(nil), nil).
+		Run(func(args mock.Arguments) { *reserved = args.Get(1).(service.IdempotencyRecord) }).
+		Once()
+	st.On("ReserveIdempotencyKey", mock.Anything, mock.Anything, service.IdempotencyTTL).Return(reserved, nil)
+	st.On("SaveTask", mock.Anything, mock.Anything).Return(nil).Once()
+	srv.On("SendTask", mock.Anything).Return(&result.AsyncResult{}, nil).Once()
+
+	svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+	req := v1.TaskRequest{Name: "n", IdempotencyKey: "key-1"}
+
+	first, err := svc.SendTask(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "key-1", reserved.Key)
+	assert.Equal(t, first.ID, reserved.TaskID)
+
+	st.On("GetTask", mock.Anything, first.ID).Return(&service.Task{ID: first.ID, Status: tasks.StateSuccess}, nil)
+	repeated, err := svc.SendTask(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, repeated.ID)
+	assert.Equal(t, tasks.StateSuccess, repeated.Status)
+
+	req.Queue = "other"
+	_, err = svc.SendTask(context.Background(), req)
+	assert.ErrorIs(t, err, domain.IdempotencyConflict)
+
+	st.AssertExpectations(t)
+	srv.AssertExpectations(t)
+}
+
+func TestSendTask_IdempotencyKeyReleasedOnFailure(t *testing.T) {
+	st := new(MockStorage)
+	srv := new(MockServer)
+	st.On("ReserveIdempotencyKey", mock.Anything, mock.Anything, service.IdempotencyTTL).
+		Return((*service.IdempotencyRecord)(nil), nil)
+	st.On("SaveTask", mock.Anything, mock.Anything).Return(nil)
+	srv.On("SendTask", mock.Anything).Return(&result.AsyncResult{}, errors.New("broker down"))
+	st.On("ReleaseIdempotencyKey", mock.Anything, "key-1").Return(nil)
+
+	svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+	_, err := svc.SendTask(context.Background(), v1.TaskRequest{Name: "n", IdempotencyKey: "key-1"})
+
+	assert.Error(t, err)
+	st.AssertExpectations(t)
+}
+
+func TestRequeueDeadLetter(t *testing.T) {
+	retry := registry.RetryPolicy{MaxAttempts: 3, Backoff: registry.BackoffFixed, Interval: time.Second}
+	letter := &service.DeadLetter{
+		ID:    "tid",
+		Name:  "n",
+		Error: "boom",
+		Retry: retry,
+		Signature: tasks.Signature{
+			UUID:      "tid",
+			Name:      "n",
+			Headers:   tasks.Headers{service.ScheduledAtHeader: "2024-01-01T00:00:00Z"},
+			OnSuccess: []*tasks.Signature{{UUID: "next", Name: "n"}},
+		},
+	}
+	failedAt := time.Now()
+
+	cases := []struct {
+		name    string
+		sendErr error
+	}{
+		{name: "Success"},
+		{name: "SendFailed", sendErr: errors.New("broker down")},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := new(MockStorage)
+			srv := new(MockServer)
+			st.On("GetDeadLetter", mock.Anything, "tid").Return(letter, nil)
+			st.On("GetTask", mock.Anything, "tid").Return(&service.Task{
+				ID: "tid", Name: "n", Status: tasks.StateFailure, Error: "boom", FinishedAt: &failedAt,
+				Attempts: []service.Attempt{{Number: 1}, {Number: 2}, {Number: 3}},
+			}, nil)
+			st.On("SaveTask", mock.Anything, mock.MatchedBy(func(task service.Task) bool {
+				return task.Status == tasks.StatePending && task.Attempts == nil && task.FinishedAt == nil
+			})).Return(nil).Once()
+			srv.On("SendTask", mock.MatchedBy(func(sig *tasks.Signature) bool {
+				_, scheduled := sig.Headers[service.ScheduledAtHeader]
+				return sig.UUID == "tid" && sig.RetryCount == 2 && !scheduled && len(sig.OnSuccess) == 1
+			})).Return(&result.AsyncResult{}, c.sendErr)
+			if c.sendErr != nil {
+				st.On("SaveTask", mock.Anything, mock.MatchedBy(func(task service.Task) bool {
+					return task.Status == tasks.StateFailure && task.Error == "boom"
+				})).Return(nil).Once()
+			} else {
+				st.On("DeleteDeadLetter", mock.Anything, "tid").Return(nil)
+			}
+
+			svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+			resp, err := svc.RequeueDeadLetter(context.Background(), "tid")
+
+			if c.sendErr != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tasks.StatePending, resp.Status)
+			}
+			st.AssertExpectations(t)
+			srv.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRequeueDeadLetter_WithoutSignature(t *testing.T) {
+	st := new(MockStorage)
+	srv := new(MockServer)
+	st.On("GetDeadLetter", mock.Anything, "tid").Return(&service.DeadLetter{
+		ID: "tid", Name: "n", Queue: "slow", Error: "boom",
+	}, nil)
+	st.On("GetTask", mock.Anything, "tid").Return(&service.Task{
+		ID: "tid", Name: "n", Queue: "slow", Priority: service.PriorityHigh, Status: tasks.StateFailure,
+	}, nil)
+	st.On("SaveTask", mock.Anything, mock.Anything).Return(nil)
+	st.On("DeleteDeadLetter", mock.Anything, "tid").Return(nil)
+
+	_, priority, _ := service.ParsePriority(service.PriorityHigh)
+	srv.On("SendTask", mock.MatchedBy(func(sig *tasks.Signature) bool {
+		return sig.UUID == "tid" && sig.RoutingKey == "slow.high" && sig.Priority == priority
+	})).Return(&result.AsyncResult{}, nil)
+
+	svc := service.NewRunnerService(srv, st, newTestRegistry(t))
+	_, err := svc.RequeueDeadLetter(context.Background(), "tid")
+	assert.NoError(t, err)
+	srv.AssertExpectations(t)
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := registry.RetryPolicy{
+		MaxAttempts: 4,
+		Backoff:     registry.BackoffExponential,
+		Interval:    time.Second,
+		MaxInterval: 3 * time.Second,
+		RetryOn:     []string{registry.ErrorClassTimeout, "rate_limited"},
+	}
+
+	assert.Equal(t, time.Second, policy.NextInterval(1))
+	assert.Equal(t, 2*time.Second, policy.NextInterval(2))
+	assert.Equal(t, 3*time.Second, policy.NextInterval(3))
+
+	assert.True(t, policy.ShouldRetry(context.DeadlineExceeded, 1))
+	assert.True(t, policy.ShouldRetry(registry.WithClass("rate_limited", errors.New("429")), 3))
+	assert.False(t, policy.ShouldRetry(context.DeadlineExceeded, 4))
+	assert.False(t, policy.ShouldRetry(errors.New("bad input"), 1))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		interval := policy.NextInterval(1)
+		assert.GreaterOrEqual(t, interval, 500*time.Millisecond)
+		assert.LessOrEqual(t, interval, 1500*time.Millisecond)
+	}
+
+	assert.Equal(t, registry.ErrorClassDefault, registry.ErrorClass(errors.New("boom")))
+	assert.Equal(t, registry.ErrorClassTimeout, registry.ErrorClass(fmt.Errorf("call: %w", context.DeadlineExceeded)))
+}
+
+func newTestRegistry(t testing.TB) *registry.TaskRegistry {
+	taskRegistry := registry.New()
+	err := taskRegistry.Register("n", func() (string, error) { return "", nil })
+	assert.NoError(t, err)
+	return taskRegistry
+}
+
 type stubStorage struct{}
 
 func (s *stubStorage) SaveTask(ctx context.Context, task service.Task) error { return nil }
 func (s *stubStorage) GetTask(ctx context.Context, id string) (*service.Task, error) {
 	return &service.Task{ID: id}, nil
 }
-func (s *stubStorage) GetTasks(ctx context.Context, status string, limit, offset int) ([]service.Task, error) {
+func (s *stubStorage) UpdateTask(ctx context.Context, id string, update func(task *service.Task) error) (*service.Task, error) {
+	task := &service.Task{ID: id}
+	return task, update(task)
+}
+func (s *stubStorage) GetTasks(ctx context.Context, query service.TaskQuery) (*service.TaskPage, error) {
+	return &service.TaskPage{}, nil
+}
+func (s *stubStorage) PublishCancellation(ctx context.Context, id string) error { return nil }
+func (s *stubStorage) SubscribeCancellations(ctx context.Context) (<-chan string, error) {
 	return nil, nil
 }
+func (s *stubStorage) SaveWorkflow(ctx context.Context, workflow service.Workflow) error { return nil }
+func (s *stubStorage) GetWorkflow(ctx context.Context, id string) (*service.Workflow, error) {
+	return &service.Workflow{ID: id}, nil
+}
+func (s *stubStorage) SaveSchedule(ctx context.Context, schedule service.Schedule) error { return nil }
+func (s *stubStorage) GetSchedule(ctx context.Context, id string) (*service.Schedule, error) {
+	return &service.Schedule{ID: id}, nil
+}
+func (s *stubStorage) GetSchedules(ctx context.Context) ([]service.Schedule, error) { return nil, nil }
+func (s *stubStorage) DeleteSchedule(ctx context.Context, id string) error          { return nil }
+func (s *stubStorage) AddScheduleRun(ctx context.Context, id string, run service.ScheduleRun, limit int) error {
+	return nil
+}
+func (s *stubStorage) GetScheduleRuns(ctx context.Context, id string, limit int) ([]service.ScheduleRun, error) {
+	return nil, nil
+}
+func (s *stubStorage) SaveDeadLetter(ctx context.Context, letter service.DeadLetter) error {
+	return nil
+}
+func (s *stubStorage) GetDeadLetter(ctx context.Context, id string) (*service.DeadLetter, error) {
+	return &service.DeadLetter{ID: id}, nil
+}
+func (s *stubStorage) GetDeadLetters(ctx context.Context, name string, limit, offset int) ([]service.DeadLetter, int, error) {
+	return nil, 0, nil
+}
+func (s *stubStorage) DeleteDeadLetter(ctx context.Context, id string) error          { return nil }
+func (s *stubStorage) PurgeDeadLetters(ctx context.Context, name string) (int, error) { return 0, nil }
+func (s *stubStorage) ReserveIdempotencyKey(ctx context.Context, record service.IdempotencyRecord, ttl time.Duration) (*service.IdempotencyRecord, error) {
+	return nil, nil
+}
+func (s *stubStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error { return nil }
+func (s *stubStorage) GetQueueDepth(ctx context.Context) (map[string]int, error)   { return nil, nil }
 
 type stubBackend struct{}
 
@@ -251,7 +1504,19 @@ type stubServer struct{ backend iface.Backend }
 func (s *stubServer) SendTask(sig *tasks.Signature) (*result.AsyncResult, error) {
 	return result.NewAsyncResult(sig, s.backend), nil
 }
+func (s *stubServer) SendChain(chain *tasks.Chain) (*result.ChainAsyncResult, error) {
+	return result.NewChainAsyncResult(chain.Tasks, s.backend), nil
+}
+func (s *stubServer) SendGroup(group *tasks.Group, sendConcurrency int) ([]*result.AsyncResult, error) {
+	return nil, nil
+}
+func (s *stubServer) SendChord(chord *tasks.Chord, sendConcurrency int) (*result.ChordAsyncResult, error) {
+	return result.NewChordAsyncResult(chord.Group.Tasks, chord.Callback, s.backend), nil
+}
 func (s *stubServer) GetBackend() iface.Backend { return s.backend }
+func (s *stubServer) GetConfig() *config.Config {
+	return &config.Config{DefaultQueue: "machinery_tasks"}
+}
 
 var _ service.MachineryServer = (*stubServer)(nil)
 
@@ -259,12 +1524,12 @@ func BenchmarkSendTask(b *testing.B) {
 	st := &stubStorage{}
 	be := &stubBackend{}
 	srv := &stubServer{backend: be}
-	svc := service.NewRunnerService(srv, st)
+	svc := service.NewRunnerService(srv, st, newTestRegistry(b))
 
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := svc.SendTask(context.Background(), fmt.Sprintf("bench-%d", i), nil, "")
+		_, err := svc.SendTask(context.Background(), v1.TaskRequest{Name: "n"})
 		if err != nil {
 			b.Fatalf("failed: %v", err)
 		}
@@ -12,9 +12,9 @@ type MockTaskService struct {
 	mock.Mock
 }
 
-func (m *MockTaskService) SendTask(ctx context.Context, name string, args []tasks.Arg, queue string) (string, error) {
-	argsList := m.Called(ctx, name, args, queue)
-	return argsList.String(0), argsList.Error(1)
+func (m *MockTaskService) SendTask(ctx context.Context, req v1.TaskRequest) (*v1.TaskResponse, error) {
+	argsList := m.Called(ctx, req)
+	return argsList.Get(0).(*v1.TaskResponse), argsList.Error(1)
 }
 
 func (m *MockTaskService) GetTaskStatus(ctx context.Context, id string) (*v1.TaskResponse, error) {
@@ -22,13 +22,101 @@ func (m *MockTaskService) GetTaskStatus(ctx context.Context, id string) (*v1.Tas
 	return argsList.Get(0).(*v1.TaskResponse), argsList.Error(1)
 }
 
-func (m *MockTaskService) GetTasks(ctx context.Context, status string, limit, offset int) ([]v1.TaskResponse, error) {
-	argsList := m.Called(ctx, status, limit, offset)
-	return argsList.Get(0).([]v1.TaskResponse), argsList.Error(1)
+func (m *MockTaskService) GetTasks(ctx context.Context, req v1.TaskListRequest) (*v1.TaskListResponse, error) {
+	argsList := m.Called(ctx, req)
+	return argsList.Get(0).(*v1.TaskListResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) GetTaskTypes(ctx context.Context) ([]v1.TaskTypeResponse, error) {
+	argsList := m.Called(ctx)
+	return argsList.Get(0).([]v1.TaskTypeResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) GetQueueDepth(ctx context.Context) (map[string]int, error) {
+	argsList := m.Called(ctx)
+	return argsList.Get(0).(map[string]int), argsList.Error(1)
+}
+
+func (m *MockTaskService) CancelTask(ctx context.Context, id string) (*v1.TaskResponse, error) {
+	argsList := m.Called(ctx, id)
+	return argsList.Get(0).(*v1.TaskResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) RescheduleTask(ctx context.Context, id string, req v1.RescheduleRequest) (*v1.TaskResponse, error) {
+	argsList := m.Called(ctx, id, req)
+	return argsList.Get(0).(*v1.TaskResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) SendWorkflow(ctx context.Context, req v1.WorkflowRequest) (*v1.WorkflowResponse, error) {
+	argsList := m.Called(ctx, req)
+	return argsList.Get(0).(*v1.WorkflowResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) GetWorkflow(ctx context.Context, id string) (*v1.WorkflowResponse, error) {
+	argsList := m.Called(ctx, id)
+	return argsList.Get(0).(*v1.WorkflowResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) CreateSchedule(ctx context.Context, req v1.ScheduleRequest) (*v1.ScheduleResponse, error) {
+	argsList := m.Called(ctx, req)
+	return argsList.Get(0).(*v1.ScheduleResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) GetSchedules(ctx context.Context) ([]v1.ScheduleResponse, error) {
+	argsList := m.Called(ctx)
+	return argsList.Get(0).([]v1.ScheduleResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) GetSchedule(ctx context.Context, id string) (*v1.ScheduleResponse, error) {
+	argsList := m.Called(ctx, id)
+	return argsList.Get(0).(*v1.ScheduleResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) PauseSchedule(ctx context.Context, id string) (*v1.ScheduleResponse, error) {
+	argsList := m.Called(ctx, id)
+	return argsList.Get(0).(*v1.ScheduleResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) ResumeSchedule(ctx context.Context, id string) (*v1.ScheduleResponse, error) {
+	argsList := m.Called(ctx, id)
+	return argsList.Get(0).(*v1.ScheduleResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) DeleteSchedule(ctx context.Context, id string) error {
+	argsList := m.Called(ctx, id)
+	return argsList.Error(0)
+}
+
+func (m *MockTaskService) GetDeadLetters(ctx context.Context, name string, limit, offset int) ([]v1.DeadLetterResponse, int, error) {
+	argsList := m.Called(ctx, name, limit, offset)
+	return argsList.Get(0).([]v1.DeadLetterResponse), argsList.Int(1), argsList.Error(2)
+}
+
+func (m *MockTaskService) GetDeadLetter(ctx context.Context, id string) (*v1.DeadLetterResponse, error) {
+	argsList := m.Called(ctx, id)
+	return argsList.Get(0).(*v1.DeadLetterResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) RequeueDeadLetter(ctx context.Context, id string) (*v1.TaskResponse, error) {
+	argsList := m.Called(ctx, id)
+	return argsList.Get(0).(*v1.TaskResponse), argsList.Error(1)
+}
+
+func (m *MockTaskService) DeleteDeadLetter(ctx context.Context, id string) error {
+	argsList := m.Called(ctx, id)
+	return argsList.Error(0)
+}
+
+func (m *MockTaskService) PurgeDeadLetters(ctx context.Context, name string) (int, error) {
+	argsList := m.Called(ctx, name)
+	return argsList.Int(0), argsList.Error(1)
 }
 
 func (m *MockTaskService) Initialize() {
-	m.On("SendTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("mockedTaskID", nil)
+	m.On("SendTask", mock.Anything, mock.Anything).Return(&v1.TaskResponse{
+		ID:     "mockedTaskID",
+		Status: tasks.StatePending,
+	}, nil)
 	m.On("GetTaskStatus", mock.Anything, mock.Anything).Return(&v1.TaskResponse{
 		ID:        "mockedTaskID",
 		Status:    tasks.StatePending,
@@ -36,14 +124,49 @@ func (m *MockTaskService) Initialize() {
 		Error:     "",
 		CreatedAt: "2025-04-21T15:10:00Z",
 	}, nil)
-	m.On("GetTasks", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]v1.TaskResponse{
+	m.On("GetTasks", mock.Anything, mock.Anything).Return(&v1.TaskListResponse{
+		Tasks: []v1.TaskResponse{
+			{
+				ID:        "mockedTaskID",
+				Name:      "mockedTask",
+				Status:    tasks.StatePending,
+				Result:    nil,
+				Error:     "",
+				CreatedAt: "2025-04-21T15:10:00Z",
+			},
+		},
+		Limit: 10,
+		Order: "desc",
+		Total: 1,
+	}, nil)
+	m.On("GetTaskTypes", mock.Anything).Return([]v1.TaskTypeResponse{
 		{
-			ID:        "mockedTaskID",
-			Name:      "mockedTask",
-			Status:    tasks.StatePending,
-			Result:    nil,
-			Error:     "",
-			CreatedAt: "2025-04-21T15:10:00Z",
+			Name: "mockedTask",
+			Args: []v1.TaskArgSpec{{Name: "message", Type: "string"}},
 		},
 	}, nil)
+	m.On("GetQueueDepth", mock.Anything).Return(map[string]int{
+		"high":   0,
+		"normal": 1,
+		"low":    0,
+	}, nil)
+	m.On("CancelTask", mock.Anything, mock.Anything).Return(&v1.TaskResponse{
+		ID:     "mockedTaskID",
+		Status: "CANCELLED",
+	}, nil)
+	m.On("RescheduleTask", mock.Anything, mock.Anything, mock.Anything).Return(&v1.TaskResponse{
+		ID:          "mockedTaskID",
+		Status:      "SCHEDULED",
+		ScheduledAt: "2025-04-21T16:00:00Z",
+	}, nil)
+	m.On("SendWorkflow", mock.Anything, mock.Anything).Return(&v1.WorkflowResponse{
+		ID:     "mockedWorkflowID",
+		Type:   "chain",
+		Status: tasks.StatePending,
+	}, nil)
+	m.On("GetWorkflow", mock.Anything, mock.Anything).Return(&v1.WorkflowResponse{
+		ID:     "mockedWorkflowID",
+		Type:   "chain",
+		Status: tasks.StatePending,
+	}, nil)
 }
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "task-runner-service/internal/api/v1"
+	"task-runner-service/internal/domain"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	ScheduleActive = "active"
+	SchedulePaused = "paused"
+)
+
+// scheduleHistoryLimit is how many produced task IDs are kept per schedule.
+const scheduleHistoryLimit = 100
+
+type Schedule struct {
+	ID        string
+	Spec      string
+	Name      string
+	Args      []tasks.Arg
+	Queue     string
+	Paused    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ScheduleRun struct {
+	TaskID  string
+	FiredAt time.Time
+	Error   string
+}
+
+func (s *RunnerService) CreateSchedule(ctx context.Context, req v1.ScheduleRequest) (*v1.ScheduleResponse, error) {
+	validationErr := &domain.ValidationError{}
+	if _, err := cron.ParseStandard(req.Spec); err != nil {
+		validationErr.Add("spec", fmt.Sprintf("invalid cron expression: %v", err))
+	}
+
+	args, err := s.registry.Validate(req.Name, req.Args)
+	if err != nil {
+		if !mergeValidationError(validationErr, "task", err) {
+			return nil, err
+		}
+	}
+	s.checkQueue(validationErr, req.Queue, req.Name)
+	if validationErr.HasErrors() {
+		return nil, validationErr
+	}
+
+	now := time.Now()
+	schedule := Schedule{
+		ID:        fmt.Sprintf("schedule_%v", uuid.New().String()),
+		Spec:      req.Spec,
+		Name:      req.Name,
+		Args:      args,
+		Queue:     req.Queue,
+		Paused:    req.Paused,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.storage.SaveSchedule(ctx, schedule); err != nil {
+		return nil, fmt.Errorf("failed to save schedule: %w", err)
+	}
+
+	return newScheduleResponse(schedule, nil), nil
+}
+
+func (s *RunnerService) GetSchedules(ctx context.Context) ([]v1.ScheduleResponse, error) {
+	schedules, err := s.storage.GetSchedules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules: %w", err)
+	}
+
+	responses := make([]v1.ScheduleResponse, 0, len(schedules))
+	for _, schedule := range schedules {
+		responses = append(responses, *newScheduleResponse(schedule, nil))
+	}
+	return responses, nil
+}
+
+func (s *RunnerService) GetSchedule(ctx context.Context, id string) (*v1.ScheduleResponse, error) {
+	schedule, err := s.storage.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+
+	runs, err := s.storage.GetScheduleRuns(ctx, id, scheduleHistoryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule history: %w", err)
+	}
+
+	return newScheduleResponse(*schedule, runs), nil
+}
+
+func (s *RunnerService) PauseSchedule(ctx context.Context, id string) (*v1.ScheduleResponse, error) {
+	return s.setSchedulePaused(ctx, id, true)
+}
+
+func (s *RunnerService) ResumeSchedule(ctx context.Context, id string) (*v1.ScheduleResponse, error) {
+	return s.setSchedulePaused(ctx, id, false)
+}
+
+func (s *RunnerService) DeleteSchedule(ctx context.Context, id string) error {
+	if err := s.storage.DeleteSchedule(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	return nil
+}
+
+// RunSchedule submits one task for the schedule and records it in the
+// schedule history. It is called by the scheduler on every cron tick.
+func (s *RunnerService) RunSchedule(ctx context.Context, id string) error {
+	schedule, err := s.storage.GetSchedule(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get schedule: %w", err)
+	}
+	if schedule.Paused {
+		return nil
+	}
+
+	run := ScheduleRun{FiredAt: time.Now()}
+	task, sendErr := s.SendTask(ctx, v1.TaskRequest{
+		Name:  schedule.Name,
+		Args:  schedule.Args,
+		Queue: schedule.Queue,
+	})
+	if sendErr != nil {
+		run.Error = sendErr.Error()
+	} else {
+		run.TaskID = task.ID
+	}
+
+	if err := s.storage.AddScheduleRun(ctx, id, run, scheduleHistoryLimit); err != nil {
+		return fmt.Errorf("failed to record schedule run: %w", err)
+	}
+	return sendErr
+}
+
+func (s *RunnerService) setSchedulePaused(ctx context.Context, id string, paused bool) (*v1.ScheduleResponse, error) {
+	schedule, err := s.storage.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+
+	if schedule.Paused != paused {
+		schedule.Paused = paused
+		schedule.UpdatedAt = time.Now()
+		if err := s.storage.SaveSchedule(ctx, *schedule); err != nil {
+			return nil, fmt.Errorf("failed to save schedule: %w", err)
+		}
+	}
+
+	return newScheduleResponse(*schedule, nil), nil
+}
+
+func newScheduleResponse(schedule Schedule, runs []ScheduleRun) *v1.ScheduleResponse {
+	response := &v1.ScheduleResponse{
+		ID:        schedule.ID,
+		Spec:      schedule.Spec,
+		Name:      schedule.Name,
+		Args:      schedule.Args,
+		Queue:     schedule.Queue,
+		Status:    ScheduleActive,
+		CreatedAt: schedule.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: schedule.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if schedule.Paused {
+		response.Status = SchedulePaused
+	} else if spec, err := cron.ParseStandard(schedule.Spec); err == nil {
+		response.NextRunAt = spec.Next(time.Now()).Format(time.RFC3339)
+	}
+
+	for _, run := range runs {
+		response.History = append(response.History, v1.ScheduleRunResponse{
+			TaskID:  run.TaskID,
+			FiredAt: run.FiredAt.Format(time.RFC3339),
+			Error:   run.Error,
+		})
+	}
+	if len(runs) > 0 {
+		response.LastRunAt = runs[0].FiredAt.Format(time.RFC3339)
+	}
+
+	return response
+}
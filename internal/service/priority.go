@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"task-runner-service/internal/domain"
+)
+
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+// Priorities lists the priority levels from highest to lowest.
+var Priorities = []string{PriorityHigh, PriorityNormal, PriorityLow}
+
+// signaturePriorities are the tasks.Signature.Priority values used when a
+// level is given by name.
+var signaturePriorities = map[string]uint8{
+	PriorityLow:    1,
+	PriorityNormal: 5,
+	PriorityHigh:   9,
+}
+
+// ParsePriority accepts a level name or a number from 0 to 9 and returns the
+// level together with the value for tasks.Signature.Priority. Numbers 0-3 are
+// low, 4-6 normal and 7-9 high. An empty value is normal.
+func ParsePriority(value string) (string, uint8, error) {
+	if value == "" {
+		return PriorityNormal, signaturePriorities[PriorityNormal], nil
+	}
+	if priority, ok := signaturePriorities[value]; ok {
+		return value, priority, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 || number > 9 {
+		validationErr := &domain.ValidationError{}
+		validationErr.Add("priority", fmt.Sprintf("must be %s, %s, %s or a number from 0 to 9", PriorityLow, PriorityNormal, PriorityHigh))
+		return "", 0, validationErr
+	}
+
+	switch {
+	case number <= 3:
+		return PriorityLow, uint8(number), nil
+	case number <= 6:
+		return PriorityNormal, uint8(number), nil
+	default:
+		return PriorityHigh, uint8(number), nil
+	}
+}
+
+// PriorityQueue returns the broker queue tasks of the given level are routed
+// to. Normal priority uses the queue itself so existing consumers keep
+// working; other levels get a suffixed queue with workers of their own.
+func PriorityQueue(queue, priority string) string {
+	if priority == "" || priority == PriorityNormal {
+		return queue
+	}
+	return queue + "." + priority
+}
+
+func (s *RunnerService) routingKey(queue, priority string) string {
+	if priority == "" || priority == PriorityNormal {
+		return queue
+	}
+	if queue == "" {
+		queue = s.server.GetConfig().DefaultQueue
+	}
+	return PriorityQueue(queue, priority)
+}
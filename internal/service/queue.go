@@ -0,0 +1,92 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"task-runner-service/internal/domain"
+)
+
+// Queue describes a named queue workers consume and the task types that may
+// be submitted to it. An empty Tasks list accepts every registered task.
+// RateLimit is in tasks per second per worker process, zero for no limit.
+type Queue struct {
+	Name        string
+	Concurrency int
+	RateLimit   float64
+	Tasks       []string
+	Enabled     bool
+}
+
+// Accepts reports whether tasks of the given type may be sent to the queue.
+func (q Queue) Accepts(name string) bool {
+	if len(q.Tasks) == 0 {
+		return true
+	}
+	for _, allowed := range q.Tasks {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SetQueues declares the queues submissions may target. Until it is called
+// any queue is accepted.
+func (s *RunnerService) SetQueues(queues []Queue) error {
+	declared := make(map[string]Queue, len(queues))
+	for _, queue := range queues {
+		if queue.Name == "" {
+			return fmt.Errorf("queue name is required")
+		}
+		if _, ok := declared[queue.Name]; ok {
+			return fmt.Errorf("queue %q is declared twice", queue.Name)
+		}
+		for _, name := range queue.Tasks {
+			if !s.registry.IsRegistered(name) {
+				return fmt.Errorf("queue %q allows unknown task %q", queue.Name, name)
+			}
+		}
+		declared[queue.Name] = queue
+	}
+
+	s.queues = declared
+	return nil
+}
+
+// checkQueue reports on validationErr when queue is not declared, is disabled
+// or does not accept one of the task types. An empty queue means the default
+// queue. Disabled queues have no workers, so their tasks would never run.
+func (s *RunnerService) checkQueue(validationErr *domain.ValidationError, queue string, names ...string) {
+	if s.queues == nil {
+		return
+	}
+	if queue == "" {
+		queue = s.server.GetConfig().DefaultQueue
+	}
+
+	declared, ok := s.queues[queue]
+	if !ok {
+		validationErr.Add("queue", fmt.Sprintf("unknown queue %q, must be one of %s", queue, strings.Join(s.queueNames(), ", ")))
+		return
+	}
+	if !declared.Enabled {
+		validationErr.Add("queue", fmt.Sprintf("queue %q is disabled", queue))
+		return
+	}
+	for _, name := range names {
+		if !declared.Accepts(name) {
+			validationErr.Add("queue", fmt.Sprintf("queue %q does not accept task %q", queue, name))
+		}
+	}
+}
+
+func (s *RunnerService) queueNames() []string {
+	names := make([]string, 0, len(s.queues))
+	for name := range s.queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"time"
+
+	v1 "task-runner-service/internal/api/v1"
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/registry"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// Attempt records a failed run of a task.
+type Attempt struct {
+	Number     int
+	Error      string
+	ErrorClass string
+	FailedAt   time.Time
+}
+
+// retryPolicy returns the policy of the named task type with the fields set
+// in override applied on top.
+func (s *RunnerService) retryPolicy(name string, override *v1.RetryPolicy) (registry.RetryPolicy, error) {
+	policy := s.registry.RetryPolicy(name)
+	if override == nil {
+		return policy, nil
+	}
+
+	validationErr := &domain.ValidationError{}
+	if override.MaxAttempts != 0 {
+		policy.MaxAttempts = override.MaxAttempts
+	}
+	if override.Backoff != "" {
+		policy.Backoff = override.Backoff
+	}
+	if override.Interval != "" {
+		interval, err := time.ParseDuration(override.Interval)
+		if err != nil {
+			validationErr.Add("retry.interval", "must be a duration such as 5s")
+		}
+		policy.Interval = interval
+	}
+	if override.MaxInterval != "" {
+		maxInterval, err := time.ParseDuration(override.MaxInterval)
+		if err != nil {
+			validationErr.Add("retry.max_interval", "must be a duration such as 1m")
+		}
+		policy.MaxInterval = maxInterval
+	}
+	if override.Jitter != nil {
+		policy.Jitter = *override.Jitter
+	}
+	if override.RetryOn != nil {
+		policy.RetryOn = override.RetryOn
+	}
+
+	if validationErr.HasErrors() {
+		return policy, validationErr
+	}
+	if err := policy.Validate(); err != nil {
+		if !mergeValidationError(validationErr, "retry", err) {
+			return policy, err
+		}
+		return policy, validationErr
+	}
+	return policy, nil
+}
+
+// applyRetryPolicy stores policy with the task and sets the retry budget on
+// the signature. The worker executor decides on every failure whether and
+// when to retry, and keeps RetryCount at the number of retries left.
+func applyRetryPolicy(signature *tasks.Signature, task *Task, policy registry.RetryPolicy) {
+	task.Retry = policy
+	setRetryBudget(signature, *task)
+}
+
+func setRetryBudget(signature *tasks.Signature, task Task) {
+	signature.RetryCount = task.Retry.MaxAttempts - len(task.Attempts) - 1
+	if signature.RetryCount < 0 {
+		signature.RetryCount = 0
+	}
+	signature.RetryTimeout = int(task.Retry.Interval / time.Second)
+}
+
+func newRetryPolicyResponse(policy registry.RetryPolicy) v1.RetryPolicy {
+	response := v1.RetryPolicy{
+		MaxAttempts: policy.MaxAttempts,
+		Backoff:     policy.Backoff,
+		Interval:    policy.Interval.String(),
+		RetryOn:     policy.RetryOn,
+	}
+	if policy.MaxInterval > 0 {
+		response.MaxInterval = policy.MaxInterval.String()
+	}
+	if policy.Jitter > 0 {
+		jitter := policy.Jitter
+		response.Jitter = &jitter
+	}
+	return response
+}
+
+func newAttemptResponses(attempts []Attempt) []v1.AttemptResponse {
+	if len(attempts) == 0 {
+		return nil
+	}
+
+	responses := make([]v1.AttemptResponse, 0, len(attempts))
+	for _, attempt := range attempts {
+		responses = append(responses, v1.AttemptResponse{
+			Attempt:    attempt.Number,
+			Error:      attempt.Error,
+			ErrorClass: attempt.ErrorClass,
+			FailedAt:   attempt.FailedAt.Format(time.RFC3339),
+		})
+	}
+	return responses
+}
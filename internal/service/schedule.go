@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "task-runner-service/internal/api/v1"
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/tracing"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// ScheduledAtHeader carries the scheduled time a delayed message was
+// published for. Rescheduling publishes a new message for the same task, so
+// deliveries whose header no longer matches the stored time are stale.
+const ScheduledAtHeader = "scheduled_at"
+
+func (s *RunnerService) RescheduleTask(ctx context.Context, id string, req v1.RescheduleRequest) (*v1.TaskResponse, error) {
+	scheduledAt, err := parseSchedule(req.ETA, req.Delay)
+	if err != nil {
+		return nil, err
+	}
+	if scheduledAt == nil {
+		validationErr := &domain.ValidationError{}
+		validationErr.Add("eta", "eta or delay is required")
+		return nil, validationErr
+	}
+
+	task, err := s.storage.GetTask(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if task.Status != StateScheduled {
+		return nil, domain.TaskNotScheduled
+	}
+	if task.ScheduledAt != nil && task.ScheduledAt.Equal(scheduledAt.UTC()) {
+		response := newTaskResponse(*task)
+		return &response, nil
+	}
+
+	signature := &tasks.Signature{
+		UUID:       task.ID,
+		Name:       task.Name,
+		Args:       task.Args,
+		RoutingKey: s.routingKey(task.Queue, task.Priority),
+	}
+	if _, priority, err := ParsePriority(task.Priority); err == nil {
+		signature.Priority = priority
+	}
+	setRetryBudget(signature, *task)
+	schedule(signature, task, *scheduledAt)
+
+	if err := s.storage.SaveTask(ctx, *task); err != nil {
+		return nil, fmt.Errorf("failed to save task: %w", err)
+	}
+
+	_, span := tracing.StartEnqueue(ctx, task.Name, signature)
+	_, err = s.server.SendTask(signature)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send task: %w", err)
+	}
+
+	response := newTaskResponse(*task)
+	return &response, nil
+}
+
+// IsStaleDelivery reports whether signature is an outdated message of a task
+// that has since been rescheduled.
+func IsStaleDelivery(task Task, signature *tasks.Signature) bool {
+	if task.ScheduledAt == nil {
+		return false
+	}
+
+	header, _ := signature.Headers[ScheduledAtHeader].(string)
+	return header != task.ScheduledAt.Format(time.RFC3339Nano)
+}
+
+func schedule(signature *tasks.Signature, task *Task, scheduledAt time.Time) {
+	scheduledAt = scheduledAt.UTC()
+
+	signature.ETA = &scheduledAt
+	if signature.Headers == nil {
+		signature.Headers = tasks.Headers{}
+	}
+	signature.Headers[ScheduledAtHeader] = scheduledAt.Format(time.RFC3339Nano)
+
+	task.Status = StateScheduled
+	task.ScheduledAt = &scheduledAt
+}
+
+func parseSchedule(eta, delay string) (*time.Time, error) {
+	validationErr := &domain.ValidationError{}
+
+	var scheduledAt *time.Time
+	switch {
+	case eta != "" && delay != "":
+		validationErr.Add("eta", "eta and delay are mutually exclusive")
+	case eta != "":
+		at, err := time.Parse(time.RFC3339, eta)
+		if err != nil {
+			validationErr.Add("eta", "must be an RFC3339 timestamp")
+			break
+		}
+		scheduledAt = &at
+	case delay != "":
+		d, err := time.ParseDuration(delay)
+		if err != nil || d < 0 {
+			validationErr.Add("delay", "must be a non-negative duration such as 90s or 5m")
+			break
+		}
+		at := time.Now().Add(d)
+		scheduledAt = &at
+	}
+
+	if validationErr.HasErrors() {
+		return nil, validationErr
+	}
+	return scheduledAt, nil
+}
@@ -6,66 +6,179 @@ import (
 	"time"
 
 	v1 "task-runner-service/internal/api/v1"
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/metrics"
+	"task-runner-service/internal/registry"
+	"task-runner-service/internal/tracing"
+	"task-runner-service/pkg/logger"
 
 	"github.com/RichardKnop/machinery/v1/tasks"
+	"go.uber.org/zap"
 )
 
 type Storage interface {
 	SaveTask(ctx context.Context, task Task) error
 	GetTask(ctx context.Context, id string) (*Task, error)
-	GetTasks(ctx context.Context, status string, limit, offset int) ([]Task, error)
+	// UpdateTask applies update to the stored task and saves it atomically;
+	// update may run more than once and must only change the task. An error
+	// from update is returned as is and nothing is saved.
+	UpdateTask(ctx context.Context, id string, update func(task *Task) error) (*Task, error)
+	GetTasks(ctx context.Context, query TaskQuery) (*TaskPage, error)
+	PublishCancellation(ctx context.Context, id string) error
+	SubscribeCancellations(ctx context.Context) (<-chan string, error)
+	SaveWorkflow(ctx context.Context, workflow Workflow) error
+	GetWorkflow(ctx context.Context, id string) (*Workflow, error)
+	SaveSchedule(ctx context.Context, schedule Schedule) error
+	GetSchedule(ctx context.Context, id string) (*Schedule, error)
+	GetSchedules(ctx context.Context) ([]Schedule, error)
+	DeleteSchedule(ctx context.Context, id string) error
+	AddScheduleRun(ctx context.Context, id string, run ScheduleRun, limit int) error
+	GetScheduleRuns(ctx context.Context, id string, limit int) ([]ScheduleRun, error)
+	SaveDeadLetter(ctx context.Context, letter DeadLetter) error
+	GetDeadLetter(ctx context.Context, id string) (*DeadLetter, error)
+	GetDeadLetters(ctx context.Context, name string, limit, offset int) ([]DeadLetter, int, error)
+	DeleteDeadLetter(ctx context.Context, id string) error
+	PurgeDeadLetters(ctx context.Context, name string) (int, error)
+	ReserveIdempotencyKey(ctx context.Context, record IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, error)
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	GetQueueDepth(ctx context.Context) (map[string]int, error)
 }
 
+const (
+	StateCancelled   = "CANCELLED"
+	StateScheduled   = "SCHEDULED"
+	StateInterrupted = "INTERRUPTED"
+)
+
 type Task struct {
-	ID        string
-	Name      string
-	Args      []tasks.Arg
-	Status    string
-	CreatedAt time.Time
-	Result    interface{}
-	Error     error
+	ID          string
+	Name        string
+	Args        []tasks.Arg
+	Queue       string
+	Priority    string
+	Labels      map[string]string
+	Status      string
+	CreatedAt   time.Time
+	ScheduledAt *time.Time
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+	Result      interface{}
+	Error       string
+	WorkflowID  string
+	Retry       registry.RetryPolicy
+	Attempt     int
+	Attempts    []Attempt
+	NextRetryAt *time.Time
+}
+
+func IsFinalState(status string) bool {
+	return status == tasks.StateSuccess || status == tasks.StateFailure || status == StateCancelled
 }
 
 type RunnerService struct {
-	server  MachineryServer
-	storage Storage
+	server   MachineryServer
+	storage  Storage
+	registry *registry.TaskRegistry
+	queues   map[string]Queue
 }
 
-func NewRunnerService(server MachineryServer, storage Storage) *RunnerService {
+func NewRunnerService(server MachineryServer, storage Storage, registry *registry.TaskRegistry) *RunnerService {
 	return &RunnerService{
-		server:  server,
-		storage: storage,
+		server:   server,
+		storage:  storage,
+		registry: registry,
 	}
 }
 
-func (s *RunnerService) SendTask(ctx context.Context, name string, args []tasks.Arg, queue string) (string, error) {
-	signature := &tasks.Signature{
-		Name: name,
-		Args: args,
+func (s *RunnerService) SendTask(ctx context.Context, req v1.TaskRequest) (*v1.TaskResponse, error) {
+	args, err := s.registry.Validate(req.Name, req.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	queueErr := &domain.ValidationError{}
+	if s.checkQueue(queueErr, req.Queue, req.Name); queueErr.HasErrors() {
+		return nil, queueErr
+	}
+
+	if err := validateLabels(req.Labels); err != nil {
+		return nil, err
 	}
 
-	if queue != "" {
-		signature.RoutingKey = queue
+	scheduledAt, err := parseSchedule(req.ETA, req.Delay)
+	if err != nil {
+		return nil, err
 	}
 
-	asyncResult, err := s.server.SendTask(signature)
+	retryPolicy, err := s.retryPolicy(req.Name, req.Retry)
 	if err != nil {
-		return "", fmt.Errorf("failed to send task: %w", err)
+		return nil, err
 	}
 
+	priority, signaturePriority, err := ParsePriority(string(req.Priority))
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := tasks.NewSignature(req.Name, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task signature: %w", err)
+	}
+
+	signature.RoutingKey = s.routingKey(req.Queue, priority)
+	signature.Priority = signaturePriority
+
+	// Metadata is stored before publishing so that worker lifecycle hooks
+	// always find the task and never race with the initial PENDING write.
 	task := Task{
-		ID:        asyncResult.GetState().TaskUUID,
-		Name:      name,
+		ID:        signature.UUID,
+		Name:      req.Name,
 		Args:      args,
+		Queue:     req.Queue,
+		Priority:  priority,
+		Labels:    req.Labels,
 		Status:    tasks.StatePending,
 		CreatedAt: time.Now(),
 	}
+	ctx = logger.WithTask(ctx, task.ID, task.Name, task.Queue)
+	applyRetryPolicy(signature, &task, retryPolicy)
+	if scheduledAt != nil {
+		schedule(signature, &task, *scheduledAt)
+	}
+
+	if req.IdempotencyKey != "" {
+		original, err := s.reserveIdempotencyKey(ctx, req, task.ID)
+		if err != nil {
+			return nil, err
+		}
+		if original != nil {
+			return original, nil
+		}
+	}
 
 	if err := s.storage.SaveTask(ctx, task); err != nil {
-		return "", fmt.Errorf("failed to save task metadata: %w", err)
+		s.releaseIdempotencyKey(ctx, req.IdempotencyKey)
+		return nil, fmt.Errorf("failed to save task metadata: %w", err)
 	}
 
-	return task.ID, nil
+	_, span := tracing.StartEnqueue(ctx, task.Name, signature)
+	_, err = s.server.SendTask(signature)
+	tracing.End(span, err)
+	if err != nil {
+		s.releaseIdempotencyKey(ctx, req.IdempotencyKey)
+		finishedAt := time.Now()
+		task.Status = tasks.StateFailure
+		task.Error = err.Error()
+		task.FinishedAt = &finishedAt
+		if saveErr := s.storage.SaveTask(ctx, task); saveErr != nil {
+			logger.Error(ctx, "failed to mark task as failed", zap.Error(saveErr))
+		}
+		return nil, fmt.Errorf("failed to send task: %w", err)
+	}
+	metrics.TaskSubmitted(task.Name, task.Queue)
+
+	response := newTaskResponse(task)
+	return &response, nil
 }
 
 func (s *RunnerService) GetTaskStatus(ctx context.Context, id string) (*v1.TaskResponse, error) {
@@ -74,66 +187,115 @@ func (s *RunnerService) GetTaskStatus(ctx context.Context, id string) (*v1.TaskR
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
 
-	state, err := s.server.GetBackend().GetState(task.ID)
+	// The stored task follows the lifecycle, retries and rescheduling
+	// included, and outlives the backend state, which expires after
+	// ResultsExpireIn. The backend is only asked for a result the worker did
+	// not store.
+	response := newTaskResponse(*task)
+	if task.Status == tasks.StateSuccess && task.Result == nil {
+		response.Result = s.retrieveResultFromBackend(ctx, task.ID)
+	}
+	return &response, nil
+}
+
+// CancelTask revokes a task that has not finished yet. Workers skip revoked
+// tasks on receipt; a running task has its context cancelled.
+func (s *RunnerService) CancelTask(ctx context.Context, id string) (*v1.TaskResponse, error) {
+	task, err := s.storage.UpdateTask(ctx, id, func(task *Task) error {
+		if IsFinalState(task.Status) {
+			return domain.TaskFinished
+		}
+		finishedAt := time.Now()
+		task.Status = StateCancelled
+		task.FinishedAt = &finishedAt
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get task state: %w", err)
+		return nil, fmt.Errorf("failed to cancel task: %w", err)
 	}
 
-	var result interface{}
-	var errorMsg string
-	if task.Error != nil {
-		errorMsg = task.Error.Error()
+	if err := s.storage.PublishCancellation(ctx, task.ID); err != nil {
+		return nil, fmt.Errorf("failed to notify workers: %w", err)
 	}
-	result, err = s.retrieveResultFromBackend(task.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve result from backend: %w", err)
-	}
-	return &v1.TaskResponse{
-		ID:        task.ID,
-		Status:    state.State,
-		Result:    result,
-		Error:     errorMsg,
-		CreatedAt: task.CreatedAt.Format(time.RFC3339),
-	}, nil
+
+	response := newTaskResponse(*task)
+	return &response, nil
 }
 
-func (s *RunnerService) retrieveResultFromBackend(taskID string) (interface{}, error) {
+func (s *RunnerService) retrieveResultFromBackend(ctx context.Context, taskID string) interface{} {
 	state, err := s.server.GetBackend().GetState(taskID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get task state: %w", err)
+		logger.Debug(ctx, "task state not found in result backend", zap.String("task_id", taskID), zap.Error(err))
+		return nil
 	}
-
-	if state.IsCompleted() {
-
-		if len(state.Results) > 0 && state.Results[0] != nil {
-			return state.Results[0].Value, nil
-		}
-		return nil, nil
+	if state.IsCompleted() && len(state.Results) > 0 && state.Results[0] != nil {
+		return state.Results[0].Value
 	}
-
-	return nil, nil
+	return nil
 }
 
-func (s *RunnerService) GetTasks(ctx context.Context, status string, limit, offset int) ([]v1.TaskResponse, error) {
-	tasks, err := s.storage.GetTasks(ctx, status, limit, offset)
+// GetQueueDepth returns the number of tasks waiting in the queues for each
+// priority level.
+func (s *RunnerService) GetQueueDepth(ctx context.Context) (map[string]int, error) {
+	depth, err := s.storage.GetQueueDepth(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get tasks: %w", err)
+		return nil, fmt.Errorf("failed to get queue depth: %w", err)
+	}
+	return depth, nil
+}
+
+func newTaskResponse(task Task) v1.TaskResponse {
+	response := v1.TaskResponse{
+		ID:          task.ID,
+		Name:        task.Name,
+		Queue:       task.Queue,
+		Priority:    task.Priority,
+		Labels:      task.Labels,
+		Status:      task.Status,
+		Result:      task.Result,
+		Error:       task.Error,
+		CreatedAt:   task.CreatedAt.Format(time.RFC3339),
+		WorkflowID:  task.WorkflowID,
+		Attempt:     task.Attempt,
+		MaxAttempts: task.Retry.MaxAttempts,
+		Attempts:    newAttemptResponses(task.Attempts),
+	}
+	if task.NextRetryAt != nil {
+		response.NextRetryAt = task.NextRetryAt.Format(time.RFC3339)
 	}
+	if task.ScheduledAt != nil {
+		response.ScheduledAt = task.ScheduledAt.Format(time.RFC3339)
+	}
+	if task.StartedAt != nil {
+		response.StartedAt = task.StartedAt.Format(time.RFC3339)
+	}
+	if task.FinishedAt != nil {
+		response.FinishedAt = task.FinishedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+func (s *RunnerService) GetTaskTypes(ctx context.Context) ([]v1.TaskTypeResponse, error) {
+	types := s.registry.Types()
 
-	var responses []v1.TaskResponse
-	for _, task := range tasks {
-		var errorMsg string
-		if task.Error != nil {
-			errorMsg = task.Error.Error()
+	responses := make([]v1.TaskTypeResponse, 0, len(types))
+	for _, taskType := range types {
+		args := make([]v1.TaskArgSpec, 0, len(taskType.Args))
+		for _, arg := range taskType.Args {
+			args = append(args, v1.TaskArgSpec{
+				Name:     arg.Name,
+				Type:     arg.Type,
+				Required: !arg.Optional,
+				Default:  arg.Default,
+				Min:      arg.Min,
+				Max:      arg.Max,
+			})
 		}
 
-		responses = append(responses, v1.TaskResponse{
-			ID:        task.ID,
-			Name:      task.Name,
-			Status:    task.Status,
-			Result:    task.Result,
-			Error:     errorMsg,
-			CreatedAt: task.CreatedAt.Format(time.RFC3339),
+		responses = append(responses, v1.TaskTypeResponse{
+			Name:  taskType.Name,
+			Args:  args,
+			Retry: newRetryPolicyResponse(taskType.Retry),
 		})
 	}
 
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	v1 "task-runner-service/internal/api/v1"
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/metrics"
+	"task-runner-service/internal/tracing"
+	"task-runner-service/pkg/logger"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+const (
+	WorkflowChain = "chain"
+	WorkflowGroup = "group"
+	WorkflowChord = "chord"
+)
+
+// groupSendConcurrency bounds how many group tasks are published at once.
+const groupSendConcurrency = 10
+
+type Workflow struct {
+	ID        string
+	Type      string
+	TaskIDs   []string
+	CreatedAt time.Time
+}
+
+func (s *RunnerService) SendWorkflow(ctx context.Context, req v1.WorkflowRequest) (*v1.WorkflowResponse, error) {
+	signatures, callback, err := s.buildWorkflowSignatures(req)
+	if err != nil {
+		return nil, err
+	}
+
+	workflow := Workflow{
+		ID:        fmt.Sprintf("workflow_%v", uuid.New().String()),
+		Type:      req.Type,
+		CreatedAt: time.Now(),
+	}
+
+	all := signatures
+	if callback != nil {
+		all = append(all, callback)
+	}
+
+	steps := make([]Task, 0, len(all))
+	for _, signature := range all {
+		task := Task{
+			ID:         signature.UUID,
+			Name:       signature.Name,
+			Args:       signature.Args,
+			Queue:      req.Queue,
+			Priority:   PriorityNormal,
+			Status:     tasks.StatePending,
+			CreatedAt:  workflow.CreatedAt,
+			WorkflowID: workflow.ID,
+		}
+		applyRetryPolicy(signature, &task, s.registry.RetryPolicy(signature.Name))
+		if err := s.storage.SaveTask(ctx, task); err != nil {
+			return nil, fmt.Errorf("failed to save task metadata: %w", err)
+		}
+		workflow.TaskIDs = append(workflow.TaskIDs, task.ID)
+		steps = append(steps, task)
+	}
+
+	if err := s.storage.SaveWorkflow(ctx, workflow); err != nil {
+		return nil, fmt.Errorf("failed to save workflow: %w", err)
+	}
+
+	_, span := tracing.StartEnqueue(ctx, req.Type+" workflow", all...)
+	span.SetAttributes(attribute.String("workflow.id", workflow.ID))
+	err = s.publishWorkflow(req.Type, signatures, callback)
+	tracing.End(span, err)
+	if err != nil {
+		s.failWorkflowSteps(ctx, steps, err)
+		return nil, fmt.Errorf("failed to send workflow: %w", err)
+	}
+	for _, step := range steps {
+		metrics.TaskSubmitted(step.Name, step.Queue)
+	}
+
+	return newWorkflowResponse(workflow, steps), nil
+}
+
+func (s *RunnerService) GetWorkflow(ctx context.Context, id string) (*v1.WorkflowResponse, error) {
+	workflow, err := s.storage.GetWorkflow(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow: %w", err)
+	}
+
+	steps := make([]Task, 0, len(workflow.TaskIDs))
+	for _, taskID := range workflow.TaskIDs {
+		task, err := s.storage.GetTask(ctx, taskID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get workflow step %s: %w", taskID, err)
+		}
+		steps = append(steps, *task)
+	}
+
+	return newWorkflowResponse(*workflow, steps), nil
+}
+
+func (s *RunnerService) buildWorkflowSignatures(req v1.WorkflowRequest) ([]*tasks.Signature, *tasks.Signature, error) {
+	validationErr := &domain.ValidationError{}
+
+	switch req.Type {
+	case WorkflowChain, WorkflowGroup:
+		if req.Callback != nil {
+			validationErr.Add("callback", fmt.Sprintf("callback is only supported for %s workflows", WorkflowChord))
+		}
+	case WorkflowChord:
+		if req.Callback == nil {
+			validationErr.Add("callback", "callback is required")
+		}
+	default:
+		validationErr.Add("type", fmt.Sprintf("must be one of %s, %s, %s", WorkflowChain, WorkflowGroup, WorkflowChord))
+	}
+	if len(req.Tasks) == 0 {
+		validationErr.Add("tasks", "at least one task is required")
+	}
+
+	signatures := make([]*tasks.Signature, 0, len(req.Tasks))
+	for i, step := range req.Tasks {
+		// Only chain steps after the first one receive results of a previous step.
+		passResults := step.PassResults && req.Type == WorkflowChain && i > 0
+		signature, err := s.buildStepSignature(step, passResults, req.Queue)
+		if err != nil {
+			if !mergeValidationError(validationErr, fmt.Sprintf("tasks[%d]", i), err) {
+				return nil, nil, err
+			}
+			continue
+		}
+		signatures = append(signatures, signature)
+	}
+
+	var callback *tasks.Signature
+	if req.Callback != nil && req.Type == WorkflowChord {
+		signature, err := s.buildStepSignature(*req.Callback, req.Callback.PassResults, req.Queue)
+		if err != nil {
+			if !mergeValidationError(validationErr, "callback", err) {
+				return nil, nil, err
+			}
+		}
+		callback = signature
+	}
+
+	names := make([]string, 0, len(req.Tasks)+1)
+	for _, step := range req.Tasks {
+		names = append(names, step.Name)
+	}
+	if req.Callback != nil {
+		names = append(names, req.Callback.Name)
+	}
+	s.checkQueue(validationErr, req.Queue, names...)
+
+	if validationErr.HasErrors() {
+		return nil, nil, validationErr
+	}
+	return signatures, callback, nil
+}
+
+func (s *RunnerService) buildStepSignature(step v1.WorkflowStep, passResults bool, queue string) (*tasks.Signature, error) {
+	validate := s.registry.Validate
+	if passResults {
+		validate = s.registry.ValidatePrefix
+	}
+
+	args, err := validate(step.Name, step.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := tasks.NewSignature(step.Name, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task signature: %w", err)
+	}
+	signature.Immutable = !passResults
+	signature.RoutingKey = s.routingKey(queue, PriorityNormal)
+	signature.Priority = signaturePriorities[PriorityNormal]
+	return signature, nil
+}
+
+func (s *RunnerService) publishWorkflow(workflowType string, signatures []*tasks.Signature, callback *tasks.Signature) error {
+	switch workflowType {
+	case WorkflowChain:
+		chain, err := tasks.NewChain(signatures...)
+		if err != nil {
+			return err
+		}
+		_, err = s.server.SendChain(chain)
+		return err
+	case WorkflowGroup:
+		group, err := tasks.NewGroup(signatures...)
+		if err != nil {
+			return err
+		}
+		_, err = s.server.SendGroup(group, groupSendConcurrency)
+		return err
+	case WorkflowChord:
+		group, err := tasks.NewGroup(signatures...)
+		if err != nil {
+			return err
+		}
+		chord, err := tasks.NewChord(group, callback)
+		if err != nil {
+			return err
+		}
+		_, err = s.server.SendChord(chord, groupSendConcurrency)
+		return err
+	}
+	return fmt.Errorf("unknown workflow type %q", workflowType)
+}
+
+func (s *RunnerService) failWorkflowSteps(ctx context.Context, steps []Task, sendErr error) {
+	finishedAt := time.Now()
+	for _, task := range steps {
+		task.Status = tasks.StateFailure
+		task.Error = sendErr.Error()
+		task.FinishedAt = &finishedAt
+		if err := s.storage.SaveTask(ctx, task); err != nil {
+			logger.Error(ctx, "failed to mark workflow task as failed", zap.String("task_id", task.ID), zap.Error(err))
+		}
+	}
+}
+
+func mergeValidationError(target *domain.ValidationError, prefix string, err error) bool {
+	var validationErr *domain.ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+	target.Merge(prefix, validationErr)
+	return true
+}
+
+func newWorkflowResponse(workflow Workflow, steps []Task) *v1.WorkflowResponse {
+	response := &v1.WorkflowResponse{
+		ID:        workflow.ID,
+		Type:      workflow.Type,
+		Steps:     make([]v1.TaskResponse, 0, len(steps)),
+		CreatedAt: workflow.CreatedAt.Format(time.RFC3339),
+	}
+
+	progress := &response.Progress
+	progress.Total = len(steps)
+	for _, task := range steps {
+		response.Steps = append(response.Steps, newTaskResponse(task))
+
+		switch task.Status {
+		case tasks.StateSuccess:
+			progress.Succeeded++
+		case tasks.StateFailure:
+			progress.Failed++
+		case StateCancelled:
+			progress.Cancelled++
+		case tasks.StatePending:
+			progress.Pending++
+		default:
+			progress.Running++
+		}
+	}
+
+	if progress.Total > 0 {
+		done := progress.Succeeded + progress.Failed + progress.Cancelled
+		progress.Percent = float64(done) * 100 / float64(progress.Total)
+	}
+
+	switch {
+	case progress.Failed > 0:
+		response.Status = tasks.StateFailure
+	case progress.Cancelled > 0:
+		response.Status = StateCancelled
+	case progress.Succeeded == progress.Total:
+		response.Status = tasks.StateSuccess
+	case progress.Pending == progress.Total:
+		response.Status = tasks.StatePending
+	default:
+		response.Status = tasks.StateStarted
+	}
+
+	return response
+}
@@ -0,0 +1,607 @@
+// Package conformance checks that a storage backend behaves the way the
+// service expects. Every backend runs the same suite from its tests, so the
+// backends stay interchangeable.
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/registry"
+	"task-runner-service/internal/service"
+	"task-runner-service/internal/storage"
+
+	"github.com/RichardKnop/machinery/v1/backends/iface"
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Open returns an empty storage for one test, and a function that moves the
+// clock of the storage forward for expiring keys, or nil if the storage
+// follows the wall clock.
+type Open func(t *testing.T) (storage.Storage, func(time.Duration))
+
+type suite struct {
+	store   storage.Storage
+	advance func(time.Duration)
+}
+
+// Run runs the suite against the storage returned by open.
+func Run(t *testing.T, open Open) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s suite)
+	}{
+		{"Task", testTask},
+		{"TaskNotFound", testTaskNotFound},
+		{"TaskUpdate", testTaskUpdate},
+		{"TaskStatus", testTaskStatus},
+		{"TaskOrder", testTaskOrder},
+		{"TaskPages", testTaskPages},
+		{"TaskFilters", testTaskFilters},
+		{"QueueDepth", testQueueDepth},
+		{"Cancellations", testCancellations},
+		{"Workflow", testWorkflow},
+		{"Schedules", testSchedules},
+		{"ScheduleRuns", testScheduleRuns},
+		{"Leadership", testLeadership},
+		{"DeadLetters", testDeadLetters},
+		{"Idempotency", testIdempotency},
+		{"Heartbeats", testHeartbeats},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, advance := open(t)
+			if advance == nil {
+				advance = time.Sleep
+			}
+			test.test(t, suite{store: store, advance: advance})
+		})
+	}
+}
+
+// base is the creation time of the tasks of the suite. Times are stored with
+// millisecond precision at least, so the suite uses no finer ones.
+var base = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func at(ms int) time.Time {
+	return base.Add(time.Duration(ms) * time.Millisecond)
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+// utc returns v with every time in it converted to UTC, as backends may
+// return times in another location.
+func utc[T any](v T) T {
+	value := reflect.ValueOf(&v).Elem()
+	toUTC(value)
+	return v
+}
+
+func toUTC(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() && v.Elem().CanSet() {
+			toUTC(v.Elem())
+		}
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			v.Set(reflect.ValueOf(t.UTC()))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).CanSet() {
+				toUTC(v.Field(i))
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			toUTC(v.Index(i))
+		}
+	}
+}
+
+func ids(tasks []service.Task) []string {
+	result := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		result = append(result, task.ID)
+	}
+	return result
+}
+
+func newTask(id string, created time.Time) service.Task {
+	return service.Task{ID: id, Name: "add", Status: tasks.StatePending, CreatedAt: created}
+}
+
+func testTask(t *testing.T, s suite) {
+	ctx := context.Background()
+	task := service.Task{
+		ID:          "task_1",
+		Name:        "add",
+		Args:        []tasks.Arg{{Name: "a", Type: "int64", Value: 1.0}, {Name: "b", Type: "string", Value: "two"}},
+		Queue:       "slow",
+		Priority:    service.PriorityHigh,
+		Labels:      map[string]string{"team": "billing"},
+		Status:      tasks.StateFailure,
+		CreatedAt:   at(0),
+		ScheduledAt: ptr(at(10)),
+		StartedAt:   ptr(at(20)),
+		FinishedAt:  ptr(at(30)),
+		Result:      []interface{}{3.0, "done"},
+		Error:       "boom",
+		WorkflowID:  "workflow_1",
+		Retry:       registry.RetryPolicy{MaxAttempts: 3, Backoff: "exponential", Interval: time.Second, RetryOn: []string{"timeout"}},
+		Attempt:     2,
+		Attempts:    []service.Attempt{{Number: 1, Error: "timeout", ErrorClass: "timeout", FailedAt: at(15)}},
+		NextRetryAt: ptr(at(40)),
+	}
+	require.NoError(t, s.store.SaveTask(ctx, task))
+
+	got, err := s.store.GetTask(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, task, utc(*got))
+
+	task.Status = tasks.StateSuccess
+	task.Error = ""
+	require.NoError(t, s.store.SaveTask(ctx, task))
+
+	got, err = s.store.GetTask(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, task, utc(*got))
+
+	page, err := s.store.GetTasks(ctx, service.TaskQuery{Statuses: []string{tasks.StateFailure}, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, page.Tasks)
+	page, err = s.store.GetTasks(ctx, service.TaskQuery{Statuses: []string{tasks.StateSuccess}, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{task.ID}, ids(page.Tasks))
+}
+
+func testTaskNotFound(t *testing.T, s suite) {
+	_, err := s.store.GetTask(context.Background(), "missing")
+	assert.Equal(t, domain.TaskNotFound, err)
+}
+
+func testTaskUpdate(t *testing.T, s suite) {
+	ctx := context.Background()
+	require.NoError(t, s.store.SaveTask(ctx, newTask("task_1", at(0))))
+
+	updated, err := s.store.UpdateTask(ctx, "task_1", func(task *service.Task) error {
+		task.Status = tasks.StateStarted
+		task.StartedAt = ptr(at(10))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, tasks.StateStarted, updated.Status)
+
+	got, err := s.store.GetTask(ctx, "task_1")
+	require.NoError(t, err)
+	assert.Equal(t, tasks.StateStarted, got.Status)
+	assert.Equal(t, ptr(at(10)), utc(got.StartedAt))
+
+	_, err = s.store.UpdateTask(ctx, "missing", func(task *service.Task) error { return nil })
+	assert.Equal(t, domain.TaskNotFound, err)
+
+	_, err = s.store.UpdateTask(ctx, "task_1", func(task *service.Task) error {
+		task.Status = tasks.StateSuccess
+		return domain.TaskFinished
+	})
+	assert.Equal(t, domain.TaskFinished, err)
+	got, err = s.store.GetTask(ctx, "task_1")
+	require.NoError(t, err)
+	assert.Equal(t, tasks.StateStarted, got.Status)
+
+	// Concurrent updates all land, none overwrites another.
+	const writers = 8
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.store.UpdateTask(ctx, "task_1", func(task *service.Task) error {
+				task.Attempts = append(task.Attempts, service.Attempt{Number: len(task.Attempts) + 1, FailedAt: at(20)})
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	got, err = s.store.GetTask(ctx, "task_1")
+	require.NoError(t, err)
+	require.Len(t, got.Attempts, writers)
+	for i, attempt := range got.Attempts {
+		assert.Equal(t, i+1, attempt.Number)
+	}
+}
+
+// expiredServer is a machinery server whose result backend has lost every
+// task state, as it does after ResultsExpireIn.
+type expiredServer struct {
+	service.MachineryServer
+}
+
+func (expiredServer) GetBackend() iface.Backend {
+	return expiredBackend{}
+}
+
+type expiredBackend struct {
+	iface.Backend
+}
+
+func (expiredBackend) GetState(taskUUID string) (*tasks.TaskState, error) {
+	return nil, fmt.Errorf("task %s not found", taskUUID)
+}
+
+func testTaskStatus(t *testing.T, s suite) {
+	ctx := context.Background()
+	svc := service.NewRunnerService(expiredServer{}, s.store, registry.New())
+
+	for _, task := range []service.Task{
+		{ID: "succeeded", Name: "add", Status: tasks.StateSuccess, Result: 3.0, CreatedAt: at(0), FinishedAt: ptr(at(10))},
+		{ID: "empty", Name: "add", Status: tasks.StateSuccess, CreatedAt: at(0), FinishedAt: ptr(at(10))},
+		{ID: "failed", Name: "add", Status: tasks.StateFailure, Error: "boom", CreatedAt: at(0), FinishedAt: ptr(at(10))},
+		{ID: "scheduled", Name: "add", Status: service.StateScheduled, CreatedAt: at(0), ScheduledAt: ptr(at(3600000))},
+		{ID: "cancelled", Name: "add", Status: service.StateCancelled, CreatedAt: at(0), FinishedAt: ptr(at(10))},
+	} {
+		require.NoError(t, s.store.SaveTask(ctx, task))
+
+		resp, err := svc.GetTaskStatus(ctx, task.ID)
+		require.NoError(t, err, task.ID)
+		assert.Equal(t, task.Status, resp.Status, task.ID)
+		assert.Equal(t, task.Result, resp.Result, task.ID)
+		assert.Equal(t, task.Error, resp.Error, task.ID)
+	}
+}
+
+func testTaskOrder(t *testing.T, s suite) {
+	ctx := context.Background()
+	// Tasks created in the same millisecond are ordered by ID.
+	for _, task := range []service.Task{
+		newTask("task_c", at(0)),
+		newTask("task_b", at(1)),
+		newTask("task_a", at(1)),
+		newTask("task_d", at(2)),
+	} {
+		require.NoError(t, s.store.SaveTask(ctx, task))
+	}
+
+	page, err := s.store.GetTasks(ctx, service.TaskQuery{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"task_d", "task_b", "task_a", "task_c"}, ids(page.Tasks))
+	assert.Equal(t, 4, page.Total)
+	assert.False(t, page.HasMore)
+
+	page, err = s.store.GetTasks(ctx, service.TaskQuery{Limit: 10, Order: service.OrderAsc})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"task_c", "task_a", "task_b", "task_d"}, ids(page.Tasks))
+}
+
+func testTaskPages(t *testing.T, s suite) {
+	ctx := context.Background()
+	var all []string
+	for i, id := range []string{"task_1", "task_2", "task_3", "task_4", "task_5"} {
+		require.NoError(t, s.store.SaveTask(ctx, newTask(id, at(i/2))))
+		all = append(all, id)
+	}
+
+	for _, order := range []string{service.OrderDesc, service.OrderAsc} {
+		t.Run(order, func(t *testing.T) {
+			want := append([]string(nil), all...)
+			if order == service.OrderDesc {
+				for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+					want[i], want[j] = want[j], want[i]
+				}
+			}
+
+			var got []string
+			query := service.TaskQuery{Limit: 2, Order: order}
+			for {
+				page, err := s.store.GetTasks(ctx, query)
+				require.NoError(t, err)
+				assert.Equal(t, 5, page.Total)
+				got = append(got, ids(page.Tasks)...)
+				if !page.HasMore {
+					break
+				}
+				cursor := service.CursorOf(page.Tasks[len(page.Tasks)-1])
+				query.After = &cursor
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func testTaskFilters(t *testing.T, s suite) {
+	ctx := context.Background()
+	saved := []service.Task{
+		{ID: "task_1", Name: "add", Status: tasks.StatePending, CreatedAt: at(0), Labels: map[string]string{"team": "billing"}},
+		{ID: "task_2", Name: "add", Queue: "slow", Status: tasks.StateSuccess, CreatedAt: at(1), FinishedAt: ptr(at(100))},
+		{ID: "task_3", Name: "mul", Queue: "default", Status: tasks.StateFailure, CreatedAt: at(2), FinishedAt: ptr(at(200)), Error: "Connection Refused"},
+		{ID: "task_4", Name: "mul", Queue: "slow", Status: tasks.StateFailure, CreatedAt: at(3), FinishedAt: ptr(at(300)), Error: "timeout", Labels: map[string]string{"team": "billing", "env": "prod"}},
+	}
+	for _, task := range saved {
+		require.NoError(t, s.store.SaveTask(ctx, task))
+	}
+
+	cases := []struct {
+		name  string
+		query service.TaskQuery
+		want  []string
+	}{
+		{"Status", service.TaskQuery{Statuses: []string{tasks.StateFailure}}, []string{"task_4", "task_3"}},
+		{"Statuses", service.TaskQuery{Statuses: []string{tasks.StatePending, tasks.StateSuccess}}, []string{"task_2", "task_1"}},
+		{"Name", service.TaskQuery{Names: []string{"add"}}, []string{"task_2", "task_1"}},
+		{"Queue", service.TaskQuery{Queues: []string{"slow"}}, []string{"task_4", "task_2"}},
+		{"DefaultQueue", service.TaskQuery{Queues: []string{"default"}}, []string{"task_3", "task_1"}},
+		{"Label", service.TaskQuery{Labels: map[string]string{"team": "billing"}}, []string{"task_4", "task_1"}},
+		{"Labels", service.TaskQuery{Labels: map[string]string{"team": "billing", "env": "prod"}}, []string{"task_4"}},
+		{"Error", service.TaskQuery{ErrorContains: "refused"}, []string{"task_3"}},
+		{"Created", service.TaskQuery{CreatedFrom: ptr(at(1)), CreatedTo: ptr(at(3))}, []string{"task_3", "task_2"}},
+		{"Finished", service.TaskQuery{FinishedFrom: ptr(at(100)), FinishedTo: ptr(at(300))}, []string{"task_3", "task_2"}},
+		{"Combined", service.TaskQuery{Names: []string{"mul"}, Queues: []string{"slow"}, ErrorContains: "TIME"}, []string{"task_4"}},
+		{"NoMatch", service.TaskQuery{Names: []string{"add"}, Statuses: []string{tasks.StateFailure}}, []string{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.query.Limit = 10
+			page, err := s.store.GetTasks(ctx, c.query)
+			require.NoError(t, err)
+			assert.Equal(t, c.want, ids(page.Tasks))
+			assert.Equal(t, len(c.want), page.Total)
+		})
+	}
+}
+
+func testQueueDepth(t *testing.T, s suite) {
+	ctx := context.Background()
+	saved := []service.Task{
+		{ID: "task_1", Status: tasks.StatePending, Priority: service.PriorityHigh, CreatedAt: at(0)},
+		{ID: "task_2", Status: tasks.StatePending, CreatedAt: at(1)},
+		{ID: "task_3", Status: tasks.StatePending, Priority: service.PriorityNormal, CreatedAt: at(2)},
+		{ID: "task_4", Status: tasks.StateStarted, Priority: service.PriorityLow, CreatedAt: at(3)},
+	}
+	for _, task := range saved {
+		require.NoError(t, s.store.SaveTask(ctx, task))
+	}
+
+	depth, err := s.store.GetQueueDepth(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{service.PriorityHigh: 1, service.PriorityNormal: 2, service.PriorityLow: 0}, depth)
+}
+
+func testCancellations(t *testing.T, s suite) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ids, err := s.store.SubscribeCancellations(ctx)
+	require.NoError(t, err)
+	require.NoError(t, s.store.PublishCancellation(ctx, "task_1"))
+
+	select {
+	case id := <-ids:
+		assert.Equal(t, "task_1", id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancellation not received")
+	}
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, open := <-ids
+		return !open
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func testWorkflow(t *testing.T, s suite) {
+	ctx := context.Background()
+	workflow := service.Workflow{ID: "workflow_1", Type: "chain", TaskIDs: []string{"task_1", "task_2"}, CreatedAt: at(0)}
+	require.NoError(t, s.store.SaveWorkflow(ctx, workflow))
+
+	got, err := s.store.GetWorkflow(ctx, workflow.ID)
+	require.NoError(t, err)
+	assert.Equal(t, workflow, utc(*got))
+
+	_, err = s.store.GetWorkflow(ctx, "missing")
+	assert.Equal(t, domain.WorkflowNotFound, err)
+}
+
+func testSchedules(t *testing.T, s suite) {
+	ctx := context.Background()
+	second := service.Schedule{ID: "schedule_2", Spec: "@hourly", Name: "add", CreatedAt: at(1), UpdatedAt: at(1)}
+	first := service.Schedule{ID: "schedule_1", Spec: "*/5 * * * *", Name: "mul", Args: []tasks.Arg{{Type: "int64", Value: 2.0}}, Queue: "slow", CreatedAt: at(0), UpdatedAt: at(0)}
+	require.NoError(t, s.store.SaveSchedule(ctx, second))
+	require.NoError(t, s.store.SaveSchedule(ctx, first))
+
+	first.Paused = true
+	first.UpdatedAt = at(5)
+	require.NoError(t, s.store.SaveSchedule(ctx, first))
+
+	got, err := s.store.GetSchedule(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first, utc(*got))
+
+	schedules, err := s.store.GetSchedules(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []service.Schedule{first, second}, utc(schedules))
+
+	require.NoError(t, s.store.DeleteSchedule(ctx, first.ID))
+	_, err = s.store.GetSchedule(ctx, first.ID)
+	assert.Equal(t, domain.ScheduleNotFound, err)
+	assert.Equal(t, domain.ScheduleNotFound, s.store.DeleteSchedule(ctx, first.ID))
+
+	schedules, err = s.store.GetSchedules(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []service.Schedule{second}, utc(schedules))
+}
+
+func testScheduleRuns(t *testing.T, s suite) {
+	ctx := context.Background()
+	schedule := service.Schedule{ID: "schedule_1", Spec: "@hourly", Name: "add", CreatedAt: at(0)}
+	require.NoError(t, s.store.SaveSchedule(ctx, schedule))
+
+	runs, err := s.store.GetScheduleRuns(ctx, schedule.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	var added []service.ScheduleRun
+	for i := 0; i < 4; i++ {
+		run := service.ScheduleRun{TaskID: fmt.Sprintf("task_%d", i), FiredAt: at(i * 1000)}
+		if i == 2 {
+			run.TaskID, run.Error = "", "broker unavailable"
+		}
+		require.NoError(t, s.store.AddScheduleRun(ctx, schedule.ID, run, 3))
+		added = append(added, run)
+	}
+
+	// Only the last three runs are kept, latest first.
+	runs, err = s.store.GetScheduleRuns(ctx, schedule.ID, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []service.ScheduleRun{added[3], added[2], added[1]}, utc(runs))
+
+	runs, err = s.store.GetScheduleRuns(ctx, schedule.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []service.ScheduleRun{added[3], added[2]}, utc(runs))
+
+	require.NoError(t, s.store.DeleteSchedule(ctx, schedule.ID))
+	runs, err = s.store.GetScheduleRuns(ctx, schedule.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
+
+func testLeadership(t *testing.T, s suite) {
+	ctx := context.Background()
+	const ttl = 500 * time.Millisecond
+
+	acquired, err := s.store.AcquireLeadership(ctx, "a", ttl)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = s.store.AcquireLeadership(ctx, "b", ttl)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	acquired, err = s.store.AcquireLeadership(ctx, "a", ttl)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	s.advance(ttl + 100*time.Millisecond)
+	acquired, err = s.store.AcquireLeadership(ctx, "b", ttl)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = s.store.AcquireLeadership(ctx, "a", ttl)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+}
+
+func testDeadLetters(t *testing.T, s suite) {
+	ctx := context.Background()
+	letters := []service.DeadLetter{
+		{ID: "task_1", Name: "add", Queue: "slow", Error: "boom", FailedAt: at(0),
+			Args:      []tasks.Arg{{Type: "int64", Value: 1.0}},
+			Signature: tasks.Signature{UUID: "task_1", Name: "add", RoutingKey: "slow"},
+			Retry:     registry.RetryPolicy{MaxAttempts: 2},
+			Attempts:  []service.Attempt{{Number: 1, Error: "boom", FailedAt: at(0)}}},
+		{ID: "task_2", Name: "mul", Error: "boom", FailedAt: at(1)},
+		{ID: "task_3", Name: "add", Error: "boom", FailedAt: at(2)},
+	}
+	for _, letter := range letters {
+		require.NoError(t, s.store.SaveDeadLetter(ctx, letter))
+	}
+
+	got, err := s.store.GetDeadLetter(ctx, "task_1")
+	require.NoError(t, err)
+	assert.Equal(t, letters[0], utc(*got))
+	_, err = s.store.GetDeadLetter(ctx, "missing")
+	assert.Equal(t, domain.DeadLetterNotFound, err)
+
+	list, total, err := s.store.GetDeadLetters(ctx, "", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []service.DeadLetter{letters[2], letters[1], letters[0]}, utc(list))
+
+	list, total, err = s.store.GetDeadLetters(ctx, "", 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []service.DeadLetter{letters[1]}, utc(list))
+
+	list, total, err = s.store.GetDeadLetters(ctx, "add", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, []service.DeadLetter{letters[2], letters[0]}, utc(list))
+
+	require.NoError(t, s.store.DeleteDeadLetter(ctx, "task_2"))
+	assert.Equal(t, domain.DeadLetterNotFound, s.store.DeleteDeadLetter(ctx, "task_2"))
+
+	purged, err := s.store.PurgeDeadLetters(ctx, "add")
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+
+	list, total, err = s.store.GetDeadLetters(ctx, "", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, list)
+
+	require.NoError(t, s.store.SaveDeadLetter(ctx, letters[1]))
+	purged, err = s.store.PurgeDeadLetters(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+}
+
+func testIdempotency(t *testing.T, s suite) {
+	ctx := context.Background()
+	const ttl = 500 * time.Millisecond
+	record := service.IdempotencyRecord{Key: "key_1", Fingerprint: "abc", TaskID: "task_1", CreatedAt: at(0)}
+
+	existing, err := s.store.ReserveIdempotencyKey(ctx, record, ttl)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+
+	existing, err = s.store.ReserveIdempotencyKey(ctx, service.IdempotencyRecord{Key: "key_1", Fingerprint: "def", TaskID: "task_2", CreatedAt: at(1)}, ttl)
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	assert.Equal(t, record, utc(*existing))
+
+	require.NoError(t, s.store.ReleaseIdempotencyKey(ctx, record.Key))
+	existing, err = s.store.ReserveIdempotencyKey(ctx, record, ttl)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+
+	s.advance(ttl + 100*time.Millisecond)
+	existing, err = s.store.ReserveIdempotencyKey(ctx, record, ttl)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+}
+
+func testHeartbeats(t *testing.T, s suite) {
+	ctx := context.Background()
+	heartbeats, err := s.store.GetHeartbeats(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, heartbeats)
+
+	long := service.Heartbeat{WorkerID: "worker_b", Hostname: "host-b", Queues: []string{"default"}, Running: 2, At: at(0)}
+	short := service.Heartbeat{WorkerID: "worker_a", Hostname: "host-a", Queues: []string{"default", "slow"}, At: at(0)}
+	require.NoError(t, s.store.SaveHeartbeat(ctx, long, time.Minute))
+	require.NoError(t, s.store.SaveHeartbeat(ctx, short, 500*time.Millisecond))
+
+	heartbeats, err = s.store.GetHeartbeats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []service.Heartbeat{short, long}, utc(heartbeats))
+
+	s.advance(600 * time.Millisecond)
+	heartbeats, err = s.store.GetHeartbeats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []service.Heartbeat{long}, utc(heartbeats))
+
+	assert.NoError(t, s.store.Ping(ctx))
+}
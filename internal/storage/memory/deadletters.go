@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/service"
+)
+
+func (s *MemoryStorage) SaveDeadLetter(ctx context.Context, letter service.DeadLetter) error {
+	stored, err := clone(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters[letter.ID] = stored
+	return nil
+}
+
+func (s *MemoryStorage) GetDeadLetter(ctx context.Context, id string) (*service.DeadLetter, error) {
+	s.mu.Lock()
+	letter, ok := s.deadLetters[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, domain.DeadLetterNotFound
+	}
+
+	letter, err := clone(letter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+	}
+	return &letter, nil
+}
+
+// GetDeadLetters returns a page of dead letters, most recent failure first,
+// and the total number matching name (all of them when name is empty).
+func (s *MemoryStorage) GetDeadLetters(ctx context.Context, name string, limit, offset int) ([]service.DeadLetter, int, error) {
+	s.mu.Lock()
+	letters := make([]service.DeadLetter, 0, len(s.deadLetters))
+	for _, letter := range s.deadLetters {
+		if name == "" || letter.Name == name {
+			letters = append(letters, letter)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(letters, func(i, j int) bool {
+		a, b := letters[i].FailedAt.UnixMilli(), letters[j].FailedAt.UnixMilli()
+		if a != b {
+			return a > b
+		}
+		return letters[i].ID > letters[j].ID
+	})
+
+	total := len(letters)
+	if offset > total {
+		offset = total
+	}
+	letters = letters[offset:]
+	if len(letters) > limit {
+		letters = letters[:limit]
+	}
+
+	letters, err := clone(letters)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal dead letters: %w", err)
+	}
+	return letters, total, nil
+}
+
+func (s *MemoryStorage) DeleteDeadLetter(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.deadLetters[id]; !ok {
+		return domain.DeadLetterNotFound
+	}
+	delete(s.deadLetters, id)
+	return nil
+}
+
+// PurgeDeadLetters deletes every dead letter of the named task type, or all
+// of them when name is empty, and returns how many were deleted.
+func (s *MemoryStorage) PurgeDeadLetters(ctx context.Context, name string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := 0
+	for id, letter := range s.deadLetters {
+		if name == "" || letter.Name == name {
+			delete(s.deadLetters, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
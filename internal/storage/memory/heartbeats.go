@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"task-runner-service/internal/service"
+)
+
+// SaveHeartbeat stores the heartbeat of a worker. It expires after ttl, so
+// workers that stopped disappear from the listing on their own; expired
+// heartbeats are removed on the way.
+func (s *MemoryStorage) SaveHeartbeat(ctx context.Context, heartbeat service.Heartbeat, ttl time.Duration) error {
+	stored, err := clone(heartbeat)
+	if err != nil {
+		return fmt.Errorf("failed to marshal heartbeat: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, existing := range s.heartbeats {
+		if !existing.live(now) {
+			delete(s.heartbeats, id)
+		}
+	}
+	s.heartbeats[heartbeat.WorkerID] = expiring[service.Heartbeat]{value: stored, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStorage) GetHeartbeats(ctx context.Context) ([]service.Heartbeat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	heartbeats := make([]service.Heartbeat, 0, len(s.heartbeats))
+	for _, heartbeat := range s.heartbeats {
+		if heartbeat.live(now) {
+			heartbeats = append(heartbeats, heartbeat.value)
+		}
+	}
+
+	heartbeats, err := clone(heartbeats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal heartbeats: %w", err)
+	}
+	sort.Slice(heartbeats, func(i, j int) bool {
+		return heartbeats[i].WorkerID < heartbeats[j].WorkerID
+	})
+	return heartbeats, nil
+}
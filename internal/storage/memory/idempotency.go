@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"task-runner-service/internal/service"
+)
+
+// ReserveIdempotencyKey stores record unless its key is already taken, in
+// which case the record stored earlier is returned. Expired keys are removed
+// on the way.
+func (s *MemoryStorage) ReserveIdempotencyKey(ctx context.Context, record service.IdempotencyRecord, ttl time.Duration) (*service.IdempotencyRecord, error) {
+	stored, err := clone(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, reserved := range s.idempotency {
+		if !reserved.live(now) {
+			delete(s.idempotency, key)
+		}
+	}
+	if existing, ok := s.idempotency[record.Key]; ok && existing.live(now) {
+		return &existing.value, nil
+	}
+	s.idempotency[record.Key] = expiring[service.IdempotencyRecord]{value: stored, expiresAt: now.Add(ttl)}
+	return nil, nil
+}
+
+func (s *MemoryStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.idempotency, key)
+	return nil
+}
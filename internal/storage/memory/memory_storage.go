@@ -0,0 +1,338 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/service"
+	"task-runner-service/pkg/logger"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"go.uber.org/zap"
+)
+
+// subscriberBuffer is how many cancellations a slow subscriber may fall
+// behind before further ones are dropped for it.
+const subscriberBuffer = 64
+
+// MemoryStorage keeps everything in the memory of the process, for local
+// development and tests; nothing survives a restart and nothing is shared
+// with other processes. Values are stored and returned as copies made
+// through JSON, so they come back as they would from the other backends.
+type MemoryStorage struct {
+	mu           sync.Mutex
+	tasks        map[string]service.Task
+	workflows    map[string]service.Workflow
+	schedules    map[string]service.Schedule
+	scheduleRuns map[string][]service.ScheduleRun
+	deadLetters  map[string]service.DeadLetter
+	idempotency  map[string]expiring[service.IdempotencyRecord]
+	heartbeats   map[string]expiring[service.Heartbeat]
+	leader       expiring[string]
+	subscribers  map[chan string]struct{}
+}
+
+type expiring[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+func (e expiring[T]) live(now time.Time) bool {
+	return now.Before(e.expiresAt)
+}
+
+func NewStorage() *MemoryStorage {
+	return &MemoryStorage{
+		tasks:        make(map[string]service.Task),
+		workflows:    make(map[string]service.Workflow),
+		schedules:    make(map[string]service.Schedule),
+		scheduleRuns: make(map[string][]service.ScheduleRun),
+		deadLetters:  make(map[string]service.DeadLetter),
+		idempotency:  make(map[string]expiring[service.IdempotencyRecord]),
+		heartbeats:   make(map[string]expiring[service.Heartbeat]),
+		subscribers:  make(map[chan string]struct{}),
+	}
+}
+
+// clone returns a deep copy of value.
+func clone[T any](value T) (T, error) {
+	var copied T
+	data, err := json.Marshal(value)
+	if err != nil {
+		return copied, err
+	}
+	err = json.Unmarshal(data, &copied)
+	return copied, err
+}
+
+func (s *MemoryStorage) SaveTask(ctx context.Context, task service.Task) error {
+	stored, err := clone(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[task.ID] = stored
+	return nil
+}
+
+// UpdateTask applies update to the task under the lock of the storage, so
+// update must not call the storage.
+func (s *MemoryStorage) UpdateTask(ctx context.Context, id string, update func(task *service.Task) error) (*service.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.tasks[id]
+	if !ok {
+		return nil, domain.TaskNotFound
+	}
+
+	task, err := clone(stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task: %w", err)
+	}
+	if err := update(&task); err != nil {
+		return nil, err
+	}
+	if stored, err = clone(task); err != nil {
+		return nil, fmt.Errorf("failed to marshal task: %w", err)
+	}
+	s.tasks[id] = stored
+	return &task, nil
+}
+
+func (s *MemoryStorage) GetTask(ctx context.Context, id string) (*service.Task, error) {
+	s.mu.Lock()
+	task, ok := s.tasks[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, domain.TaskNotFound
+	}
+
+	task, err := clone(task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task: %w", err)
+	}
+	return &task, nil
+}
+
+// GetTasks returns a page of tasks ordered by creation time, with the
+// precision of service.CursorTime, and then by ID. Every task is checked
+// against the filters.
+func (s *MemoryStorage) GetTasks(ctx context.Context, query service.TaskQuery) (*service.TaskPage, error) {
+	s.mu.Lock()
+	matching := make([]service.Task, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		if matches(task, query) {
+			matching = append(matching, task)
+		}
+	}
+	s.mu.Unlock()
+
+	desc := query.Order != service.OrderAsc
+	sort.Slice(matching, func(i, j int) bool {
+		if desc {
+			i, j = j, i
+		}
+		return before(service.CursorOf(matching[i]), service.CursorOf(matching[j]))
+	})
+
+	page := &service.TaskPage{Total: len(matching), Tasks: []service.Task{}}
+	for _, task := range matching {
+		if query.After != nil {
+			cursor := service.CursorOf(task)
+			if (desc && !before(cursor, *query.After)) || (!desc && !before(*query.After, cursor)) {
+				continue
+			}
+		}
+		if len(page.Tasks) == query.Limit {
+			page.HasMore = true
+			break
+		}
+
+		task, err := clone(task)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal task: %w", err)
+		}
+		page.Tasks = append(page.Tasks, task)
+	}
+	return page, nil
+}
+
+func before(a, b service.Cursor) bool {
+	if a.CreatedAt != b.CreatedAt {
+		return a.CreatedAt < b.CreatedAt
+	}
+	return a.ID < b.ID
+}
+
+func matches(task service.Task, query service.TaskQuery) bool {
+	if len(query.Statuses) > 0 && !contains(query.Statuses, task.Status) {
+		return false
+	}
+	if len(query.Names) > 0 && !contains(query.Names, task.Name) {
+		return false
+	}
+	if len(query.Queues) > 0 {
+		queue := service.QueueName(task.Queue)
+		found := false
+		for _, name := range query.Queues {
+			if service.QueueName(name) == queue {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for key, value := range query.Labels {
+		if label, ok := task.Labels[key]; !ok || label != value {
+			return false
+		}
+	}
+	if query.ErrorContains != "" && (task.Error == "" ||
+		!strings.Contains(strings.ToLower(task.Error), strings.ToLower(query.ErrorContains))) {
+		return false
+	}
+
+	created := service.CursorTime(task.CreatedAt)
+	if !inWindow(created, query.CreatedFrom, query.CreatedTo) {
+		return false
+	}
+	if query.FinishedFrom != nil || query.FinishedTo != nil {
+		if task.FinishedAt == nil || !inWindow(service.CursorTime(*task.FinishedAt), query.FinishedFrom, query.FinishedTo) {
+			return false
+		}
+	}
+	return true
+}
+
+// inWindow tells whether ms is within from, inclusive, and to, exclusive.
+func inWindow(ms int64, from, to *time.Time) bool {
+	if from != nil && ms < service.CursorTime(*from) {
+		return false
+	}
+	if to != nil && ms >= service.CursorTime(*to) {
+		return false
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishCancellation delivers the cancellation to the current subscribers.
+// Like Redis pub/sub, a subscriber that does not keep up misses messages.
+func (s *MemoryStorage) PublishCancellation(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- id:
+		default:
+			logger.Warn(ctx, "cancellation subscriber is full, cancellation dropped", zap.String("task_id", id))
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStorage) SubscribeCancellations(ctx context.Context) (<-chan string, error) {
+	subscriber := make(chan string, subscriberBuffer)
+	s.mu.Lock()
+	s.subscribers[subscriber] = struct{}{}
+	s.mu.Unlock()
+
+	ids := make(chan string)
+	go func() {
+		defer close(ids)
+		defer func() {
+			s.mu.Lock()
+			delete(s.subscribers, subscriber)
+			s.mu.Unlock()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case id := <-subscriber:
+				select {
+				case ids <- id:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ids, nil
+}
+
+func (s *MemoryStorage) GetQueueDepth(ctx context.Context) (map[string]int, error) {
+	depth := make(map[string]int, len(service.Priorities))
+	for _, priority := range service.Priorities {
+		depth[priority] = 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, task := range s.tasks {
+		if task.Status != tasks.StatePending {
+			continue
+		}
+		priority := task.Priority
+		if priority == "" {
+			priority = service.PriorityNormal
+		}
+		depth[priority]++
+	}
+	return depth, nil
+}
+
+func (s *MemoryStorage) SaveWorkflow(ctx context.Context, workflow service.Workflow) error {
+	stored, err := clone(workflow)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workflow: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workflows[workflow.ID] = stored
+	return nil
+}
+
+func (s *MemoryStorage) GetWorkflow(ctx context.Context, id string) (*service.Workflow, error) {
+	s.mu.Lock()
+	workflow, ok := s.workflows[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, domain.WorkflowNotFound
+	}
+
+	workflow, err := clone(workflow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal workflow: %w", err)
+	}
+	return &workflow, nil
+}
+
+// Ping always succeeds; the storage has no connection to lose.
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *MemoryStorage) Close() error {
+	return nil
+}
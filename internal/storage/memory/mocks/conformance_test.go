@@ -0,0 +1,16 @@
+package mocks
+
+import (
+	"testing"
+	"time"
+
+	"task-runner-service/internal/storage"
+	"task-runner-service/internal/storage/conformance"
+	"task-runner-service/internal/storage/memory"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) (storage.Storage, func(time.Duration)) {
+		return memory.NewStorage(), nil
+	})
+}
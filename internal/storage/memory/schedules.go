@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/service"
+)
+
+func (s *MemoryStorage) SaveSchedule(ctx context.Context, schedule service.Schedule) error {
+	stored, err := clone(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules[schedule.ID] = stored
+	return nil
+}
+
+func (s *MemoryStorage) GetSchedule(ctx context.Context, id string) (*service.Schedule, error) {
+	s.mu.Lock()
+	schedule, ok := s.schedules[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, domain.ScheduleNotFound
+	}
+
+	schedule, err := clone(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schedule: %w", err)
+	}
+	return &schedule, nil
+}
+
+func (s *MemoryStorage) GetSchedules(ctx context.Context) ([]service.Schedule, error) {
+	s.mu.Lock()
+	schedules := make([]service.Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		schedules = append(schedules, schedule)
+	}
+	s.mu.Unlock()
+
+	schedules, err := clone(schedules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schedules: %w", err)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if !schedules[i].CreatedAt.Equal(schedules[j].CreatedAt) {
+			return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+		}
+		return schedules[i].ID < schedules[j].ID
+	})
+	return schedules, nil
+}
+
+func (s *MemoryStorage) DeleteSchedule(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.scheduleRuns, id)
+	if _, ok := s.schedules[id]; !ok {
+		return domain.ScheduleNotFound
+	}
+	delete(s.schedules, id)
+	return nil
+}
+
+// AddScheduleRun records a run and keeps only the last limit runs of the
+// schedule.
+func (s *MemoryStorage) AddScheduleRun(ctx context.Context, id string, run service.ScheduleRun, limit int) error {
+	stored, err := clone(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule run: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := append([]service.ScheduleRun{stored}, s.scheduleRuns[id]...)
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	s.scheduleRuns[id] = runs
+	return nil
+}
+
+// GetScheduleRuns returns up to limit runs of the schedule, latest first.
+func (s *MemoryStorage) GetScheduleRuns(ctx context.Context, id string, limit int) ([]service.ScheduleRun, error) {
+	s.mu.Lock()
+	runs := s.scheduleRuns[id]
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	runs = append([]service.ScheduleRun{}, runs...)
+	s.mu.Unlock()
+
+	runs, err := clone(runs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schedule runs: %w", err)
+	}
+	return runs, nil
+}
+
+// AcquireLeadership takes the scheduler lease if it is free or expired, or
+// extends it if it is already held by the same instance.
+func (s *MemoryStorage) AcquireLeadership(ctx context.Context, instanceID string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.leader.live(now) && s.leader.value != instanceID {
+		return false, nil
+	}
+	s.leader = expiring[string]{value: instanceID, expiresAt: now.Add(ttl)}
+	return true, nil
+}
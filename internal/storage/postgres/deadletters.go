@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/service"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *PostgresStorage) SaveDeadLetter(ctx context.Context, letter service.DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, `INSERT INTO dead_letters (id, name, failed_at, data) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, failed_at = EXCLUDED.failed_at, data = EXCLUDED.data`,
+		letter.ID, letter.Name, letter.FailedAt, data)
+	if err != nil {
+		return fmt.Errorf("failed to save dead letter in PostgreSQL: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetDeadLetter(ctx context.Context, id string) (*service.DeadLetter, error) {
+	var data []byte
+	if err := s.pool.QueryRow(ctx, "SELECT data FROM dead_letters WHERE id = $1", id).Scan(&data); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.DeadLetterNotFound
+		}
+		return nil, fmt.Errorf("failed to get dead letter from PostgreSQL: %w", err)
+	}
+
+	var letter service.DeadLetter
+	if err := json.Unmarshal(data, &letter); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+	}
+	return &letter, nil
+}
+
+// GetDeadLetters returns a page of dead letters, most recent failure first,
+// and the total number matching name (all of them when name is empty).
+func (s *PostgresStorage) GetDeadLetters(ctx context.Context, name string, limit, offset int) ([]service.DeadLetter, int, error) {
+	var total int
+	if err := s.pool.QueryRow(ctx, "SELECT count(*) FROM dead_letters WHERE $1 = '' OR name = $1", name).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead letters in PostgreSQL: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx, `SELECT data FROM dead_letters WHERE $1 = '' OR name = $1
+		ORDER BY failed_at DESC, id DESC LIMIT $2 OFFSET $3`, name, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead letters from PostgreSQL: %w", err)
+	}
+	letters, err := scanJSON[service.DeadLetter](rows)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead letters from PostgreSQL: %w", err)
+	}
+	return letters, total, nil
+}
+
+func (s *PostgresStorage) DeleteDeadLetter(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM dead_letters WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter from PostgreSQL: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.DeadLetterNotFound
+	}
+	return nil
+}
+
+// PurgeDeadLetters deletes every dead letter of the named task type, or all
+// of them when name is empty, and returns how many were deleted.
+func (s *PostgresStorage) PurgeDeadLetters(ctx context.Context, name string) (int, error) {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM dead_letters WHERE $1 = '' OR name = $1", name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete dead letters from PostgreSQL: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"task-runner-service/internal/service"
+)
+
+// SaveHeartbeat stores the heartbeat of a worker. It expires after ttl, so
+// workers that stopped disappear from the listing on their own; expired rows
+// are removed on the way.
+func (s *PostgresStorage) SaveHeartbeat(ctx context.Context, heartbeat service.Heartbeat, ttl time.Duration) error {
+	data, err := json.Marshal(heartbeat)
+	if err != nil {
+		return fmt.Errorf("failed to marshal heartbeat: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, `WITH expired AS (DELETE FROM worker_heartbeats WHERE expires_at <= now())
+		INSERT INTO worker_heartbeats (worker_id, data, expires_at)
+		VALUES ($1, $2, now() + $3::interval)
+		ON CONFLICT (worker_id) DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at`,
+		heartbeat.WorkerID, data, ttl)
+	if err != nil {
+		return fmt.Errorf("failed to save heartbeat in PostgreSQL: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetHeartbeats(ctx context.Context) ([]service.Heartbeat, error) {
+	rows, err := s.pool.Query(ctx, "SELECT data FROM worker_heartbeats WHERE expires_at > now() ORDER BY worker_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get heartbeats from PostgreSQL: %w", err)
+	}
+
+	heartbeats, err := scanJSON[service.Heartbeat](rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get heartbeats from PostgreSQL: %w", err)
+	}
+	return heartbeats, nil
+}
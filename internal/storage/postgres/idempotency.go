@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-runner-service/internal/service"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReserveIdempotencyKey stores record unless its key is already taken, in
+// which case the record stored earlier is returned. Expired keys are taken
+// over.
+func (s *PostgresStorage) ReserveIdempotencyKey(ctx context.Context, record service.IdempotencyRecord, ttl time.Duration) (*service.IdempotencyRecord, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	// The update only happens for an expired key; otherwise no row is
+	// returned and the stored record is read back.
+	var reserved bool
+	err = s.pool.QueryRow(ctx, `INSERT INTO idempotency_keys (key, data, expires_at)
+		VALUES ($1, $2, now() + $3::interval)
+		ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= now()
+		RETURNING true`, record.Key, data, ttl).Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to reserve idempotency key in PostgreSQL: %w", err)
+	}
+
+	var existing []byte
+	err = s.pool.QueryRow(ctx, "SELECT data FROM idempotency_keys WHERE key = $1 AND expires_at > now()", record.Key).Scan(&existing)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The key expired in between; try again.
+			return s.ReserveIdempotencyKey(ctx, record, ttl)
+		}
+		return nil, fmt.Errorf("failed to get idempotency key from PostgreSQL: %w", err)
+	}
+
+	var stored service.IdempotencyRecord
+	if err := json.Unmarshal(existing, &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+	}
+	return &stored, nil
+}
+
+func (s *PostgresStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if _, err := s.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE key = $1", key); err != nil {
+		return fmt.Errorf("failed to release idempotency key in PostgreSQL: %w", err)
+	}
+	return nil
+}
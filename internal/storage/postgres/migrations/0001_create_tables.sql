@@ -0,0 +1,77 @@
+-- Task IDs use the C collation so that tasks created in the same millisecond
+-- are ordered by the bytes of their IDs, like the Redis storage orders them.
+CREATE TABLE tasks (
+    id            text COLLATE "C" PRIMARY KEY,
+    name          text NOT NULL,
+    queue         text NOT NULL,
+    priority      text NOT NULL,
+    status        text NOT NULL,
+    labels        jsonb NOT NULL,
+    args          jsonb NOT NULL,
+    result        jsonb NOT NULL,
+    error         text NOT NULL,
+    workflow_id   text NOT NULL,
+    retry         jsonb NOT NULL,
+    attempt       integer NOT NULL,
+    attempts      jsonb NOT NULL,
+    created_at    timestamptz NOT NULL,
+    scheduled_at  timestamptz,
+    started_at    timestamptz,
+    finished_at   timestamptz,
+    next_retry_at timestamptz
+);
+
+CREATE INDEX tasks_created_at_idx ON tasks (created_at, id);
+CREATE INDEX tasks_status_idx ON tasks (status, created_at, id);
+CREATE INDEX tasks_name_idx ON tasks (name, created_at, id);
+CREATE INDEX tasks_queue_idx ON tasks (queue, created_at, id);
+CREATE INDEX tasks_finished_at_idx ON tasks (finished_at) WHERE finished_at IS NOT NULL;
+CREATE INDEX tasks_labels_idx ON tasks USING gin (labels jsonb_path_ops);
+CREATE INDEX tasks_pending_idx ON tasks (priority) WHERE status = 'PENDING';
+
+CREATE TABLE workflows (
+    id   text PRIMARY KEY,
+    data jsonb NOT NULL
+);
+
+CREATE TABLE schedules (
+    id         text PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    data       jsonb NOT NULL
+);
+
+CREATE TABLE schedule_runs (
+    id          bigserial PRIMARY KEY,
+    schedule_id text NOT NULL,
+    data        jsonb NOT NULL
+);
+
+CREATE INDEX schedule_runs_schedule_id_idx ON schedule_runs (schedule_id, id);
+
+CREATE TABLE scheduler_leader (
+    id         boolean PRIMARY KEY DEFAULT true CHECK (id),
+    holder     text NOT NULL,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE TABLE dead_letters (
+    id        text COLLATE "C" PRIMARY KEY,
+    name      text NOT NULL,
+    failed_at timestamptz NOT NULL,
+    data      jsonb NOT NULL
+);
+
+CREATE INDEX dead_letters_failed_at_idx ON dead_letters (failed_at, id);
+CREATE INDEX dead_letters_name_idx ON dead_letters (name, failed_at, id);
+
+CREATE TABLE idempotency_keys (
+    key        text PRIMARY KEY,
+    data       jsonb NOT NULL,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE TABLE worker_heartbeats (
+    worker_id  text PRIMARY KEY,
+    data       jsonb NOT NULL,
+    expires_at timestamptz NOT NULL
+);
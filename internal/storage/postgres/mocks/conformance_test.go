@@ -0,0 +1,48 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/config"
+	"task-runner-service/internal/storage"
+	"task-runner-service/internal/storage/conformance"
+	"task-runner-service/internal/storage/postgres"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// dsnEnv names the database the tests run against. Each test gets a schema
+// of its own, dropped when it ends.
+const dsnEnv = "TASK_RUNNER_TEST_POSTGRES_DSN"
+
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", dsnEnv)
+	}
+
+	conformance.Run(t, func(t *testing.T) (storage.Storage, func(time.Duration)) {
+		schema := fmt.Sprintf("conformance_%d", time.Now().UnixNano())
+		t.Cleanup(func() { dropSchema(t, dsn, schema) })
+
+		store, err := postgres.NewStorage(config.PostgresConfig{DSN: dsn, Schema: schema})
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+		return store, nil
+	})
+}
+
+func dropSchema(t *testing.T, dsn, schema string) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, dsn)
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, "DROP SCHEMA IF EXISTS "+pgx.Identifier{schema}.Sanitize()+" CASCADE")
+	require.NoError(t, err)
+}
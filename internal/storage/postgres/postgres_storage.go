@@ -0,0 +1,304 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-runner-service/internal/config"
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/service"
+	"task-runner-service/pkg/logger"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+const (
+	cancellationChannel = "tasks_cancel"
+	listenRetryInterval = time.Second
+)
+
+// taskColumns are the columns a service.Task is read from, in the order of
+// scanTask.
+const taskColumns = `id, name, queue, priority, status, labels, args, result, error, workflow_id,
+	retry, attempt, attempts, created_at, scheduled_at, started_at, finished_at, next_retry_at`
+
+// PostgresStorage keeps task metadata in PostgreSQL, one row per task with
+// indexed columns for the list filters and JSONB for the rest. Creation
+// times are stored with millisecond precision, the precision task lists are
+// ordered and paged by.
+type PostgresStorage struct {
+	pool   *pgxpool.Pool
+	schema string
+}
+
+func NewStorage(cfg config.PostgresConfig) (*PostgresStorage, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PostgreSQL DSN: %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = int32(cfg.MaxConns)
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = int32(cfg.MinConns)
+	}
+	if cfg.Schema != "" {
+		poolConfig.ConnConfig.RuntimeParams["search_path"] = cfg.Schema
+	}
+	poolConfig.ConnConfig.Tracer = queryTracer{}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	if err := pool.Ping(context.Background()); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	storage := &PostgresStorage{pool: pool, schema: cfg.Schema}
+	if err := storage.migrate(context.Background()); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return storage, nil
+}
+
+func (s *PostgresStorage) SaveTask(ctx context.Context, task service.Task) error {
+	return saveTask(ctx, s.pool, task)
+}
+
+// UpdateTask applies update to the task while holding a lock on its row, so
+// that concurrent updates apply one after the other.
+func (s *PostgresStorage) UpdateTask(ctx context.Context, id string, update func(task *service.Task) error) (*service.Task, error) {
+	var updated *service.Task
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		task, err := scanTask(tx.QueryRow(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id = $1 FOR UPDATE", id))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return domain.TaskNotFound
+			}
+			return fmt.Errorf("failed to get task from PostgreSQL: %w", err)
+		}
+		if err := update(task); err != nil {
+			return err
+		}
+		if err := saveTask(ctx, tx, *task); err != nil {
+			return err
+		}
+		updated = task
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// execer is a pool or a transaction.
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+}
+
+func saveTask(ctx context.Context, db execer, task service.Task) error {
+	values, err := jsonValues(task.Labels, task.Args, task.Result, task.Retry, task.Attempts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `INSERT INTO tasks (`+taskColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name, queue = EXCLUDED.queue, priority = EXCLUDED.priority,
+			status = EXCLUDED.status, labels = EXCLUDED.labels, args = EXCLUDED.args,
+			result = EXCLUDED.result, error = EXCLUDED.error, workflow_id = EXCLUDED.workflow_id,
+			retry = EXCLUDED.retry, attempt = EXCLUDED.attempt, attempts = EXCLUDED.attempts,
+			created_at = EXCLUDED.created_at, scheduled_at = EXCLUDED.scheduled_at,
+			started_at = EXCLUDED.started_at, finished_at = EXCLUDED.finished_at,
+			next_retry_at = EXCLUDED.next_retry_at`,
+		task.ID, task.Name, task.Queue, task.Priority, task.Status,
+		values[0], values[1], values[2], task.Error, task.WorkflowID, values[3], task.Attempt, values[4],
+		task.CreatedAt.Truncate(time.Millisecond), task.ScheduledAt, task.StartedAt, task.FinishedAt, task.NextRetryAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save task in PostgreSQL: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetTask(ctx context.Context, id string) (*service.Task, error) {
+	task, err := scanTask(s.pool.QueryRow(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.TaskNotFound
+		}
+		return nil, fmt.Errorf("failed to get task from PostgreSQL: %w", err)
+	}
+	return task, nil
+}
+
+func scanTask(row pgx.Row) (*service.Task, error) {
+	var (
+		task                                  service.Task
+		labels, args, result, retry, attempts []byte
+	)
+	err := row.Scan(
+		&task.ID, &task.Name, &task.Queue, &task.Priority, &task.Status,
+		&labels, &args, &result, &task.Error, &task.WorkflowID,
+		&retry, &task.Attempt, &attempts,
+		&task.CreatedAt, &task.ScheduledAt, &task.StartedAt, &task.FinishedAt, &task.NextRetryAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range []struct {
+		data   []byte
+		target interface{}
+	}{
+		{labels, &task.Labels},
+		{args, &task.Args},
+		{result, &task.Result},
+		{retry, &task.Retry},
+		{attempts, &task.Attempts},
+	} {
+		if err := json.Unmarshal(field.data, field.target); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal task: %w", err)
+		}
+	}
+	return &task, nil
+}
+
+// jsonValues marshals values for JSONB columns. They are passed as bytes so
+// that pgx does not take strings for JSON text.
+func jsonValues(values ...interface{}) ([][]byte, error) {
+	encoded := make([][]byte, len(values))
+	for i, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = data
+	}
+	return encoded, nil
+}
+
+func (s *PostgresStorage) PublishCancellation(ctx context.Context, id string) error {
+	if _, err := s.pool.Exec(ctx, "SELECT pg_notify($1, $2)", cancellationChannel, id); err != nil {
+		return fmt.Errorf("failed to publish cancellation: %w", err)
+	}
+	return nil
+}
+
+// SubscribeCancellations listens for cancellations on a connection of its
+// own. Should it break, the storage listens again on a new one; cancellations
+// published in between are missed, as with Redis pub/sub.
+func (s *PostgresStorage) SubscribeCancellations(ctx context.Context) (<-chan string, error) {
+	conn, err := s.listen(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to cancellations: %w", err)
+	}
+
+	ids := make(chan string)
+	go func() {
+		defer close(ids)
+		defer func() {
+			if conn != nil {
+				conn.Close(context.Background())
+			}
+		}()
+
+		for {
+			if conn == nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(listenRetryInterval):
+				}
+				if conn, err = s.listen(ctx); err != nil {
+					logger.Warn(ctx, "failed to subscribe to cancellations, retrying", zap.Error(err))
+					continue
+				}
+			}
+
+			notification, err := conn.WaitForNotification(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				logger.Warn(ctx, "cancellation subscription lost, reconnecting", zap.Error(err))
+				conn.Close(context.Background())
+				conn = nil
+				continue
+			}
+			select {
+			case ids <- notification.Payload:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ids, nil
+}
+
+// listen takes a connection out of the pool for LISTEN, which holds it for
+// as long as notifications are wanted.
+func (s *PostgresStorage) listen(ctx context.Context) (*pgx.Conn, error) {
+	pooled, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn := pooled.Hijack()
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{cancellationChannel}.Sanitize()); err != nil {
+		conn.Close(context.Background())
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (s *PostgresStorage) GetQueueDepth(ctx context.Context) (map[string]int, error) {
+	rows, err := s.pool.Query(ctx, "SELECT priority, count(*) FROM tasks WHERE status = $1 GROUP BY priority", tasks.StatePending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue depth from PostgreSQL: %w", err)
+	}
+	defer rows.Close()
+
+	depth := make(map[string]int, len(service.Priorities))
+	for _, priority := range service.Priorities {
+		depth[priority] = 0
+	}
+	for rows.Next() {
+		var priority string
+		var count int
+		if err := rows.Scan(&priority, &count); err != nil {
+			return nil, fmt.Errorf("failed to get queue depth from PostgreSQL: %w", err)
+		}
+		if priority == "" {
+			priority = service.PriorityNormal
+		}
+		depth[priority] += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get queue depth from PostgreSQL: %w", err)
+	}
+	return depth, nil
+}
+
+// Ping checks the connection to PostgreSQL.
+func (s *PostgresStorage) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+// Close closes the connections to PostgreSQL.
+func (s *PostgresStorage) Close() error {
+	s.pool.Close()
+	return nil
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"task-runner-service/internal/service"
+)
+
+// filter collects the conditions of a task query and their arguments.
+type filter struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (f *filter) arg(value interface{}) string {
+	f.args = append(f.args, value)
+	return fmt.Sprintf("$%d", len(f.args))
+}
+
+func (f *filter) add(condition string) {
+	f.conditions = append(f.conditions, condition)
+}
+
+func (f *filter) where() string {
+	if len(f.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(f.conditions, " AND ")
+}
+
+// GetTasks returns a page of tasks ordered by creation time and ID. Every
+// filter maps to an indexed column except the error substring, which is
+// matched case-insensitively on the tasks that passed the other filters.
+func (s *PostgresStorage) GetTasks(ctx context.Context, query service.TaskQuery) (*service.TaskPage, error) {
+	f := &filter{}
+	if len(query.Statuses) > 0 {
+		f.add("status = ANY(" + f.arg(query.Statuses) + ")")
+	}
+	if len(query.Names) > 0 {
+		f.add("name = ANY(" + f.arg(query.Names) + ")")
+	}
+	if len(query.Queues) > 0 {
+		queues := make([]string, 0, len(query.Queues)+1)
+		for _, queue := range query.Queues {
+			queue = service.QueueName(queue)
+			queues = append(queues, queue)
+			// Tasks submitted without a queue are listed as the default one.
+			if queue == service.DefaultQueueName {
+				queues = append(queues, "")
+			}
+		}
+		f.add("queue = ANY(" + f.arg(queues) + ")")
+	}
+	if len(query.Labels) > 0 {
+		labels, err := json.Marshal(query.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal labels: %w", err)
+		}
+		f.add("labels @> " + f.arg(labels) + "::jsonb")
+	}
+	if query.ErrorContains != "" {
+		f.add("error <> '' AND strpos(lower(error), lower(" + f.arg(query.ErrorContains) + ")) > 0")
+	}
+	if query.CreatedFrom != nil {
+		f.add("created_at >= " + f.arg(query.CreatedFrom.Truncate(time.Millisecond)))
+	}
+	if query.CreatedTo != nil {
+		f.add("created_at < " + f.arg(query.CreatedTo.Truncate(time.Millisecond)))
+	}
+	if query.FinishedFrom != nil {
+		f.add("finished_at >= " + f.arg(query.FinishedFrom.Truncate(time.Millisecond)))
+	}
+	if query.FinishedTo != nil {
+		f.add("finished_at < " + f.arg(query.FinishedTo.Truncate(time.Millisecond)))
+	}
+
+	page := &service.TaskPage{}
+	if err := s.pool.QueryRow(ctx, "SELECT count(*) FROM tasks"+f.where(), f.args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("failed to count tasks in PostgreSQL: %w", err)
+	}
+
+	direction, comparison := "DESC", "<"
+	if query.Order == service.OrderAsc {
+		direction, comparison = "ASC", ">"
+	}
+	if query.After != nil {
+		f.add(fmt.Sprintf("(created_at, id) %s (%s, %s)", comparison, f.arg(time.UnixMilli(query.After.CreatedAt)), f.arg(query.After.ID)))
+	}
+
+	// One task more than requested tells whether there is a next page.
+	sql := fmt.Sprintf("SELECT %s FROM tasks%s ORDER BY created_at %s, id %s LIMIT %s",
+		taskColumns, f.where(), direction, direction, f.arg(query.Limit+1))
+	rows, err := s.pool.Query(ctx, sql, f.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks from PostgreSQL: %w", err)
+	}
+	defer rows.Close()
+
+	page.Tasks = []service.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tasks from PostgreSQL: %w", err)
+		}
+		page.Tasks = append(page.Tasks, *task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tasks from PostgreSQL: %w", err)
+	}
+
+	if len(page.Tasks) > query.Limit {
+		page.Tasks = page.Tasks[:query.Limit]
+		page.HasMore = true
+	}
+	return page, nil
+}
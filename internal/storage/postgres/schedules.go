@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/service"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *PostgresStorage) SaveSchedule(ctx context.Context, schedule service.Schedule) error {
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, `INSERT INTO schedules (id, created_at, data) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET created_at = EXCLUDED.created_at, data = EXCLUDED.data`,
+		schedule.ID, schedule.CreatedAt, data)
+	if err != nil {
+		return fmt.Errorf("failed to save schedule in PostgreSQL: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetSchedule(ctx context.Context, id string) (*service.Schedule, error) {
+	var data []byte
+	if err := s.pool.QueryRow(ctx, "SELECT data FROM schedules WHERE id = $1", id).Scan(&data); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ScheduleNotFound
+		}
+		return nil, fmt.Errorf("failed to get schedule from PostgreSQL: %w", err)
+	}
+
+	var schedule service.Schedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schedule: %w", err)
+	}
+	return &schedule, nil
+}
+
+func (s *PostgresStorage) GetSchedules(ctx context.Context) ([]service.Schedule, error) {
+	rows, err := s.pool.Query(ctx, "SELECT data FROM schedules ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules from PostgreSQL: %w", err)
+	}
+
+	schedules, err := scanJSON[service.Schedule](rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules from PostgreSQL: %w", err)
+	}
+	return schedules, nil
+}
+
+func (s *PostgresStorage) DeleteSchedule(ctx context.Context, id string) error {
+	var deleted int64
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, "DELETE FROM schedules WHERE id = $1", id)
+		if err != nil {
+			return err
+		}
+		deleted = tag.RowsAffected()
+		_, err = tx.Exec(ctx, "DELETE FROM schedule_runs WHERE schedule_id = $1", id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule from PostgreSQL: %w", err)
+	}
+
+	if deleted == 0 {
+		return domain.ScheduleNotFound
+	}
+	return nil
+}
+
+// AddScheduleRun records a run and keeps only the last limit runs of the
+// schedule.
+func (s *PostgresStorage) AddScheduleRun(ctx context.Context, id string, run service.ScheduleRun, limit int) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule run: %w", err)
+	}
+
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "INSERT INTO schedule_runs (schedule_id, data) VALUES ($1, $2)", id, data); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM schedule_runs WHERE schedule_id = $1 AND id < (
+			SELECT min(id) FROM (
+				SELECT id FROM schedule_runs WHERE schedule_id = $1 ORDER BY id DESC LIMIT $2
+			) AS kept
+		)`, id, limit)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save schedule run in PostgreSQL: %w", err)
+	}
+	return nil
+}
+
+// GetScheduleRuns returns up to limit runs of the schedule, latest first.
+func (s *PostgresStorage) GetScheduleRuns(ctx context.Context, id string, limit int) ([]service.ScheduleRun, error) {
+	rows, err := s.pool.Query(ctx, "SELECT data FROM schedule_runs WHERE schedule_id = $1 ORDER BY id DESC LIMIT $2", id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule runs from PostgreSQL: %w", err)
+	}
+
+	runs, err := scanJSON[service.ScheduleRun](rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule runs from PostgreSQL: %w", err)
+	}
+	return runs, nil
+}
+
+// AcquireLeadership takes the scheduler lease if it is free or expired, or
+// extends it if it is already held by the same instance.
+func (s *PostgresStorage) AcquireLeadership(ctx context.Context, instanceID string, ttl time.Duration) (bool, error) {
+	var holder string
+	err := s.pool.QueryRow(ctx, `INSERT INTO scheduler_leader (holder, expires_at)
+		VALUES ($1, now() + $2::interval)
+		ON CONFLICT (id) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE scheduler_leader.holder = EXCLUDED.holder OR scheduler_leader.expires_at <= now()
+		RETURNING holder`, instanceID, ttl).Scan(&holder)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to acquire scheduler leadership: %w", err)
+	}
+	return true, nil
+}
+
+// scanJSON reads rows of a single JSONB column into values of type T.
+func scanJSON[T any](rows pgx.Rows) ([]T, error) {
+	defer rows.Close()
+
+	values := []T{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var value T
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal row: %w", err)
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"task-runner-service/internal/metrics"
+	"task-runner-service/internal/tracing"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type queryKey struct{}
+
+type queryStart struct {
+	operation string
+	at        time.Time
+}
+
+// queryTracer records the latency of every statement issued by the storage,
+// labelled with its first keyword, and wraps it in a client span so storage
+// calls show up inside request and task traces.
+type queryTracer struct{}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := "query"
+	if fields := strings.Fields(data.SQL); len(fields) > 0 {
+		operation = strings.ToLower(fields[0])
+	}
+
+	ctx, _ = tracing.Tracer().Start(ctx, "postgres "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql")),
+	)
+	return context.WithValue(ctx, queryKey{}, queryStart{operation: operation, at: time.Now()})
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	err := data.Err
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+	}
+	if start, ok := ctx.Value(queryKey{}).(queryStart); ok {
+		metrics.ObserveStorage(start.operation, time.Since(start.at), err)
+	}
+	tracing.End(trace.SpanFromContext(ctx), err)
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/service"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *PostgresStorage) SaveWorkflow(ctx context.Context, workflow service.Workflow) error {
+	data, err := json.Marshal(workflow)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workflow: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, `INSERT INTO workflows (id, data) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data`, workflow.ID, data)
+	if err != nil {
+		return fmt.Errorf("failed to save workflow in PostgreSQL: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetWorkflow(ctx context.Context, id string) (*service.Workflow, error) {
+	var data []byte
+	if err := s.pool.QueryRow(ctx, "SELECT data FROM workflows WHERE id = $1", id).Scan(&data); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.WorkflowNotFound
+		}
+		return nil, fmt.Errorf("failed to get workflow from PostgreSQL: %w", err)
+	}
+
+	var workflow service.Workflow
+	if err := json.Unmarshal(data, &workflow); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal workflow: %w", err)
+	}
+	return &workflow, nil
+}
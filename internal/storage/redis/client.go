@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"task-runner-service/internal/config"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const defaultAddr = "localhost:6379"
+
+// Options resolves cfg into the options of a universal client. They are
+// shared by the storage and the machinery broker and backend, so that all of
+// them connect to the same deployment the same way.
+func Options(cfg config.RedisConfig) (*redis.UniversalOptions, error) {
+	if cfg.Cluster != nil && len(cfg.Cluster.Addrs) > 0 && cfg.Sentinel != nil && cfg.Sentinel.MasterName != "" {
+		return nil, fmt.Errorf("redis: sentinel and cluster are mutually exclusive")
+	}
+
+	opts := &redis.UniversalOptions{
+		PoolSize:     cfg.PoolSize,
+		MinIdleConns: cfg.MinIdleConns,
+		DialTimeout:  cfg.DialTimeout,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		PoolTimeout:  cfg.PoolTimeout,
+	}
+
+	switch cfg.Mode() {
+	case config.RedisCluster:
+		opts.Addrs = cfg.Cluster.Addrs
+	case config.RedisSentinel:
+		if len(cfg.Sentinel.Addrs) == 0 {
+			return nil, fmt.Errorf("redis: sentinel.addrs is required with sentinel.master_name")
+		}
+		opts.MasterName = cfg.Sentinel.MasterName
+		opts.Addrs = cfg.Sentinel.Addrs
+		opts.SentinelPassword = cfg.Sentinel.Password
+	default:
+		if err := applyURL(opts, cfg.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Password != "" {
+		opts.Password = cfg.Password
+	}
+	if cfg.DB != 0 {
+		opts.DB = cfg.DB
+	}
+	if cfg.TLS != nil && cfg.TLS.Enabled {
+		tlsConfig, err := newTLSConfig(*cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+	return opts, nil
+}
+
+// applyURL sets the address and credentials of a single server from a
+// redis:// or rediss:// URL, or from a bare host:port.
+func applyURL(opts *redis.UniversalOptions, rawURL string) error {
+	if rawURL == "" {
+		opts.Addrs = []string{defaultAddr}
+		return nil
+	}
+	if !strings.Contains(rawURL, "://") {
+		opts.Addrs = []string{rawURL}
+		return nil
+	}
+
+	parsed, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return fmt.Errorf("redis: invalid url: %w", err)
+	}
+	opts.Addrs = []string{parsed.Addr}
+	opts.Username = parsed.Username
+	opts.Password = parsed.Password
+	opts.DB = parsed.DB
+	opts.TLSConfig = parsed.TLSConfig
+	return nil
+}
+
+func newTLSConfig(cfg config.RedisTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis: no certificates found in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// NewClient returns a client for the deployment cfg describes. A cluster is
+// addressed by its seeds even when there is only one of them, which
+// redis.NewUniversalClient would take for a single server.
+func NewClient(cfg config.RedisConfig) (redis.UniversalClient, error) {
+	opts, err := Options(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Mode() {
+	case config.RedisCluster:
+		return redis.NewClusterClient(opts.Cluster()), nil
+	case config.RedisSentinel:
+		return redis.NewFailoverClient(opts.Failover()), nil
+	}
+	return redis.NewClient(opts.Simple()), nil
+}
@@ -0,0 +1,159 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/service"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Dead letters are stored in one hash and indexed by failure time in a
+// sorted set, plus one sorted set per task name for filtered listings.
+const (
+	deadLettersKey         = "dlq"
+	deadLetterIndexKey     = "dlq:index"
+	deadLetterNameIndexKey = "dlq:name:"
+)
+
+func (s *RedisStorage) SaveDeadLetter(ctx context.Context, letter service.DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	score := float64(letter.FailedAt.UnixMilli())
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, s.key(deadLettersKey), letter.ID, data)
+	pipe.ZAdd(ctx, s.key(deadLetterIndexKey), &redis.Z{Score: score, Member: letter.ID})
+	pipe.ZAdd(ctx, s.key(deadLetterNameIndexKey+letter.Name), &redis.Z{Score: score, Member: letter.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save dead letter in Redis: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisStorage) GetDeadLetter(ctx context.Context, id string) (*service.DeadLetter, error) {
+	data, err := s.client.HGet(ctx, s.key(deadLettersKey), id).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, domain.DeadLetterNotFound
+		}
+		return nil, fmt.Errorf("failed to get dead letter from Redis: %w", err)
+	}
+
+	var letter service.DeadLetter
+	if err := json.Unmarshal(data, &letter); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+	}
+
+	return &letter, nil
+}
+
+// GetDeadLetters returns a page of dead letters, most recent failure first,
+// and the total number matching name (all of them when name is empty).
+func (s *RedisStorage) GetDeadLetters(ctx context.Context, name string, limit, offset int) ([]service.DeadLetter, int, error) {
+	index := s.key(deadLetterIndexKey)
+	if name != "" {
+		index = s.key(deadLetterNameIndexKey + name)
+	}
+
+	total, err := s.client.ZCard(ctx, index).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead letters in Redis: %w", err)
+	}
+
+	ids, err := s.client.ZRevRange(ctx, index, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead letters from Redis: %w", err)
+	}
+
+	letters, err := s.getDeadLetters(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	return letters, int(total), nil
+}
+
+func (s *RedisStorage) DeleteDeadLetter(ctx context.Context, id string) error {
+	letter, err := s.GetDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.removeDeadLetters(ctx, []service.DeadLetter{*letter})
+}
+
+// PurgeDeadLetters deletes every dead letter of the named task type, or all
+// of them when name is empty, and returns how many were deleted.
+func (s *RedisStorage) PurgeDeadLetters(ctx context.Context, name string) (int, error) {
+	index := s.key(deadLetterIndexKey)
+	if name != "" {
+		index = s.key(deadLetterNameIndexKey + name)
+	}
+
+	ids, err := s.client.ZRange(ctx, index, 0, -1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list dead letters from Redis: %w", err)
+	}
+
+	letters, err := s.getDeadLetters(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.removeDeadLetters(ctx, letters); err != nil {
+		return 0, err
+	}
+	return len(letters), nil
+}
+
+func (s *RedisStorage) getDeadLetters(ctx context.Context, ids []string) ([]service.DeadLetter, error) {
+	if len(ids) == 0 {
+		return []service.DeadLetter{}, nil
+	}
+
+	values, err := s.client.HMGet(ctx, s.key(deadLettersKey), ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letters from Redis: %w", err)
+	}
+
+	letters := make([]service.DeadLetter, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		var letter service.DeadLetter
+		if err := json.Unmarshal([]byte(data), &letter); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, nil
+}
+
+func (s *RedisStorage) removeDeadLetters(ctx context.Context, letters []service.DeadLetter) error {
+	if len(letters) == 0 {
+		return nil
+	}
+
+	pipe := s.client.TxPipeline()
+	for _, letter := range letters {
+		pipe.HDel(ctx, s.key(deadLettersKey), letter.ID)
+		pipe.ZRem(ctx, s.key(deadLetterIndexKey), letter.ID)
+		pipe.ZRem(ctx, s.key(deadLetterNameIndexKey+letter.Name), letter.ID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete dead letters from Redis: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"task-runner-service/internal/service"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const heartbeatKeyPrefix = "workers:heartbeat:"
+
+// SaveHeartbeat stores the heartbeat of a worker. It expires after ttl, so
+// workers that stopped disappear from the listing on their own.
+func (s *RedisStorage) SaveHeartbeat(ctx context.Context, heartbeat service.Heartbeat, ttl time.Duration) error {
+	data, err := json.Marshal(heartbeat)
+	if err != nil {
+		return fmt.Errorf("failed to marshal heartbeat: %w", err)
+	}
+	if err := s.client.Set(ctx, s.key(heartbeatKeyPrefix+heartbeat.WorkerID), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save heartbeat in Redis: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStorage) GetHeartbeats(ctx context.Context) ([]service.Heartbeat, error) {
+	scanner, err := s.scanner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	iter := scanner.Scan(ctx, 0, s.key(heartbeatKeyPrefix)+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list heartbeats in Redis: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get heartbeats from Redis: %w", err)
+	}
+
+	heartbeats := make([]service.Heartbeat, 0, len(values))
+	for _, value := range values {
+		// Keys may expire between SCAN and MGET.
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var heartbeat service.Heartbeat
+		if err := json.Unmarshal([]byte(data), &heartbeat); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal heartbeat: %w", err)
+		}
+		heartbeats = append(heartbeats, heartbeat)
+	}
+	sort.Slice(heartbeats, func(i, j int) bool {
+		return heartbeats[i].WorkerID < heartbeats[j].WorkerID
+	})
+	return heartbeats, nil
+}
+
+// Ping checks the connection to Redis.
+func (s *RedisStorage) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Close closes the connections to Redis.
+func (s *RedisStorage) Close() error {
+	return s.client.Close()
+}
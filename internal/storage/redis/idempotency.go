@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-runner-service/internal/service"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const idempotencyKeyPrefix = "idempotency:"
+
+// ReserveIdempotencyKey stores record unless its key is already taken, in
+// which case the record stored earlier is returned.
+func (s *RedisStorage) ReserveIdempotencyKey(ctx context.Context, record service.IdempotencyRecord, ttl time.Duration) (*service.IdempotencyRecord, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	reserved, err := s.client.SetNX(ctx, s.key(idempotencyKeyPrefix+record.Key), data, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key in Redis: %w", err)
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := s.client.Get(ctx, s.key(idempotencyKeyPrefix+record.Key)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			// The key expired in between; try again.
+			return s.ReserveIdempotencyKey(ctx, record, ttl)
+		}
+		return nil, fmt.Errorf("failed to get idempotency key from Redis: %w", err)
+	}
+
+	var stored service.IdempotencyRecord
+	if err := json.Unmarshal(existing, &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+	}
+
+	return &stored, nil
+}
+
+func (s *RedisStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, s.key(idempotencyKeyPrefix+key)).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key in Redis: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"task-runner-service/internal/metrics"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type startedAtKey struct{}
+
+// metricsHook records the latency of every Redis command issued by the
+// storage, labelled with the command name.
+type metricsHook struct{}
+
+func (metricsHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startedAtKey{}, time.Now()), nil
+}
+
+func (metricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	observe(ctx, cmd.Name(), cmd.Err())
+	return nil
+}
+
+func (metricsHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startedAtKey{}, time.Now()), nil
+}
+
+func (metricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && cmdErr != redis.Nil {
+			err = cmdErr
+			break
+		}
+	}
+	observe(ctx, "pipeline", err)
+	return nil
+}
+
+func observe(ctx context.Context, operation string, err error) {
+	startedAt, ok := ctx.Value(startedAtKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	if err == redis.Nil {
+		err = nil
+	}
+	metrics.ObserveStorage(operation, time.Since(startedAt), err)
+}
@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"task-runner-service/internal/service"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	migrationBatchSize = 500
+
+	// taskIndexVersion is increased whenever indexes are added, so that
+	// existing tasks are indexed again on startup.
+	taskIndexVersion = 2
+)
+
+// migrateTaskIndex builds the task indexes from the task hash when they are
+// older than taskIndexVersion, e.g. for data written before an index
+// existed, and removes the unordered status sets they replace.
+func (s *RedisStorage) migrateTaskIndex(ctx context.Context) error {
+	version, err := s.client.Get(ctx, s.key(indexVersionKey)).Int()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to read task index version from Redis: %w", err)
+	}
+	if version >= taskIndexVersion {
+		return nil
+	}
+
+	var cursor uint64
+	for {
+		fields, next, err := s.client.HScan(ctx, s.key(tasksKey), cursor, "", migrationBatchSize).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan tasks in Redis: %w", err)
+		}
+
+		pipe := s.client.Pipeline()
+		for i := 0; i+1 < len(fields); i += 2 {
+			var task service.Task
+			if err := json.Unmarshal([]byte(fields[i+1]), &task); err != nil {
+				continue
+			}
+			s.indexTask(ctx, pipe, task)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to index tasks in Redis: %w", err)
+		}
+
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+
+	if err := s.deleteKeys(ctx, s.key(legacyStatusPrefix)+"*"); err != nil {
+		return err
+	}
+	if err := s.client.Set(ctx, s.key(indexVersionKey), taskIndexVersion, 0).Err(); err != nil {
+		return fmt.Errorf("failed to store task index version in Redis: %w", err)
+	}
+	return nil
+}
+
+// indexTask adds the task to the indexes saveTaskScript maintains.
+func (s *RedisStorage) indexTask(ctx context.Context, pipe redis.Pipeliner, task service.Task) {
+	created := float64(service.CursorTime(task.CreatedAt))
+	member := &redis.Z{Score: created, Member: task.ID}
+
+	pipe.ZAdd(ctx, s.key(taskIndexKey), member)
+	pipe.ZAdd(ctx, s.key(statusIndexPrefix+task.Status), member)
+	for _, index := range s.fixedIndexes(task) {
+		pipe.ZAdd(ctx, index, member)
+	}
+	if task.FinishedAt != nil {
+		pipe.ZAdd(ctx, s.key(finishedIndexKey), &redis.Z{Score: float64(service.CursorTime(*task.FinishedAt)), Member: task.ID})
+	}
+	if task.Error != "" {
+		pipe.ZAdd(ctx, s.key(errorIndexKey), member)
+	}
+}
+
+func (s *RedisStorage) deleteKeys(ctx context.Context, pattern string) error {
+	scanner, err := s.scanner(ctx)
+	if err != nil {
+		return err
+	}
+
+	var cursor uint64
+	for {
+		keys, next, err := scanner.Scan(ctx, cursor, pattern, migrationBatchSize).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan keys in Redis: %w", err)
+		}
+		if len(keys) > 0 {
+			if err := s.client.Del(ctx, keys...).Err(); err != nil {
+				return fmt.Errorf("failed to delete keys in Redis: %w", err)
+			}
+		}
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
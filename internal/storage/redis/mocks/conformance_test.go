@@ -0,0 +1,24 @@
+package mocks
+
+import (
+	"testing"
+	"time"
+
+	"task-runner-service/internal/config"
+	"task-runner-service/internal/storage"
+	"task-runner-service/internal/storage/conformance"
+	"task-runner-service/internal/storage/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) (storage.Storage, func(time.Duration)) {
+		mr := miniredis.RunT(t)
+		store, err := redis.NewStorage(config.RedisConfig{URL: mr.Addr()})
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+		return store, mr.FastForward
+	})
+}
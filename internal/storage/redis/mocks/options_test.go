@@ -0,0 +1,98 @@
+package mocks
+
+import (
+	"testing"
+	"time"
+
+	"task-runner-service/internal/config"
+	"task-runner-service/internal/storage/redis"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptions_Standalone(t *testing.T) {
+	cases := []struct {
+		name         string
+		cfg          config.RedisConfig
+		wantAddr     string
+		wantPassword string
+		wantDB       int
+		wantTLS      bool
+	}{
+		{"Default", config.RedisConfig{}, "localhost:6379", "", 0, false},
+		{"HostPort", config.RedisConfig{URL: "redis:6379", Password: "secret", DB: 2}, "redis:6379", "secret", 2, false},
+		{"URL", config.RedisConfig{URL: "redis://:secret@redis:6380/3"}, "redis:6380", "secret", 3, false},
+		{"OverridesURL", config.RedisConfig{URL: "redis://:secret@redis:6380/3", Password: "other", DB: 4}, "redis:6380", "other", 4, false},
+		{"TLSScheme", config.RedisConfig{URL: "rediss://redis.example.com:6380"}, "redis.example.com:6380", "", 0, true},
+		{"TLSEnabled", config.RedisConfig{URL: "redis:6379", TLS: &config.RedisTLSConfig{Enabled: true}}, "redis:6379", "", 0, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, config.RedisStandalone, c.cfg.Mode())
+
+			opts, err := redis.Options(c.cfg)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{c.wantAddr}, opts.Addrs)
+			assert.Equal(t, c.wantPassword, opts.Password)
+			assert.Equal(t, c.wantDB, opts.DB)
+			assert.Equal(t, c.wantTLS, opts.TLSConfig != nil)
+		})
+	}
+}
+
+func TestOptions_Sentinel(t *testing.T) {
+	cfg := config.RedisConfig{
+		URL:      "ignored:6379",
+		Password: "secret",
+		Sentinel: &config.RedisSentinelConfig{
+			MasterName: "mymaster",
+			Addrs:      []string{"sentinel-1:26379", "sentinel-2:26379"},
+			Password:   "sentinel-secret",
+		},
+		PoolSize:    20,
+		ReadTimeout: 2 * time.Second,
+	}
+	assert.Equal(t, config.RedisSentinel, cfg.Mode())
+
+	opts, err := redis.Options(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "mymaster", opts.MasterName)
+	assert.Equal(t, cfg.Sentinel.Addrs, opts.Addrs)
+	assert.Equal(t, "secret", opts.Password)
+	assert.Equal(t, "sentinel-secret", opts.SentinelPassword)
+	assert.Equal(t, 20, opts.PoolSize)
+	assert.Equal(t, 2*time.Second, opts.ReadTimeout)
+}
+
+func TestOptions_Cluster(t *testing.T) {
+	cfg := config.RedisConfig{Cluster: &config.RedisClusterConfig{Addrs: []string{"node-1:6379"}}}
+	assert.Equal(t, config.RedisCluster, cfg.Mode())
+
+	opts, err := redis.Options(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node-1:6379"}, opts.Addrs)
+	assert.Empty(t, opts.MasterName)
+}
+
+func TestOptions_Invalid(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  config.RedisConfig
+	}{
+		{"SentinelAndCluster", config.RedisConfig{
+			Sentinel: &config.RedisSentinelConfig{MasterName: "mymaster", Addrs: []string{"sentinel:26379"}},
+			Cluster:  &config.RedisClusterConfig{Addrs: []string{"node-1:6379"}},
+		}},
+		{"SentinelWithoutAddrs", config.RedisConfig{Sentinel: &config.RedisSentinelConfig{MasterName: "mymaster"}}},
+		{"BadURL", config.RedisConfig{URL: "http://redis:6379"}},
+		{"MissingCA", config.RedisConfig{TLS: &config.RedisTLSConfig{Enabled: true, CAFile: "/nonexistent/ca.pem"}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := redis.Options(c.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
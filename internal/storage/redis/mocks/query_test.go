@@ -0,0 +1,55 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/config"
+	"task-runner-service/internal/service"
+	"task-runner-service/internal/storage/redis"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTasks_ErrorTotalApproximate(t *testing.T) {
+	ctx := context.Background()
+	store, err := redis.NewStorage(config.RedisConfig{URL: miniredis.RunT(t).Addr()})
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 6000; i++ {
+		message := "connection refused"
+		if i%3 == 0 {
+			message = "i/o timeout"
+		}
+		require.NoError(t, store.SaveTask(ctx, service.Task{
+			ID:        fmt.Sprintf("task_%04d", i),
+			Name:      "add",
+			Status:    tasks.StateFailure,
+			Error:     message,
+			CreatedAt: created.Add(time.Duration(i) * time.Millisecond),
+		}))
+	}
+
+	page, err := store.GetTasks(ctx, service.TaskQuery{ErrorContains: "Timeout", Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, page.Tasks, 10)
+	assert.True(t, page.HasMore)
+	assert.True(t, page.TotalApproximate)
+	assert.InDelta(t, 2000, page.Total, 10)
+
+	page, err = store.GetTasks(ctx, service.TaskQuery{ErrorContains: "timeout", Limit: 10, CreatedFrom: ptr(created.Add(3000 * time.Millisecond))})
+	require.NoError(t, err)
+	assert.False(t, page.TotalApproximate)
+	assert.Equal(t, 1000, page.Total)
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,84 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/config"
+	"task-runner-service/internal/registry"
+	"task-runner-service/internal/service"
+	"task-runner-service/internal/storage/redis"
+	"task-runner-service/internal/worker"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// interleavedStorage runs between after the first update has read the task
+// and before it is saved, as a concurrent writer would.
+type interleavedStorage struct {
+	*redis.RedisStorage
+	once    sync.Once
+	between func()
+}
+
+func (s *interleavedStorage) UpdateTask(ctx context.Context, id string, update func(task *service.Task) error) (*service.Task, error) {
+	return s.RedisStorage.UpdateTask(ctx, id, func(task *service.Task) error {
+		s.once.Do(s.between)
+		return update(task)
+	})
+}
+
+func newInterleavedStorage(t *testing.T) *interleavedStorage {
+	store, err := redis.NewStorage(config.RedisConfig{URL: miniredis.RunT(t).Addr()})
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	require.NoError(t, store.SaveTask(context.Background(), service.Task{
+		ID:        "task_1",
+		Name:      "add",
+		Status:    tasks.StatePending,
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}))
+	return &interleavedStorage{RedisStorage: store}
+}
+
+func TestUpdateTask_StartDuringCancel(t *testing.T) {
+	store := newInterleavedStorage(t)
+	store.between = func() {
+		worker.NewLifecycle(store.RedisStorage, nil).PreTask(&tasks.Signature{UUID: "task_1", Name: "add"})
+	}
+
+	runnerService := service.NewRunnerService(nil, store, registry.New())
+	response, err := runnerService.CancelTask(context.Background(), "task_1")
+	require.NoError(t, err)
+	assert.Equal(t, service.StateCancelled, response.Status)
+
+	task, err := store.GetTask(context.Background(), "task_1")
+	require.NoError(t, err)
+	assert.Equal(t, service.StateCancelled, task.Status)
+	assert.NotNil(t, task.StartedAt)
+	assert.NotNil(t, task.FinishedAt)
+	assert.Equal(t, 1, task.Attempt)
+}
+
+func TestUpdateTask_CancelDuringStart(t *testing.T) {
+	store := newInterleavedStorage(t)
+	store.between = func() {
+		runnerService := service.NewRunnerService(nil, store.RedisStorage, registry.New())
+		_, err := runnerService.CancelTask(context.Background(), "task_1")
+		require.NoError(t, err)
+	}
+
+	worker.NewLifecycle(store, nil).PreTask(&tasks.Signature{UUID: "task_1", Name: "add"})
+
+	task, err := store.GetTask(context.Background(), "task_1")
+	require.NoError(t, err)
+	assert.Equal(t, service.StateCancelled, task.Status)
+	assert.Nil(t, task.StartedAt)
+	assert.NotNil(t, task.FinishedAt)
+}
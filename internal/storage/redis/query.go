@@ -0,0 +1,350 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-runner-service/internal/service"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const (
+	queryKeyPrefix = "tasks:query:"
+	queryKeyTTL    = time.Minute
+	scanBatchSize  = 200
+
+	// errorCountLimit bounds how many tasks with an error are read to count
+	// the matches of an error search. Past it the total is extrapolated from
+	// the share of matches among the tasks read.
+	errorCountLimit = 5000
+)
+
+// window is a range of creation times in milliseconds; from is inclusive and
+// to exclusive.
+type window struct {
+	from, to *int64
+}
+
+func newWindow(from, to *time.Time) window {
+	var w window
+	if from != nil {
+		ms := service.CursorTime(*from)
+		w.from = &ms
+	}
+	if to != nil {
+		ms := service.CursorTime(*to)
+		w.to = &ms
+	}
+	return w
+}
+
+func (w window) min() string {
+	if w.from == nil {
+		return "-inf"
+	}
+	return strconv.FormatInt(*w.from, 10)
+}
+
+func (w window) max() string {
+	if w.to == nil {
+		return "+inf"
+	}
+	return "(" + strconv.FormatInt(*w.to, 10)
+}
+
+// GetTasks returns a page of tasks ordered by creation time. Filters are
+// resolved by intersecting the sorted set indexes, so only matching tasks
+// are loaded; the error substring is the exception and is checked on the
+// tasks that have an error. The position of the cursor is looked up by
+// value, so pages neither skip nor repeat tasks when others are added or
+// change status in between.
+func (s *RedisStorage) GetTasks(ctx context.Context, query service.TaskQuery) (*service.TaskPage, error) {
+	index, cleanup, err := s.filterIndex(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	w := newWindow(query.CreatedFrom, query.CreatedTo)
+	desc := query.Order != service.OrderAsc
+	if query.ErrorContains != "" {
+		return s.searchErrors(ctx, index, w, query, desc)
+	}
+
+	total, err := s.client.ZCount(ctx, index, w.min(), w.max()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks in Redis: %w", err)
+	}
+
+	// One task more than requested tells whether there is a next page.
+	ids, err := s.rangeIDs(ctx, index, w, query.After, desc, 0, int64(query.Limit)+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &service.TaskPage{Total: int(total)}
+	if len(ids) > query.Limit {
+		ids = ids[:query.Limit]
+		page.HasMore = true
+	}
+	if page.Tasks, err = s.getTasks(ctx, ids); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// filterIndex returns a sorted set of the tasks matching the index-backed
+// filters, scored by creation time. Unless a single index answers the query,
+// it is built in temporary keys removed by the returned function.
+func (s *RedisStorage) filterIndex(ctx context.Context, query service.TaskQuery) (string, func(), error) {
+	var groups [][]string
+	if len(query.Statuses) > 0 {
+		groups = append(groups, s.prefixed(statusIndexPrefix, query.Statuses))
+	}
+	if len(query.Names) > 0 {
+		groups = append(groups, s.prefixed(nameIndexPrefix, query.Names))
+	}
+	if len(query.Queues) > 0 {
+		queues := make([]string, 0, len(query.Queues))
+		for _, queue := range query.Queues {
+			queues = append(queues, service.QueueName(queue))
+		}
+		groups = append(groups, s.prefixed(queueIndexPrefix, queues))
+	}
+	for key, value := range query.Labels {
+		groups = append(groups, []string{s.labelIndex(key, value)})
+	}
+	if query.ErrorContains != "" {
+		groups = append(groups, []string{s.key(errorIndexKey)})
+	}
+
+	finished := query.FinishedFrom != nil || query.FinishedTo != nil
+	if len(groups) == 0 && !finished {
+		return s.key(taskIndexKey), func() {}, nil
+	}
+	if len(groups) == 1 && len(groups[0]) == 1 && !finished {
+		return groups[0][0], func() {}, nil
+	}
+
+	prefix := s.key(queryKeyPrefix) + uuid.New().String()
+	var temp []string
+	newKey := func() string {
+		key := fmt.Sprintf("%s:%d", prefix, len(temp))
+		temp = append(temp, key)
+		return key
+	}
+
+	pipe := s.client.TxPipeline()
+	keys := make([]string, 0, len(groups)+1)
+	for _, group := range groups {
+		if len(group) == 1 {
+			keys = append(keys, group[0])
+			continue
+		}
+		union := newKey()
+		pipe.ZUnionStore(ctx, union, &redis.ZStore{Keys: group, Aggregate: "MAX"})
+		keys = append(keys, union)
+	}
+
+	if finished {
+		// Score the candidates by finishing time to cut the range, then
+		// restore the creation time scores.
+		weights := make([]float64, len(keys)+1)
+		weights[len(keys)] = 1
+		byFinish := newKey()
+		pipe.ZInterStore(ctx, byFinish, &redis.ZStore{Keys: append(keys, s.key(finishedIndexKey)), Weights: weights})
+		fw := newWindow(query.FinishedFrom, query.FinishedTo)
+		if fw.from != nil {
+			pipe.ZRemRangeByScore(ctx, byFinish, "-inf", "("+strconv.FormatInt(*fw.from, 10))
+		}
+		if fw.to != nil {
+			pipe.ZRemRangeByScore(ctx, byFinish, strconv.FormatInt(*fw.to, 10), "+inf")
+		}
+		keys = []string{byFinish, s.key(taskIndexKey)}
+		result := newKey()
+		pipe.ZInterStore(ctx, result, &redis.ZStore{Keys: keys, Weights: []float64{0, 1}})
+		keys = []string{result}
+	} else if len(keys) > 1 {
+		result := newKey()
+		pipe.ZInterStore(ctx, result, &redis.ZStore{Keys: keys, Aggregate: "MAX"})
+		keys = []string{result}
+	}
+	for _, key := range temp {
+		pipe.Expire(ctx, key, queryKeyTTL)
+	}
+
+	// Should the deletion fail, the keys expire on their own.
+	cleanup := func() {
+		s.client.Del(context.Background(), temp...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to filter tasks in Redis: %w", err)
+	}
+	return keys[0], cleanup, nil
+}
+
+// rangeIDs returns up to count IDs from index within w, after skipping offset
+// IDs that follow the cursor. Tasks created in the same millisecond are
+// ordered by ID, as Redis orders members with equal scores.
+func (s *RedisStorage) rangeIDs(ctx context.Context, index string, w window, after *service.Cursor, desc bool, offset, count int64) ([]string, error) {
+	by := &redis.ZRangeBy{Min: w.min(), Max: w.max(), Offset: offset, Count: count}
+
+	if after != nil {
+		pastEnd := (desc && w.from != nil && after.CreatedAt < *w.from) ||
+			(!desc && w.to != nil && after.CreatedAt >= *w.to)
+		if pastEnd {
+			return nil, nil
+		}
+		// A cursor before the start of the window leaves the range as is.
+		inWindow := (w.from == nil || after.CreatedAt >= *w.from) && (w.to == nil || after.CreatedAt < *w.to)
+		if inWindow {
+			score := strconv.FormatInt(after.CreatedAt, 10)
+			ties, err := s.client.ZRangeByScore(ctx, index, &redis.ZRangeBy{Min: score, Max: score}).Result()
+			if err != nil {
+				return nil, fmt.Errorf("failed to find cursor position in Redis: %w", err)
+			}
+			for _, id := range ties {
+				if (desc && id >= after.ID) || (!desc && id <= after.ID) {
+					by.Offset++
+				}
+			}
+			if desc {
+				by.Max = score
+			} else {
+				by.Min = score
+			}
+		}
+	}
+
+	var ids []string
+	var err error
+	if desc {
+		ids, err = s.client.ZRevRangeByScore(ctx, index, by).Result()
+	} else {
+		ids, err = s.client.ZRangeByScore(ctx, index, by).Result()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks from Redis: %w", err)
+	}
+	return ids, nil
+}
+
+// searchErrors pages through the candidate tasks, all of which have an
+// error, and keeps those whose error contains the query, case-insensitively.
+func (s *RedisStorage) searchErrors(ctx context.Context, index string, w window, query service.TaskQuery, desc bool) (*service.TaskPage, error) {
+	needle := strings.ToLower(query.ErrorContains)
+	matches := func(task service.Task) bool {
+		return strings.Contains(strings.ToLower(task.Error), needle)
+	}
+
+	page := &service.TaskPage{Tasks: []service.Task{}}
+	for offset := int64(0); !page.HasMore; offset += scanBatchSize {
+		ids, err := s.rangeIDs(ctx, index, w, query.After, desc, offset, scanBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		tasks, err := s.getTasks(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			if !matches(task) {
+				continue
+			}
+			if len(page.Tasks) == query.Limit {
+				page.HasMore = true
+				break
+			}
+			page.Tasks = append(page.Tasks, task)
+		}
+		if len(ids) < scanBatchSize {
+			break
+		}
+	}
+
+	// Without a cursor or a next page every match has been seen already.
+	page.Total = len(page.Tasks)
+	if query.After != nil || page.HasMore {
+		if err := s.countErrors(ctx, page, index, w, matches); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// countErrors sets the total of an error search. It reads at most
+// errorCountLimit candidates and estimates the total beyond that.
+func (s *RedisStorage) countErrors(ctx context.Context, page *service.TaskPage, index string, w window, matches func(service.Task) bool) error {
+	candidates, err := s.client.ZCount(ctx, index, w.min(), w.max()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to count tasks in Redis: %w", err)
+	}
+
+	count, read := 0, int64(0)
+	for read < candidates && read < errorCountLimit {
+		ids, err := s.rangeIDs(ctx, index, w, nil, false, read, scanBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		tasks, err := s.getTasks(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			if matches(task) {
+				count++
+			}
+		}
+		read += int64(len(ids))
+	}
+
+	page.Total = count
+	if read < candidates {
+		page.Total = int(float64(count) * float64(candidates) / float64(read))
+		page.TotalApproximate = true
+	}
+	return nil
+}
+
+func (s *RedisStorage) getTasks(ctx context.Context, ids []string) ([]service.Task, error) {
+	if len(ids) == 0 {
+		return []service.Task{}, nil
+	}
+
+	values, err := s.client.HMGet(ctx, s.key(tasksKey), ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks from Redis: %w", err)
+	}
+
+	tasks := make([]service.Task, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var task service.Task
+		if err := json.Unmarshal([]byte(data), &task); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func (s *RedisStorage) prefixed(prefix string, values []string) []string {
+	keys := make([]string, 0, len(values))
+	for _, value := range values {
+		keys = append(keys, s.key(prefix+value))
+	}
+	return keys
+}
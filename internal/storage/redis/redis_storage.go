@@ -5,55 +5,257 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"time"
+	"strconv"
 
 	"task-runner-service/internal/config"
+	"task-runner-service/internal/domain"
 	"task-runner-service/internal/service"
 
+	"github.com/RichardKnop/machinery/v1/tasks"
 	"github.com/go-redis/redis/v8"
 )
 
+// saveTaskScript stores the task and updates its index entries in a single
+// step, so listings never see a task in two status indexes or in none. The
+// indexes are sorted sets scored by creation time, except the finished index
+// which is scored by finishing time. Tasks waiting in a queue are also kept in
+// a set per priority, which gives the queue depth.
+//
+// Every key is passed in KEYS, as Redis Cluster requires: the new and the
+// previous status index, then the name, queue and label indexes, which never
+// change for a task. The previous record is read before running the script;
+// should the stored one differ by then, the script changes nothing and returns
+// 0 so that the caller reads it again.
+var saveTaskScript = redis.NewScript(`
+local id, created, expected = ARGV[1], ARGV[3], ARGV[7]
+local prev = redis.call('HGET', KEYS[1], id) or ''
+if prev ~= expected then
+	return 0
+end
+if KEYS[7] ~= KEYS[6] then
+	redis.call('ZREM', KEYS[7], id)
+end
+redis.call('HSET', KEYS[1], id, ARGV[2])
+redis.call('ZADD', KEYS[2], created, id)
+redis.call('ZADD', KEYS[6], created, id)
+if ARGV[4] ~= '' then
+	redis.call('ZADD', KEYS[3], ARGV[4], id)
+else
+	redis.call('ZREM', KEYS[3], id)
+end
+if ARGV[5] == '1' then
+	redis.call('ZADD', KEYS[4], created, id)
+else
+	redis.call('ZREM', KEYS[4], id)
+end
+if ARGV[6] == '1' then
+	redis.call('SADD', KEYS[5], id)
+else
+	redis.call('SREM', KEYS[5], id)
+end
+for i = 8, #KEYS do
+	redis.call('ZADD', KEYS[i], created, id)
+end
+return 1
+`)
+
+// saveTaskAttempts bounds how many times SaveTask and UpdateTask read the
+// task again when concurrent writes keep changing it.
+const saveTaskAttempts = 10
+
+const (
+	tasksKey            = "tasks"
+	taskIndexKey        = "tasks:index"
+	statusIndexPrefix   = "tasks:index:status:"
+	nameIndexPrefix     = "tasks:index:name:"
+	queueIndexPrefix    = "tasks:index:queue:"
+	labelIndexPrefix    = "tasks:index:label:"
+	finishedIndexKey    = "tasks:index:finished"
+	errorIndexKey       = "tasks:index:error"
+	indexVersionKey     = "tasks:index:version"
+	legacyStatusPrefix  = "tasks:status:"
+	queuedKeyPrefix     = "tasks:queued:"
+	cancellationChannel = "tasks:cancel"
+
+	// clusterKeyPrefix is a hash tag that puts every key of the storage in
+	// the same cluster slot, as the scripts and transactions updating a task
+	// together with its indexes require.
+	clusterKeyPrefix = "{task-runner}:"
+)
+
 type RedisStorage struct {
-	client *redis.Client
+	client redis.UniversalClient
+	prefix string
 }
 
 func NewStorage(cfg config.RedisConfig) (*RedisStorage, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: cfg.Password,
-		DB:       cfg.DB,
-	})
+	client, err := NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client.AddHook(metricsHook{})
+	client.AddHook(tracingHook{})
 
 	if _, err := client.Ping(context.Background()).Result(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &RedisStorage{client: client}, nil
+	storage := &RedisStorage{client: client}
+	if cfg.Mode() == config.RedisCluster {
+		storage.prefix = clusterKeyPrefix
+	}
+	if err := storage.migrateTaskIndex(context.Background()); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return storage, nil
 }
 
 func (s *RedisStorage) SaveTask(ctx context.Context, task service.Task) error {
+	for attempt := 0; attempt < saveTaskAttempts; attempt++ {
+		prev, err := s.storedTask(ctx, task.ID)
+		if err != nil {
+			return fmt.Errorf("failed to save task in Redis: %w", err)
+		}
+		if saved, err := s.saveTask(ctx, task, prev); err != nil || saved {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to save task in Redis: task %s kept changing while saving", task.ID)
+}
+
+// UpdateTask applies update to the stored task and saves the result, unless
+// the task changed in between, in which case update runs again on the new
+// record.
+func (s *RedisStorage) UpdateTask(ctx context.Context, id string, update func(task *service.Task) error) (*service.Task, error) {
+	for attempt := 0; attempt < saveTaskAttempts; attempt++ {
+		prev, err := s.storedTask(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get task from Redis: %w", err)
+		}
+		if prev == "" {
+			return nil, domain.TaskNotFound
+		}
+
+		var task service.Task
+		if err := json.Unmarshal([]byte(prev), &task); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal task: %w", err)
+		}
+		if err := update(&task); err != nil {
+			return nil, err
+		}
+		saved, err := s.saveTask(ctx, task, prev)
+		if err != nil {
+			return nil, err
+		}
+		if saved {
+			return &task, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to save task in Redis: task %s kept changing while saving", id)
+}
+
+// saveTask runs saveTaskScript, which saves the task only if the stored
+// record is still prev, an empty string standing for no record.
+func (s *RedisStorage) saveTask(ctx context.Context, task service.Task, prev string) (bool, error) {
 	data, err := json.Marshal(task)
 	if err != nil {
-		return fmt.Errorf("failed to marshal task: %w", err)
+		return false, fmt.Errorf("failed to marshal task: %w", err)
 	}
 
-	pipe := s.client.TxPipeline()
-	pipe.HSet(ctx, "tasks", task.ID, data)
-	pipe.SAdd(ctx, fmt.Sprintf("tasks:status:%s", task.Status), task.ID)
-	pipe.Expire(ctx, task.ID, 72*time.Hour) // TTL 3 дня
+	priority := task.Priority
+	if priority == "" {
+		priority = service.PriorityNormal
+	}
+	finished := ""
+	if task.FinishedAt != nil {
+		finished = strconv.FormatInt(service.CursorTime(*task.FinishedAt), 10)
+	}
 
-	if _, err := pipe.Exec(ctx); err != nil {
-		return fmt.Errorf("failed to save task in Redis: %w", err)
+	statusKey := s.key(statusIndexPrefix + task.Status)
+	prevKey := statusKey
+	if prev != "" {
+		var stored struct{ Status string }
+		if err := json.Unmarshal([]byte(prev), &stored); err != nil {
+			return false, fmt.Errorf("failed to unmarshal task: %w", err)
+		}
+		prevKey = s.key(statusIndexPrefix + stored.Status)
 	}
 
-	return nil
+	keys := []string{
+		s.key(tasksKey), s.key(taskIndexKey), s.key(finishedIndexKey), s.key(errorIndexKey),
+		s.key(queuedKeyPrefix + priority), statusKey, prevKey,
+	}
+	keys = append(keys, s.fixedIndexes(task)...)
+	args := []interface{}{
+		task.ID, data, service.CursorTime(task.CreatedAt), finished,
+		flag(task.Error != ""), flag(task.Status == tasks.StatePending), prev,
+	}
+
+	saved, err := saveTaskScript.Run(ctx, s.client, keys, args...).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to save task in Redis: %w", err)
+	}
+	return saved == 1, nil
+}
+
+// storedTask returns the stored record of the task, or an empty string if
+// there is none.
+func (s *RedisStorage) storedTask(ctx context.Context, id string) (string, error) {
+	data, err := s.client.HGet(ctx, s.key(tasksKey), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return data, err
+}
+
+// fixedIndexes returns the indexes of the task attributes that never change.
+func (s *RedisStorage) fixedIndexes(task service.Task) []string {
+	indexes := []string{
+		s.key(nameIndexPrefix + task.Name),
+		s.key(queueIndexPrefix + service.QueueName(task.Queue)),
+	}
+	for key, value := range task.Labels {
+		indexes = append(indexes, s.labelIndex(key, value))
+	}
+	return indexes
+}
+
+func (s *RedisStorage) labelIndex(key, value string) string {
+	return s.key(labelIndexPrefix + key + "=" + value)
+}
+
+func (s *RedisStorage) key(name string) string {
+	return s.prefix + name
+}
+
+// scanner returns the client to SCAN the keys of the storage with. A cluster
+// client would scan a random node rather than the one owning the storage slot.
+func (s *RedisStorage) scanner(ctx context.Context) (redis.Cmdable, error) {
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		node, err := cluster.MasterForKey(ctx, s.prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the Redis cluster node of the storage: %w", err)
+		}
+		return node, nil
+	}
+	return s.client, nil
+}
+
+func flag(value bool) string {
+	if value {
+		return "1"
+	}
+	return ""
 }
 
 func (s *RedisStorage) GetTask(ctx context.Context, id string) (*service.Task, error) {
-	data, err := s.client.HGet(ctx, "tasks", id).Bytes()
+	data, err := s.client.HGet(ctx, s.key(tasksKey), id).Bytes()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return nil, fmt.Errorf("task not found")
+			return nil, domain.TaskNotFound
 		}
 		return nil, fmt.Errorf("failed to get task from Redis: %w", err)
 	}
@@ -66,38 +268,59 @@ func (s *RedisStorage) GetTask(ctx context.Context, id string) (*service.Task, e
 	return &task, nil
 }
 
-func (s *RedisStorage) GetTasks(ctx context.Context, status string, limit, offset int) ([]service.Task, error) {
-	var taskIDs []string
-	var err error
-
-	if status != "" {
-		taskIDs, err = s.client.SMembers(ctx, fmt.Sprintf("tasks:status:%s", status)).Result()
-	} else {
-		taskIDs, err = s.client.HKeys(ctx, "tasks").Result()
+func (s *RedisStorage) PublishCancellation(ctx context.Context, id string) error {
+	if err := s.client.Publish(ctx, cancellationChannel, id).Err(); err != nil {
+		return fmt.Errorf("failed to publish cancellation: %w", err)
 	}
+	return nil
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to get task IDs: %w", err)
+func (s *RedisStorage) SubscribeCancellations(ctx context.Context) (<-chan string, error) {
+	sub := s.client.Subscribe(ctx, cancellationChannel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("failed to subscribe to cancellations: %w", err)
 	}
 
-	if offset >= len(taskIDs) {
-		return []service.Task{}, nil
-	}
+	ids := make(chan string)
+	go func() {
+		defer close(ids)
+		defer sub.Close()
 
-	end := offset + limit
-	if end > len(taskIDs) {
-		end = len(taskIDs)
-	}
-	taskIDs = taskIDs[offset:end]
-
-	var tasks []service.Task
-	for _, id := range taskIDs {
-		task, err := s.GetTask(ctx, id)
-		if err != nil {
-			continue
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case ids <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
 		}
-		tasks = append(tasks, *task)
+	}()
+
+	return ids, nil
+}
+
+func (s *RedisStorage) GetQueueDepth(ctx context.Context) (map[string]int, error) {
+	pipe := s.client.Pipeline()
+	counts := make(map[string]*redis.IntCmd, len(service.Priorities))
+	for _, priority := range service.Priorities {
+		counts[priority] = pipe.SCard(ctx, s.key(queuedKeyPrefix+priority))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get queue depth from Redis: %w", err)
 	}
 
-	return tasks, nil
+	depth := make(map[string]int, len(counts))
+	for priority, count := range counts {
+		depth[priority] = int(count.Val())
+	}
+	return depth, nil
 }
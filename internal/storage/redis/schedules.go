@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/service"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	schedulesKey          = "schedules"
+	scheduleRunsKeyPrefix = "schedules:runs:"
+	schedulerLeaderKey    = "schedules:leader"
+)
+
+// acquireLeadershipScript takes the leader key if it is free, or extends it
+// if it is already held by the same instance.
+var acquireLeadershipScript = redis.NewScript(`
+local holder = redis.call('GET', KEYS[1])
+if not holder then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+if holder == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+func (s *RedisStorage) SaveSchedule(ctx context.Context, schedule service.Schedule) error {
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule: %w", err)
+	}
+
+	if err := s.client.HSet(ctx, s.key(schedulesKey), schedule.ID, data).Err(); err != nil {
+		return fmt.Errorf("failed to save schedule in Redis: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisStorage) GetSchedule(ctx context.Context, id string) (*service.Schedule, error) {
+	data, err := s.client.HGet(ctx, s.key(schedulesKey), id).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, domain.ScheduleNotFound
+		}
+		return nil, fmt.Errorf("failed to get schedule from Redis: %w", err)
+	}
+
+	var schedule service.Schedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schedule: %w", err)
+	}
+
+	return &schedule, nil
+}
+
+func (s *RedisStorage) GetSchedules(ctx context.Context) ([]service.Schedule, error) {
+	values, err := s.client.HGetAll(ctx, s.key(schedulesKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules from Redis: %w", err)
+	}
+
+	schedules := make([]service.Schedule, 0, len(values))
+	for _, data := range values {
+		var schedule service.Schedule
+		if err := json.Unmarshal([]byte(data), &schedule); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+	})
+	return schedules, nil
+}
+
+func (s *RedisStorage) DeleteSchedule(ctx context.Context, id string) error {
+	pipe := s.client.TxPipeline()
+	deleted := pipe.HDel(ctx, s.key(schedulesKey), id)
+	pipe.Del(ctx, s.key(scheduleRunsKeyPrefix+id))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete schedule from Redis: %w", err)
+	}
+
+	if deleted.Val() == 0 {
+		return domain.ScheduleNotFound
+	}
+	return nil
+}
+
+func (s *RedisStorage) AddScheduleRun(ctx context.Context, id string, run service.ScheduleRun, limit int) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule run: %w", err)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.LPush(ctx, s.key(scheduleRunsKeyPrefix+id), data)
+	pipe.LTrim(ctx, s.key(scheduleRunsKeyPrefix+id), 0, int64(limit-1))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save schedule run in Redis: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisStorage) GetScheduleRuns(ctx context.Context, id string, limit int) ([]service.ScheduleRun, error) {
+	values, err := s.client.LRange(ctx, s.key(scheduleRunsKeyPrefix+id), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule runs from Redis: %w", err)
+	}
+
+	runs := make([]service.ScheduleRun, 0, len(values))
+	for _, data := range values {
+		var run service.ScheduleRun
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal schedule run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+func (s *RedisStorage) AcquireLeadership(ctx context.Context, instanceID string, ttl time.Duration) (bool, error) {
+	acquired, err := acquireLeadershipScript.Run(ctx, s.client, []string{s.key(schedulerLeaderKey)}, instanceID, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire scheduler leadership: %w", err)
+	}
+	return acquired == 1, nil
+}
@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"context"
+
+	"task-runner-service/internal/tracing"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingHook wraps every Redis command issued by the storage in a client
+// span, so storage calls show up inside request and task traces.
+type tracingHook struct{}
+
+func (tracingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return startSpan(ctx, "redis "+cmd.Name(), 1), nil
+}
+
+func (tracingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endSpan(ctx, cmd.Err())
+	return nil
+}
+
+func (tracingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return startSpan(ctx, "redis pipeline", len(cmds)), nil
+}
+
+func (tracingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && cmdErr != redis.Nil {
+			err = cmdErr
+			break
+		}
+	}
+	endSpan(ctx, err)
+	return nil
+}
+
+func startSpan(ctx context.Context, name string, commands int) context.Context {
+	ctx, _ = tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.Int("db.redis.commands", commands),
+		),
+	)
+	return ctx
+}
+
+func endSpan(ctx context.Context, err error) {
+	if err == redis.Nil {
+		err = nil
+	}
+	tracing.End(trace.SpanFromContext(ctx), err)
+}
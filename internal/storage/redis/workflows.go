@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/service"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const workflowsKey = "workflows"
+
+func (s *RedisStorage) SaveWorkflow(ctx context.Context, workflow service.Workflow) error {
+	data, err := json.Marshal(workflow)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workflow: %w", err)
+	}
+
+	if err := s.client.HSet(ctx, s.key(workflowsKey), workflow.ID, data).Err(); err != nil {
+		return fmt.Errorf("failed to save workflow in Redis: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisStorage) GetWorkflow(ctx context.Context, id string) (*service.Workflow, error) {
+	data, err := s.client.HGet(ctx, s.key(workflowsKey), id).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, domain.WorkflowNotFound
+		}
+		return nil, fmt.Errorf("failed to get workflow from Redis: %w", err)
+	}
+
+	var workflow service.Workflow
+	if err := json.Unmarshal(data, &workflow); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal workflow: %w", err)
+	}
+
+	return &workflow, nil
+}
@@ -0,0 +1,23 @@
+// Package storage defines what a storage backend of the service provides.
+// The backends live in the subpackages and are checked against the same
+// behaviour by the conformance package.
+package storage
+
+import (
+	"context"
+
+	"task-runner-service/internal/scheduler"
+	"task-runner-service/internal/service"
+	"task-runner-service/internal/worker"
+)
+
+// Storage keeps task metadata, schedules, dead letters and the state shared
+// between replicas: worker heartbeats and the scheduler lease.
+type Storage interface {
+	service.Storage
+	worker.HeartbeatStore
+	scheduler.Elector
+
+	Ping(ctx context.Context) error
+	Close() error
+}
@@ -0,0 +1,102 @@
+package mocks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/tracing"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	_, err := tracing.Setup(context.Background(), nil)
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func spanNamed(spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestExecution_ContinuesEnqueueTrace(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	signature := &tasks.Signature{UUID: "task_1", Name: "sleep", RoutingKey: "slow"}
+	_, enqueue := tracing.StartEnqueue(context.Background(), signature.Name, signature)
+	tracing.End(enqueue, nil)
+	assert.NotEmpty(t, signature.Headers["traceparent"])
+
+	enqueuedAt, ok := tracing.EnqueuedAt(signature)
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now(), enqueuedAt, time.Second)
+
+	ctx, execute := tracing.StartExecution(context.Background(), signature)
+	assert.Equal(t, enqueue.SpanContext().TraceID(), trace.SpanContextFromContext(ctx).TraceID())
+	tracing.End(execute, nil)
+
+	spans := recorder.Ended()
+	executeSpan := spanNamed(spans, "execute sleep")
+	waitSpan := spanNamed(spans, "queue.wait")
+	if assert.NotNil(t, executeSpan) && assert.NotNil(t, waitSpan) {
+		assert.Equal(t, enqueue.SpanContext().SpanID(), executeSpan.Parent().SpanID())
+		assert.Equal(t, trace.SpanKindConsumer, executeSpan.SpanKind())
+		assert.Equal(t, executeSpan.SpanContext().SpanID(), waitSpan.Parent().SpanID())
+	}
+}
+
+func TestEnqueuedAt_UsesLaterETA(t *testing.T) {
+	eta := time.Now().Add(time.Hour).UTC()
+	signature := &tasks.Signature{ETA: &eta}
+	tracing.Inject(context.Background(), signature)
+
+	enqueuedAt, ok := tracing.EnqueuedAt(signature)
+	assert.True(t, ok)
+	assert.True(t, enqueuedAt.Equal(eta))
+
+	_, ok = tracing.EnqueuedAt(&tasks.Signature{})
+	assert.False(t, ok)
+}
+
+func TestMiddleware_NamesSpanAfterRoute(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	router := chi.NewRouter()
+	router.Use(tracing.Middleware)
+	router.Get("/api/v1/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	request := httptest.NewRequest("GET", "/api/v1/tasks/task_1", nil)
+	request.Header.Set("traceparent", parent)
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /api/v1/tasks/{id}", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	assert.Equal(t, "Error", spans[0].Status().Code.String())
+}
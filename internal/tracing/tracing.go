@@ -0,0 +1,201 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"task-runner-service/internal/config"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+
+	// EnqueuedAtHeader carries the time a task was published, so the worker
+	// can report how long it waited in the queue.
+	EnqueuedAtHeader = "enqueued_at"
+
+	tracerName         = "task-runner-service"
+	defaultServiceName = "task-runner-service"
+)
+
+// Setup installs the W3C trace context propagator and, unless tracing is
+// disabled, a tracer provider exporting to the configured exporter. The
+// returned function flushes pending spans.
+func Setup(ctx context.Context, cfg *config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	noop := func(context.Context) error { return nil }
+	if cfg == nil || cfg.Exporter == "" || cfg.Exporter == ExporterNone {
+		return noop, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	default:
+		return noop, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return noop, fmt.Errorf("failed to create %s exporter: %w", cfg.Exporter, err)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	ratio := cfg.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// headersCarrier lets the propagator read and write machinery signature
+// headers.
+type headersCarrier tasks.Headers
+
+func (c headersCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+func (c headersCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c headersCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Inject stores the trace context of ctx and the publishing time in the
+// signature headers.
+func Inject(ctx context.Context, signature *tasks.Signature) {
+	if signature.Headers == nil {
+		signature.Headers = tasks.Headers{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, headersCarrier(signature.Headers))
+	signature.Headers[EnqueuedAtHeader] = time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+// Extract returns ctx with the trace context stored in the signature headers.
+func Extract(ctx context.Context, signature *tasks.Signature) context.Context {
+	if signature == nil || signature.Headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, headersCarrier(signature.Headers))
+}
+
+// EnqueuedAt returns when the signature was published or became due, for
+// delayed tasks, whichever is later.
+func EnqueuedAt(signature *tasks.Signature) (time.Time, bool) {
+	if signature == nil || signature.Headers == nil {
+		return time.Time{}, false
+	}
+	value, _ := signature.Headers[EnqueuedAtHeader].(string)
+	enqueuedAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if signature.ETA != nil && signature.ETA.After(enqueuedAt) {
+		enqueuedAt = *signature.ETA
+	}
+	return enqueuedAt, true
+}
+
+// StartEnqueue starts the producer span of a publish and stores its trace
+// context in the signatures, so that workers continue the same trace.
+func StartEnqueue(ctx context.Context, name string, signatures ...*tasks.Signature) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, "enqueue "+name, trace.WithSpanKind(trace.SpanKindProducer))
+	for _, signature := range signatures {
+		Inject(ctx, signature)
+	}
+	if len(signatures) == 1 {
+		span.SetAttributes(
+			attribute.String("task.id", signatures[0].UUID),
+			attribute.String("task.name", signatures[0].Name),
+			attribute.String("task.queue", signatures[0].RoutingKey),
+		)
+	}
+	return ctx, span
+}
+
+// End records err on the span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StartExecution continues the trace stored in the signature with a consumer
+// span around the task run. The time the task spent in the queue is recorded
+// as a finished child span, so that it shows up next to the execution.
+func StartExecution(ctx context.Context, signature *tasks.Signature) (context.Context, trace.Span) {
+	if signature == nil {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	ctx = Extract(ctx, signature)
+	ctx, span := Tracer().Start(ctx, "execute "+signature.Name,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("task.id", signature.UUID),
+			attribute.String("task.name", signature.Name),
+			attribute.String("task.queue", signature.RoutingKey),
+			attribute.Int("task.retries_left", signature.RetryCount),
+		),
+	)
+
+	if enqueuedAt, ok := EnqueuedAt(signature); ok {
+		now := time.Now()
+		if enqueuedAt.After(now) {
+			enqueuedAt = now
+		}
+		_, wait := Tracer().Start(ctx, "queue.wait", trace.WithTimestamp(enqueuedAt))
+		wait.End(trace.WithTimestamp(now))
+	}
+	return ctx, span
+}
@@ -0,0 +1,427 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/registry"
+	"task-runner-service/internal/service"
+	"task-runner-service/internal/tracing"
+	"task-runner-service/pkg/logger"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+var (
+	ErrTaskCancelled   = errors.New("task cancelled")
+	ErrStaleDelivery   = errors.New("task was rescheduled")
+	ErrTaskInterrupted = errors.New("task interrupted by worker shutdown")
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Executor wraps registered task functions so that every run gets its own
+// cancellable context, and revoked tasks or outdated deliveries of
+// rescheduled ones are skipped before they start. Tasks of a queue with a
+// rate limit wait for their turn before they start.
+type Executor struct {
+	storage service.Storage
+
+	mu       sync.Mutex
+	running  map[string]context.CancelFunc
+	limiters map[string]*rate.Limiter
+
+	interruptOnce sync.Once
+	interrupted   chan struct{}
+}
+
+func NewExecutor(storage service.Storage) *Executor {
+	return &Executor{
+		storage:     storage,
+		running:     make(map[string]context.CancelFunc),
+		limiters:    make(map[string]*rate.Limiter),
+		interrupted: make(chan struct{}),
+	}
+}
+
+// Wrap returns the task functions in a form suitable for
+// machinery.Server.RegisterTasks.
+func (e *Executor) Wrap(taskFuncs map[string]interface{}) map[string]interface{} {
+	return e.WrapQueue("", taskFuncs)
+}
+
+// WrapQueue is Wrap for the task functions of the workers consuming queue,
+// which are subject to its rate limit.
+func (e *Executor) WrapQueue(queue string, taskFuncs map[string]interface{}) map[string]interface{} {
+	wrapped := make(map[string]interface{}, len(taskFuncs))
+	for name, fn := range taskFuncs {
+		wrapped[name] = e.wrap(queue, fn)
+	}
+	return wrapped
+}
+
+// SetRateLimit caps the number of tasks of queue started per second, up to
+// one second's worth at once. Zero removes the limit. Tasks already waiting
+// keep the turn they were given.
+func (e *Executor) SetRateLimit(queue string, limit float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if limit <= 0 {
+		delete(e.limiters, queue)
+		return
+	}
+
+	burst := int(math.Ceil(limit))
+	if limiter, ok := e.limiters[queue]; ok {
+		limiter.SetLimit(rate.Limit(limit))
+		limiter.SetBurst(burst)
+		return
+	}
+	e.limiters[queue] = rate.NewLimiter(rate.Limit(limit), burst)
+}
+
+// Watch cancels running tasks as cancellations are published, until ctx is
+// done.
+func (e *Executor) Watch(ctx context.Context) error {
+	ids, err := e.storage.SubscribeCancellations(ctx)
+	if err != nil {
+		return err
+	}
+
+	for id := range ids {
+		if e.Cancel(id) {
+			logger.Info(ctx, "cancelled running task", zap.String("task_id", id))
+		}
+	}
+	return nil
+}
+
+func (e *Executor) Cancel(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cancel, ok := e.running[id]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// Running returns the number of tasks being executed.
+func (e *Executor) Running() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.running)
+}
+
+// Interrupt stops waiting for running tasks and returns how many there were.
+// Each of them is marked INTERRUPTED and handed back to machinery for an
+// immediate redelivery, without using up an attempt. Tasks that ignore
+// their context keep running in the background until the process exits.
+func (e *Executor) Interrupt() int {
+	e.interruptOnce.Do(func() { close(e.interrupted) })
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, cancel := range e.running {
+		cancel()
+	}
+	return len(e.running)
+}
+
+func (e *Executor) wrap(queue string, fn interface{}) interface{} {
+	fnValue := reflect.ValueOf(fn)
+	fnType := fnValue.Type()
+
+	usesContext := fnType.NumIn() > 0 && tasks.IsContextType(fnType.In(0))
+	in := []reflect.Type{contextType}
+	for i := 0; i < fnType.NumIn(); i++ {
+		if i == 0 && usesContext {
+			continue
+		}
+		in = append(in, fnType.In(i))
+	}
+	out := make([]reflect.Type, fnType.NumOut())
+	for i := range out {
+		out[i] = fnType.Out(i)
+	}
+
+	wrappedType := reflect.FuncOf(in, out, fnType.IsVariadic())
+	return reflect.MakeFunc(wrappedType, func(args []reflect.Value) []reflect.Value {
+		ctx := args[0].Interface().(context.Context)
+		signature := tasks.SignatureFromContext(ctx)
+
+		ctx, span := tracing.StartExecution(ctx, signature)
+		ctx = taskContext(ctx, signature)
+		var taskErr error
+		defer func() { tracing.End(span, taskErr) }()
+
+		ctx, done := e.start(ctx, signature)
+		defer done()
+
+		if taskErr = e.checkRunnable(ctx, signature); taskErr != nil {
+			// Revoked and outdated deliveries must not be retried.
+			signature.RetryCount = 0
+			return errorResults(fnType, taskErr)
+		}
+
+		if taskErr = e.wait(ctx, queue); taskErr != nil {
+			if taskErr == ErrTaskInterrupted {
+				taskErr = e.handleInterruption(ctx, signature)
+			} else if signature != nil {
+				signature.RetryCount = 0
+			}
+			return errorResults(fnType, taskErr)
+		}
+
+		callArgs := args[1:]
+		if usesContext {
+			callArgs = append([]reflect.Value{reflect.ValueOf(ctx)}, callArgs...)
+		}
+
+		results, interrupted := e.call(fnValue, callArgs)
+		if interrupted {
+			taskErr = e.handleInterruption(ctx, signature)
+			return errorResults(fnType, taskErr)
+		}
+
+		if ctx.Err() != nil && e.checkRunnable(context.WithoutCancel(ctx), signature) == ErrTaskCancelled {
+			signature.RetryCount = 0
+			taskErr = ErrTaskCancelled
+			return errorResults(fnType, taskErr)
+		}
+		if taskErr = resultError(results); taskErr != nil {
+			taskErr = e.handleFailure(ctx, signature, taskErr)
+			return errorResults(fnType, taskErr)
+		}
+		return results
+	}).Interface()
+}
+
+// call runs the task function and returns early when the executor is
+// interrupted. A panic is re-raised in the caller so machinery still recovers
+// it as a task error.
+func (e *Executor) call(fnValue reflect.Value, args []reflect.Value) ([]reflect.Value, bool) {
+	type outcome struct {
+		results []reflect.Value
+		panic   interface{}
+	}
+
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{panic: r}
+			}
+		}()
+		if fnValue.Type().IsVariadic() {
+			done <- outcome{results: fnValue.CallSlice(args)}
+		} else {
+			done <- outcome{results: fnValue.Call(args)}
+		}
+	}()
+
+	select {
+	case result := <-done:
+		if result.panic != nil {
+			panic(result.panic)
+		}
+		return result.results, false
+	case <-e.interrupted:
+		return nil, true
+	}
+}
+
+// wait blocks until the rate limit of queue lets the task start. It returns
+// ErrTaskInterrupted when the executor is interrupted and ErrTaskCancelled
+// when the task is cancelled meanwhile.
+func (e *Executor) wait(ctx context.Context, queue string) error {
+	e.mu.Lock()
+	limiter := e.limiters[queue]
+	e.mu.Unlock()
+	if limiter == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-e.interrupted:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := limiter.Wait(ctx); err != nil {
+		select {
+		case <-e.interrupted:
+			return ErrTaskInterrupted
+		default:
+			return ErrTaskCancelled
+		}
+	}
+	return nil
+}
+
+func (e *Executor) start(ctx context.Context, signature *tasks.Signature) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	if signature == nil {
+		return ctx, cancel
+	}
+
+	e.mu.Lock()
+	e.running[signature.UUID] = cancel
+	e.mu.Unlock()
+
+	return ctx, func() {
+		e.mu.Lock()
+		delete(e.running, signature.UUID)
+		e.mu.Unlock()
+		cancel()
+	}
+}
+
+func (e *Executor) checkRunnable(ctx context.Context, signature *tasks.Signature) error {
+	if signature == nil {
+		return nil
+	}
+
+	task, err := e.storage.GetTask(ctx, signature.UUID)
+	if err != nil {
+		return nil
+	}
+	if task.Status == service.StateCancelled {
+		return ErrTaskCancelled
+	}
+	if service.IsStaleDelivery(*task, signature) {
+		return ErrStaleDelivery
+	}
+	return nil
+}
+
+// handleFailure records a failed attempt and applies the retry policy stored
+// with the task. A retry is requested from machinery with
+// tasks.ErrRetryTaskLater so that our interval is used instead of its own
+// Fibonacci backoff; RetryCount always holds the number of retries left.
+func (e *Executor) handleFailure(ctx context.Context, signature *tasks.Signature, taskErr error) error {
+	var retriable tasks.Retriable
+	if signature == nil || errors.As(taskErr, &retriable) {
+		return taskErr
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), hookTimeout)
+	defer cancel()
+
+	var (
+		attempt  int
+		retry    bool
+		interval time.Duration
+	)
+	task, err := e.storage.UpdateTask(ctx, signature.UUID, func(task *service.Task) error {
+		attempt = task.Retry.MaxAttempts - signature.RetryCount
+		if attempt < 1 {
+			attempt = len(task.Attempts) + 1
+		}
+
+		now := time.Now()
+		task.Attempts = append(task.Attempts, service.Attempt{
+			Number:     attempt,
+			Error:      taskErr.Error(),
+			ErrorClass: registry.ErrorClass(taskErr),
+			FailedAt:   now,
+		})
+		task.NextRetryAt = nil
+
+		retry = task.Retry.ShouldRetry(taskErr, attempt)
+		interval = 0
+		if retry {
+			interval = task.Retry.NextInterval(attempt)
+			nextRetryAt := now.Add(interval)
+			task.NextRetryAt = &nextRetryAt
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error(ctx, "failed to record attempt", zap.Error(err))
+		return taskErr
+	}
+
+	if !retry {
+		signature.RetryCount = 0
+		return taskErr
+	}
+
+	signature.RetryCount = task.Retry.MaxAttempts - attempt - 1
+	logger.Info(ctx, "task failed, retrying", zap.Int("attempt", attempt), zap.Duration("retry_in", interval), zap.NamedError("task_error", taskErr))
+	return tasks.NewErrRetryTaskLater(taskErr.Error(), interval)
+}
+
+// handleInterruption records that the task was cut short by a shutdown and
+// asks machinery to publish it again right away. RetryCount is left as is so
+// the interrupted run does not count as an attempt.
+func (e *Executor) handleInterruption(ctx context.Context, signature *tasks.Signature) error {
+	if signature == nil {
+		return ErrTaskInterrupted
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), hookTimeout)
+	defer cancel()
+
+	_, err := e.storage.UpdateTask(ctx, signature.UUID, func(task *service.Task) error {
+		task.Status = service.StateInterrupted
+		task.Error = ErrTaskInterrupted.Error()
+		return nil
+	})
+	if err != nil && !errors.Is(err, domain.TaskNotFound) {
+		logger.Error(ctx, "failed to record interruption", zap.Error(err))
+	}
+
+	logger.Info(ctx, "task interrupted by shutdown, requeueing")
+	return tasks.NewErrRetryTaskLater(ErrTaskInterrupted.Error(), 0)
+}
+
+// taskContext returns ctx with the log fields of the task the signature
+// belongs to.
+func taskContext(ctx context.Context, signature *tasks.Signature) context.Context {
+	if signature == nil {
+		return ctx
+	}
+	return logger.WithTask(ctx, signature.UUID, signature.Name, signature.RoutingKey)
+}
+
+func resultError(results []reflect.Value) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	last := results[len(results)-1]
+	if last.IsNil() {
+		return nil
+	}
+	err, _ := last.Interface().(error)
+	return err
+}
+
+func errorResults(fnType reflect.Type, err error) []reflect.Value {
+	results := make([]reflect.Value, fnType.NumOut())
+	for i := 0; i < len(results)-1; i++ {
+		results[i] = reflect.Zero(fnType.Out(i))
+	}
+
+	errValue := reflect.New(errorType).Elem()
+	errValue.Set(reflect.ValueOf(err))
+	results[len(results)-1] = errValue
+	return results
+}
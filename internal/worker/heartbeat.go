@@ -0,0 +1,129 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"task-runner-service/internal/service"
+	"task-runner-service/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	HeartbeatInterval = 5 * time.Second
+	// HeartbeatTimeout is the age after which a worker is considered stale.
+	HeartbeatTimeout = 3 * HeartbeatInterval
+	// heartbeatTTL keeps stale heartbeats around for a while so that health
+	// reports can show them before they disappear.
+	heartbeatTTL = 20 * HeartbeatInterval
+)
+
+type HeartbeatStore interface {
+	SaveHeartbeat(ctx context.Context, heartbeat service.Heartbeat, ttl time.Duration) error
+	GetHeartbeats(ctx context.Context) ([]service.Heartbeat, error)
+}
+
+// Heartbeat periodically publishes that this worker process is alive.
+type Heartbeat struct {
+	store    HeartbeatStore
+	executor *Executor
+	workerID string
+	hostname string
+	queues   []string
+}
+
+func NewHeartbeat(store HeartbeatStore, executor *Executor, queues []string) *Heartbeat {
+	hostname, _ := os.Hostname()
+	return &Heartbeat{
+		store:    store,
+		executor: executor,
+		workerID: fmt.Sprintf("%s-%s", hostname, uuid.New().String()),
+		hostname: hostname,
+		queues:   queues,
+	}
+}
+
+func (h *Heartbeat) WorkerID() string {
+	return h.workerID
+}
+
+// Run publishes heartbeats until ctx is done.
+func (h *Heartbeat) Run(ctx context.Context) {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		h.beat(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Heartbeat) beat(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	heartbeat := service.Heartbeat{
+		WorkerID: h.workerID,
+		Hostname: h.hostname,
+		Queues:   h.queues,
+		Running:  h.executor.Running(),
+		At:       time.Now(),
+	}
+	if err := h.store.SaveHeartbeat(ctx, heartbeat, heartbeatTTL); err != nil {
+		logger.Error(ctx, "failed to publish worker heartbeat", zap.String("worker_id", h.workerID), zap.Error(err))
+	}
+}
+
+// CheckHeartbeats returns a health check of worker heartbeats. With a
+// workerID it requires that worker to be fresh, otherwise any worker.
+func CheckHeartbeats(store HeartbeatStore, workerID string) func(ctx context.Context) (map[string]interface{}, error) {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		heartbeats, err := store.GetHeartbeats(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		fresh, stale := 0, 0
+		var lastSeen time.Time
+		ownFresh := false
+		for _, heartbeat := range heartbeats {
+			age := time.Since(heartbeat.At)
+			if age <= HeartbeatTimeout {
+				fresh++
+				if heartbeat.WorkerID == workerID {
+					ownFresh = true
+				}
+			} else {
+				stale++
+			}
+			if heartbeat.At.After(lastSeen) {
+				lastSeen = heartbeat.At
+			}
+		}
+
+		details := map[string]interface{}{
+			"fresh": fresh,
+			"stale": stale,
+		}
+		if !lastSeen.IsZero() {
+			details["last_seen"] = lastSeen.Format(time.RFC3339)
+			details["age_seconds"] = int(time.Since(lastSeen).Seconds())
+		}
+
+		if workerID != "" && !ownFresh {
+			return details, fmt.Errorf("no heartbeat from this worker in the last %s", HeartbeatTimeout)
+		}
+		if fresh == 0 {
+			return details, fmt.Errorf("no worker heartbeat in the last %s", HeartbeatTimeout)
+		}
+		return details, nil
+	}
+}
@@ -0,0 +1,159 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/metrics"
+	"task-runner-service/internal/service"
+	"task-runner-service/internal/tracing"
+	"task-runner-service/pkg/logger"
+
+	"github.com/RichardKnop/machinery/v1"
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"go.uber.org/zap"
+)
+
+const hookTimeout = 5 * time.Second
+
+// Lifecycle mirrors the state machinery keeps in its result backend into
+// service.Storage, so task listings reflect what the workers are doing.
+type Lifecycle struct {
+	storage service.Storage
+	backend service.MachineryBackend
+}
+
+func NewLifecycle(storage service.Storage, backend service.MachineryBackend) *Lifecycle {
+	return &Lifecycle{
+		storage: storage,
+		backend: backend,
+	}
+}
+
+func (l *Lifecycle) Attach(worker *machinery.Worker) {
+	worker.SetPreTaskHandler(l.PreTask)
+	worker.SetPostTaskHandler(l.PostTask)
+	worker.SetErrorHandler(l.HandleError)
+}
+
+func (l *Lifecycle) PreTask(signature *tasks.Signature) {
+	ctx, cancel := context.WithTimeout(taskContext(tracing.Extract(context.Background(), signature), signature), hookTimeout)
+	defer cancel()
+
+	_, err := l.updateTask(ctx, signature, func(task *service.Task) error {
+		if task.Status == service.StateCancelled || service.IsStaleDelivery(*task, signature) {
+			return errTaskUnchanged
+		}
+
+		now := time.Now()
+		task.Status = tasks.StateStarted
+		task.StartedAt = &now
+		task.FinishedAt = nil
+		task.Error = ""
+		task.Attempt = len(task.Attempts) + 1
+		task.NextRetryAt = nil
+		return nil
+	})
+	if err != nil && !errors.Is(err, errTaskUnchanged) {
+		logger.Error(ctx, "failed to store STARTED state", zap.Error(err))
+	}
+}
+
+func (l *Lifecycle) PostTask(signature *tasks.Signature) {
+	ctx, cancel := context.WithTimeout(taskContext(tracing.Extract(context.Background(), signature), signature), hookTimeout)
+	defer cancel()
+
+	state, err := l.backend.GetState(signature.UUID)
+	if err != nil {
+		logger.Error(ctx, "failed to read backend state", zap.Error(err))
+		return
+	}
+
+	task, err := l.updateTask(ctx, signature, func(task *service.Task) error {
+		// Interrupted tasks were already requeued; the next delivery reports
+		// their progress.
+		if task.Status == service.StateCancelled || task.Status == service.StateInterrupted ||
+			service.IsStaleDelivery(*task, signature) {
+			return errTaskUnchanged
+		}
+
+		task.Status = state.State
+		// machinery republishes a retried task before this hook runs, which
+		// moves its backend state from RETRY back to PENDING.
+		if task.Status == tasks.StatePending && task.NextRetryAt != nil {
+			task.Status = tasks.StateRetry
+		}
+		task.Error = state.Error
+		if len(state.Results) > 0 && state.Results[0] != nil {
+			task.Result = state.Results[0].Value
+		}
+		if service.IsFinalState(task.Status) {
+			now := time.Now()
+			task.FinishedAt = &now
+		}
+		return nil
+	})
+	if errors.Is(err, errTaskUnchanged) {
+		return
+	}
+	if err != nil {
+		logger.Error(ctx, "failed to store task state", zap.String("status", state.State), zap.Error(err))
+		return
+	}
+
+	observe(*task)
+
+	// Retries are exhausted once machinery reports FAILURE; RETRY is reported
+	// for every failed attempt that will run again.
+	if task.Status == tasks.StateFailure {
+		if err := l.storage.SaveDeadLetter(ctx, service.NewDeadLetter(*task, signature)); err != nil {
+			logger.Error(ctx, "failed to move task to the dead-letter queue", zap.Error(err))
+		}
+	}
+}
+
+func observe(task service.Task) {
+	var duration time.Duration
+	if task.StartedAt != nil {
+		duration = time.Since(*task.StartedAt)
+	}
+
+	switch task.Status {
+	case tasks.StateSuccess:
+		metrics.TaskSucceeded(task.Name, task.Queue, duration)
+	case tasks.StateFailure:
+		metrics.TaskFailed(task.Name, task.Queue, duration)
+	case tasks.StateRetry:
+		metrics.TaskRetried(task.Name, task.Queue, duration)
+	}
+}
+
+func (l *Lifecycle) HandleError(err error) {
+	logger.Error(context.Background(), "task processing error", zap.Error(err))
+}
+
+// errTaskUnchanged tells updateTask to leave the task as it is.
+var errTaskUnchanged = errors.New("task left unchanged")
+
+// updateTask applies update to the stored task, or to a record built from the
+// signature for tasks that were not submitted through RunnerService (e.g.
+// callbacks).
+func (l *Lifecycle) updateTask(ctx context.Context, signature *tasks.Signature, update func(task *service.Task) error) (*service.Task, error) {
+	task, err := l.storage.UpdateTask(ctx, signature.UUID, update)
+	if !errors.Is(err, domain.TaskNotFound) {
+		return task, err
+	}
+
+	task = &service.Task{
+		ID:        signature.UUID,
+		Name:      signature.Name,
+		Args:      signature.Args,
+		CreatedAt: time.Now(),
+	}
+	if err := update(task); err != nil {
+		return nil, err
+	}
+	return task, l.storage.SaveTask(ctx, *task)
+}
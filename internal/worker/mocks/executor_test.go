@@ -0,0 +1,242 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/registry"
+	"task-runner-service/internal/service"
+	"task-runner-service/internal/worker"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/stretchr/testify/assert"
+)
+
+func blockingTask(ctx context.Context, label string) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestExecutor_SkipsCancelledTask(t *testing.T) {
+	st := newFakeStorage()
+	assert.NoError(t, st.SaveTask(context.Background(), service.Task{ID: "tid", Status: service.StateCancelled}))
+
+	called := false
+	executor := worker.NewExecutor(st)
+	wrapped := executor.Wrap(map[string]interface{}{
+		"echo": func(message string) (string, error) {
+			called = true
+			return message, nil
+		},
+	})
+
+	sig := &tasks.Signature{UUID: "tid", Name: "echo", RetryCount: 3, Args: []tasks.Arg{{Type: "string", Value: "hi"}}}
+	task, err := tasks.NewWithSignature(wrapped["echo"], sig)
+	assert.NoError(t, err)
+
+	_, err = task.Call()
+	assert.ErrorIs(t, err, worker.ErrTaskCancelled)
+	assert.False(t, called)
+	assert.Equal(t, 0, sig.RetryCount)
+}
+
+func TestExecutor_CancelsRunningTask(t *testing.T) {
+	st := newFakeStorage()
+	assert.NoError(t, st.SaveTask(context.Background(), service.Task{ID: "tid", Status: tasks.StateStarted}))
+
+	executor := worker.NewExecutor(st)
+	wrapped := executor.Wrap(map[string]interface{}{"block": blockingTask})
+
+	sig := &tasks.Signature{UUID: "tid", Name: "block", Args: []tasks.Arg{{Type: "string", Value: "x"}}}
+	task, err := tasks.NewWithSignature(wrapped["block"], sig)
+	assert.NoError(t, err)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := task.Call()
+		errs <- err
+	}()
+
+	assert.Eventually(t, func() bool {
+		task, _ := st.GetTask(context.Background(), "tid")
+		task.Status = service.StateCancelled
+		_ = st.SaveTask(context.Background(), *task)
+		return executor.Cancel("tid")
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, worker.ErrTaskCancelled)
+	case <-time.After(time.Second):
+		t.Fatal("task was not cancelled")
+	}
+}
+
+func TestExecutor_RetryPolicy(t *testing.T) {
+	timeoutErr := registry.WithClass(registry.ErrorClassTimeout, errors.New("upstream timed out"))
+	policy := registry.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     registry.BackoffExponential,
+		Interval:    time.Second,
+		RetryOn:     []string{registry.ErrorClassTimeout},
+	}
+
+	cases := []struct {
+		name           string
+		taskErr        error
+		retryCount     int
+		wantRetryIn    time.Duration
+		wantRetryCount int
+		wantAttempt    int
+	}{
+		{name: "FirstAttempt", taskErr: timeoutErr, retryCount: 2, wantRetryIn: time.Second, wantRetryCount: 1, wantAttempt: 1},
+		{name: "SecondAttempt", taskErr: timeoutErr, retryCount: 1, wantRetryIn: 2 * time.Second, wantRetryCount: 0, wantAttempt: 2},
+		{name: "Exhausted", taskErr: timeoutErr, retryCount: 0, wantAttempt: 3},
+		{name: "NotRetryable", taskErr: errors.New("bad input"), retryCount: 2, wantAttempt: 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := newFakeStorage()
+			assert.NoError(t, st.SaveTask(context.Background(), service.Task{ID: "tid", Status: tasks.StateStarted, Retry: policy}))
+
+			executor := worker.NewExecutor(st)
+			wrapped := executor.Wrap(map[string]interface{}{
+				"fail": func() (string, error) { return "", c.taskErr },
+			})
+
+			sig := &tasks.Signature{UUID: "tid", Name: "fail", RetryCount: c.retryCount}
+			task, err := tasks.NewWithSignature(wrapped["fail"], sig)
+			assert.NoError(t, err)
+
+			_, err = task.Call()
+			retryLater, retried := err.(tasks.ErrRetryTaskLater)
+			if c.wantRetryIn > 0 {
+				assert.True(t, retried)
+				assert.Equal(t, c.wantRetryIn, retryLater.RetryIn())
+			} else {
+				assert.False(t, retried)
+				assert.Equal(t, c.taskErr, err)
+			}
+			assert.Equal(t, c.wantRetryCount, sig.RetryCount)
+
+			stored, _ := st.GetTask(context.Background(), "tid")
+			assert.Len(t, stored.Attempts, 1)
+			assert.Equal(t, c.wantAttempt, stored.Attempts[0].Number)
+			assert.Equal(t, c.taskErr.Error(), stored.Attempts[0].Error)
+			assert.Equal(t, c.wantRetryIn > 0, stored.NextRetryAt != nil)
+		})
+	}
+}
+
+func TestExecutor_InterruptRequeuesRunningTask(t *testing.T) {
+	st := newFakeStorage()
+	assert.NoError(t, st.SaveTask(context.Background(), service.Task{ID: "tid", Status: tasks.StateStarted}))
+
+	release := make(chan struct{})
+	defer close(release)
+
+	executor := worker.NewExecutor(st)
+	wrapped := executor.Wrap(map[string]interface{}{
+		// Ignores its context, so only the interruption can end the call.
+		"stuck": func() (string, error) {
+			<-release
+			return "", nil
+		},
+	})
+
+	sig := &tasks.Signature{UUID: "tid", Name: "stuck", RetryCount: 2}
+	task, err := tasks.NewWithSignature(wrapped["stuck"], sig)
+	assert.NoError(t, err)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := task.Call()
+		errs <- err
+	}()
+
+	assert.Eventually(t, func() bool { return executor.Running() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, executor.Interrupt())
+
+	select {
+	case err := <-errs:
+		retryLater, ok := err.(tasks.ErrRetryTaskLater)
+		if assert.True(t, ok) {
+			assert.Equal(t, time.Duration(0), retryLater.RetryIn())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("task was not interrupted")
+	}
+	assert.Equal(t, 2, sig.RetryCount)
+
+	stored, _ := st.GetTask(context.Background(), "tid")
+	assert.Equal(t, service.StateInterrupted, stored.Status)
+	assert.Empty(t, stored.Attempts)
+	assert.Equal(t, 0, executor.Running())
+}
+
+func TestExecutor_RateLimit(t *testing.T) {
+	st := newFakeStorage()
+	executor := worker.NewExecutor(st)
+	executor.SetRateLimit("limited", 10)
+	echo := map[string]interface{}{
+		"echo": func(message string) (string, error) { return message, nil },
+	}
+	limited := executor.WrapQueue("limited", echo)
+	unlimited := executor.WrapQueue("other", echo)
+
+	run := func(wrapped map[string]interface{}, n int) time.Duration {
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			task, err := tasks.NewWithSignature(wrapped["echo"], &tasks.Signature{Name: "echo", Args: []tasks.Arg{{Type: "string", Value: "hi"}}})
+			assert.NoError(t, err)
+			_, err = task.Call()
+			assert.NoError(t, err)
+		}
+		return time.Since(start)
+	}
+
+	// The burst of one second's worth starts at once, the rest at 10/s.
+	assert.Less(t, run(unlimited, 20), 100*time.Millisecond)
+	assert.GreaterOrEqual(t, run(limited, 15), 400*time.Millisecond)
+
+	executor.SetRateLimit("limited", 0)
+	assert.Less(t, run(limited, 20), 100*time.Millisecond)
+}
+
+func TestExecutor_InterruptsRateLimitWait(t *testing.T) {
+	st := newFakeStorage()
+	assert.NoError(t, st.SaveTask(context.Background(), service.Task{ID: "tid", Status: tasks.StateReceived}))
+
+	executor := worker.NewExecutor(st)
+	executor.SetRateLimit("limited", 0.01)
+	wrapped := executor.WrapQueue("limited", map[string]interface{}{
+		"echo": func(message string) (string, error) { return message, nil },
+	})
+
+	call := func(id string) error {
+		task, err := tasks.NewWithSignature(wrapped["echo"], &tasks.Signature{UUID: id, Name: "echo", Args: []tasks.Arg{{Type: "string", Value: "hi"}}})
+		assert.NoError(t, err)
+		_, err = task.Call()
+		return err
+	}
+	// The first task uses up the burst.
+	assert.NoError(t, call("first"))
+
+	errs := make(chan error, 1)
+	go func() { errs <- call("tid") }()
+	assert.Eventually(t, func() bool { return executor.Running() == 1 }, time.Second, 10*time.Millisecond)
+	executor.Interrupt()
+
+	select {
+	case err := <-errs:
+		var retryErr tasks.ErrRetryTaskLater
+		assert.ErrorAs(t, err, &retryErr)
+		task, _ := st.GetTask(context.Background(), "tid")
+		assert.Equal(t, service.StateInterrupted, task.Status)
+	case <-time.After(time.Second):
+		t.Fatal("waiting task was not interrupted")
+	}
+}
@@ -0,0 +1,58 @@
+package mocks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/service"
+	"task-runner-service/internal/worker"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeHeartbeatStore struct {
+	heartbeats []service.Heartbeat
+}
+
+func (s *fakeHeartbeatStore) SaveHeartbeat(ctx context.Context, heartbeat service.Heartbeat, ttl time.Duration) error {
+	s.heartbeats = append(s.heartbeats, heartbeat)
+	return nil
+}
+
+func (s *fakeHeartbeatStore) GetHeartbeats(ctx context.Context) ([]service.Heartbeat, error) {
+	return s.heartbeats, nil
+}
+
+func TestCheckHeartbeats(t *testing.T) {
+	now := time.Now()
+	fresh := service.Heartbeat{WorkerID: "fresh", At: now}
+	stale := service.Heartbeat{WorkerID: "stale", At: now.Add(-time.Minute)}
+
+	cases := []struct {
+		name       string
+		heartbeats []service.Heartbeat
+		workerID   string
+		wantErr    bool
+		wantFresh  int
+		wantStale  int
+	}{
+		{"AnyFresh", []service.Heartbeat{fresh, stale}, "", false, 1, 1},
+		{"NoneFresh", []service.Heartbeat{stale}, "", true, 0, 1},
+		{"NoWorkers", nil, "", true, 0, 0},
+		{"OwnFresh", []service.Heartbeat{fresh, stale}, "fresh", false, 1, 1},
+		{"OwnStale", []service.Heartbeat{fresh, stale}, "stale", true, 1, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			check := worker.CheckHeartbeats(&fakeHeartbeatStore{heartbeats: c.heartbeats}, c.workerID)
+
+			details, err := check(context.Background())
+
+			assert.Equal(t, c.wantErr, err != nil)
+			assert.Equal(t, c.wantFresh, details["fresh"])
+			assert.Equal(t, c.wantStale, details["stale"])
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/domain"
+	"task-runner-service/internal/service"
+	"task-runner-service/internal/worker"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStorage struct {
+	service.Storage
+
+	mu          sync.Mutex
+	tasks       map[string]service.Task
+	deadLetters map[string]service.DeadLetter
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{
+		tasks:       make(map[string]service.Task),
+		deadLetters: make(map[string]service.DeadLetter),
+	}
+}
+
+func (s *fakeStorage) SaveTask(ctx context.Context, task service.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[task.ID] = task
+	return nil
+}
+
+func (s *fakeStorage) GetTask(ctx context.Context, id string) (*service.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, domain.TaskNotFound
+	}
+	return &task, nil
+}
+
+func (s *fakeStorage) UpdateTask(ctx context.Context, id string, update func(task *service.Task) error) (*service.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, domain.TaskNotFound
+	}
+	if err := update(&task); err != nil {
+		return nil, err
+	}
+	s.tasks[id] = task
+	return &task, nil
+}
+
+func (s *fakeStorage) SaveDeadLetter(ctx context.Context, letter service.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters[letter.ID] = letter
+	return nil
+}
+
+type fakeBackend struct {
+	states map[string]*tasks.TaskState
+}
+
+func (b *fakeBackend) GetState(taskUUID string) (*tasks.TaskState, error) {
+	state, ok := b.states[taskUUID]
+	if !ok {
+		return nil, errors.New("no state")
+	}
+	return state, nil
+}
+
+func TestLifecycle(t *testing.T) {
+	cases := []struct {
+		name       string
+		state      *tasks.TaskState
+		wantStatus string
+		wantResult interface{}
+		wantError  string
+		wantFinish bool
+	}{
+		{
+			name:       "Success",
+			state:      &tasks.TaskState{State: tasks.StateSuccess, Results: []*tasks.TaskResult{{Type: "int64", Value: int64(3)}}},
+			wantStatus: tasks.StateSuccess,
+			wantResult: int64(3),
+			wantFinish: true,
+		},
+		{
+			name:       "Failure",
+			state:      &tasks.TaskState{State: tasks.StateFailure, Error: "boom"},
+			wantStatus: tasks.StateFailure,
+			wantError:  "boom",
+			wantFinish: true,
+		},
+		{
+			name:       "Retry",
+			state:      &tasks.TaskState{State: tasks.StateRetry, Error: "try again"},
+			wantStatus: tasks.StateRetry,
+			wantError:  "try again",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := newFakeStorage()
+			be := &fakeBackend{states: map[string]*tasks.TaskState{"tid": c.state}}
+			lifecycle := worker.NewLifecycle(st, be)
+
+			assert.NoError(t, st.SaveTask(context.Background(), service.Task{ID: "tid", Name: "sum", Status: tasks.StatePending}))
+			sig := &tasks.Signature{UUID: "tid", Name: "sum"}
+
+			lifecycle.PreTask(sig)
+			started, err := st.GetTask(context.Background(), "tid")
+			assert.NoError(t, err)
+			assert.Equal(t, tasks.StateStarted, started.Status)
+			assert.NotNil(t, started.StartedAt)
+
+			lifecycle.PostTask(sig)
+			done, err := st.GetTask(context.Background(), "tid")
+			assert.NoError(t, err)
+			assert.Equal(t, "sum", done.Name)
+			assert.Equal(t, c.wantStatus, done.Status)
+			assert.Equal(t, c.wantResult, done.Result)
+			assert.Equal(t, c.wantError, done.Error)
+			assert.Equal(t, c.wantFinish, done.FinishedAt != nil)
+
+			letter, deadLettered := st.deadLetters["tid"]
+			assert.Equal(t, c.wantStatus == tasks.StateFailure, deadLettered)
+			if deadLettered {
+				assert.Equal(t, "sum", letter.Name)
+				assert.Equal(t, c.wantError, letter.Error)
+				assert.Equal(t, "tid", letter.Signature.UUID)
+			}
+		})
+	}
+}
+
+func TestLifecycle_UnknownTask(t *testing.T) {
+	st := newFakeStorage()
+	lifecycle := worker.NewLifecycle(st, &fakeBackend{})
+
+	lifecycle.PreTask(&tasks.Signature{UUID: "callback", Name: "echo"})
+
+	task, err := st.GetTask(context.Background(), "callback")
+	assert.NoError(t, err)
+	assert.Equal(t, "echo", task.Name)
+	assert.Equal(t, tasks.StateStarted, task.Status)
+}
+
+func TestLifecycle_KeepsCancelled(t *testing.T) {
+	st := newFakeStorage()
+	be := &fakeBackend{states: map[string]*tasks.TaskState{"tid": {State: tasks.StateFailure, Error: "task cancelled"}}}
+	lifecycle := worker.NewLifecycle(st, be)
+
+	assert.NoError(t, st.SaveTask(context.Background(), service.Task{ID: "tid", Status: service.StateCancelled}))
+	sig := &tasks.Signature{UUID: "tid", Name: "sum"}
+
+	lifecycle.PreTask(sig)
+	lifecycle.PostTask(sig)
+
+	task, err := st.GetTask(context.Background(), "tid")
+	assert.NoError(t, err)
+	assert.Equal(t, service.StateCancelled, task.Status)
+	assert.Empty(t, st.deadLetters)
+}
+
+func TestLifecycle_RepublishedRetry(t *testing.T) {
+	st := newFakeStorage()
+	be := &fakeBackend{states: map[string]*tasks.TaskState{"tid": {State: tasks.StatePending}}}
+	lifecycle := worker.NewLifecycle(st, be)
+
+	nextRetryAt := time.Now().Add(time.Second)
+	assert.NoError(t, st.SaveTask(context.Background(), service.Task{ID: "tid", Name: "sum", Status: tasks.StateStarted, NextRetryAt: &nextRetryAt}))
+
+	lifecycle.PostTask(&tasks.Signature{UUID: "tid", Name: "sum"})
+
+	task, err := st.GetTask(context.Background(), "tid")
+	assert.NoError(t, err)
+	assert.Equal(t, tasks.StateRetry, task.Status)
+	assert.Nil(t, task.FinishedAt)
+}
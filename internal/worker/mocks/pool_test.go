@@ -0,0 +1,160 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"task-runner-service/internal/broker/eager"
+	"task-runner-service/internal/worker"
+
+	"github.com/RichardKnop/machinery/v1"
+	"github.com/RichardKnop/machinery/v1/brokers/iface"
+	machineryConfig "github.com/RichardKnop/machinery/v1/config"
+	eagerlock "github.com/RichardKnop/machinery/v1/locks/eager"
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// poolBroker is an in-process broker with a task that runs until release is
+// closed, reporting on started when it begins. Every worker gets a server of
+// its own, as the workers of the runner do.
+type poolBroker struct {
+	hub     *eager.Hub
+	client  *machinery.Server
+	started chan struct{}
+	release chan struct{}
+}
+
+func newPoolBroker(t *testing.T) *poolBroker {
+	b := &poolBroker{hub: eager.NewHub(), started: make(chan struct{}, 10), release: make(chan struct{})}
+	b.client = b.server(t)
+	return b
+}
+
+func (b *poolBroker) server(t *testing.T) *machinery.Server {
+	cnf := &machineryConfig.Config{DefaultQueue: "pool", NoUnixSignals: true}
+	server := machinery.NewServerWithBrokerBackendLock(cnf, b.hub.Broker(cnf), b.hub.Backend(), eagerlock.New())
+	require.NoError(t, server.RegisterTask("block", func() error {
+		b.started <- struct{}{}
+		<-b.release
+		return nil
+	}))
+	return server
+}
+
+func (b *poolBroker) worker(t *testing.T, tag string) *machinery.Worker {
+	return b.server(t).NewWorker(tag, 1)
+}
+
+func (b *poolBroker) run(t *testing.T) {
+	_, err := b.client.SendTask(&tasks.Signature{Name: "block"})
+	require.NoError(t, err)
+	select {
+	case <-b.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not start")
+	}
+}
+
+// returned runs fn in the background and returns a channel receiving its
+// result.
+func returned(fn func() error) <-chan error {
+	result := make(chan error, 1)
+	go func() { result <- fn() }()
+	return result
+}
+
+func TestPool_StopWaitsForRunningTasks(t *testing.T) {
+	broker := newPoolBroker(t)
+	pool := worker.NewPool()
+	pool.Launch("pool", broker.worker(t, "pool_worker"))
+	broker.run(t)
+
+	stopped := returned(func() error { return pool.Stop(context.Background()) })
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned while a task was running: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(broker.release)
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not return once the task finished")
+	}
+	assert.NoError(t, pool.Wait())
+}
+
+func TestPool_StopDeadline(t *testing.T) {
+	broker := newPoolBroker(t)
+	pool := worker.NewPool()
+	pool.Launch("pool", broker.worker(t, "pool_worker"))
+	broker.run(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Stop(ctx), context.DeadlineExceeded)
+
+	// Stopping again keeps waiting for the task.
+	close(broker.release)
+	assert.NoError(t, pool.Stop(context.Background()))
+	assert.ErrorIs(t, pool.Replace("pool"), worker.ErrPoolStopped)
+}
+
+func TestPool_ReplaceKeepsWaiting(t *testing.T) {
+	broker := newPoolBroker(t)
+	pool := worker.NewPool()
+	pool.Launch("pool", broker.worker(t, "pool_worker_1"))
+	waited := returned(pool.Wait)
+
+	require.NoError(t, pool.Replace("pool", broker.worker(t, "pool_worker_2")))
+	assert.Equal(t, 1, pool.Len())
+	select {
+	case err := <-waited:
+		t.Fatalf("Wait returned after a replacement: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// The replacement consumes the queue.
+	close(broker.release)
+	broker.run(t)
+
+	assert.NoError(t, pool.Stop(context.Background()))
+	select {
+	case err := <-waited:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return once the pool stopped")
+	}
+}
+
+// failingBroker fails to consume for good, as a broker losing its connection
+// without retrying does.
+type failingBroker struct {
+	iface.Broker
+}
+
+func (failingBroker) StartConsuming(string, int, iface.TaskProcessor) (bool, error) {
+	return false, errors.New("connection lost")
+}
+
+func TestPool_WorkerError(t *testing.T) {
+	hub := eager.NewHub()
+	cnf := &machineryConfig.Config{DefaultQueue: "pool", NoUnixSignals: true}
+	server := machinery.NewServerWithBrokerBackendLock(cnf, failingBroker{hub.Broker(cnf)}, hub.Backend(), eagerlock.New())
+
+	pool := worker.NewPool()
+	pool.Launch("pool", server.NewWorker("pool_worker", 1))
+
+	select {
+	case err := <-returned(pool.Wait):
+		assert.EqualError(t, err, "connection lost")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return the worker error")
+	}
+}
@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"testing"
+
+	"task-runner-service/internal/worker"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitConcurrency(t *testing.T) {
+	weights := map[string]int{"high": 6, "normal": 3, "low": 1}
+
+	cases := []struct {
+		name    string
+		total   int
+		weights map[string]int
+		want    map[string]int
+	}{
+		{"Weighted", 10, weights, map[string]int{"high": 6, "normal": 3, "low": 1}},
+		{"Leftovers", 20, weights, map[string]int{"high": 12, "normal": 6, "low": 2}},
+		{"KeepsEveryLevel", 2, weights, map[string]int{"high": 1, "normal": 1, "low": 1}},
+		{"SkipsZeroWeight", 4, map[string]int{"high": 1, "low": 0}, map[string]int{"high": 4}},
+		{"Empty", 10, nil, map[string]int{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, worker.SplitConcurrency(c.total, c.weights))
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/RichardKnop/machinery/v1"
+)
+
+var ErrPoolStopped = errors.New("worker pool is stopped")
+
+// Pool runs machinery workers and stops them together. Workers must be
+// created from servers with NoUnixSignals set, otherwise machinery handles
+// SIGTERM on its own and the pool cannot wait for them. Workers are launched
+// in named groups, typically one per queue, that can be replaced while the
+// pool runs.
+type Pool struct {
+	mu       sync.Mutex
+	groups   map[string][]*machinery.Worker
+	stopping bool
+	wg       sync.WaitGroup
+	failed   chan error
+	quitOnce sync.Once
+}
+
+func NewPool() *Pool {
+	return &Pool{groups: make(map[string][]*machinery.Worker), failed: make(chan error, 1)}
+}
+
+// Launch starts workers as part of group.
+func (p *Pool) Launch(group string, workers ...*machinery.Worker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.groups[group] = append(p.groups[group], workers...)
+	p.launch(workers)
+}
+
+// Replace starts workers in place of the current ones of group. These stop
+// fetching messages and finish the tasks they are running in the background,
+// so that no task is lost.
+func (p *Pool) Replace(group string, workers ...*machinery.Worker) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopping {
+		return ErrPoolStopped
+	}
+
+	// The new workers are counted before the old ones stop, so Wait does not
+	// take the replacement for a stop.
+	p.launch(workers)
+	for _, worker := range p.groups[group] {
+		go worker.Quit()
+	}
+	p.groups[group] = workers
+	return nil
+}
+
+func (p *Pool) launch(workers []*machinery.Worker) {
+	for _, worker := range workers {
+		errorsChan := make(chan error, 1)
+		p.wg.Add(1)
+		worker.LaunchAsync(errorsChan)
+		go func() {
+			defer p.wg.Done()
+			if err := <-errorsChan; err != nil {
+				select {
+				case p.failed <- err:
+				default:
+				}
+			}
+		}()
+	}
+}
+
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, workers := range p.groups {
+		n += len(workers)
+	}
+	return n
+}
+
+// Wait blocks until a worker fails or every worker has stopped.
+func (p *Pool) Wait() error {
+	select {
+	case err := <-p.failed:
+		return err
+	case <-p.stopped():
+		return nil
+	}
+}
+
+// Stop tells every worker to stop fetching messages and waits for the tasks
+// they are running to finish, or for ctx to be done. It can be called again
+// to keep waiting after ctx expired.
+func (p *Pool) Stop(ctx context.Context) error {
+	p.quitOnce.Do(func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.stopping = true
+		for _, workers := range p.groups {
+			for _, worker := range workers {
+				go worker.Quit()
+			}
+		}
+	})
+
+	select {
+	case <-p.stopped():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) stopped() <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	return done
+}
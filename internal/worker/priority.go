@@ -0,0 +1,47 @@
+package worker
+
+import "sort"
+
+// SplitConcurrency divides total worker slots between priority levels in
+// proportion to their weights, so higher levels are drained faster while
+// lower ones still make progress. Every level with a positive weight gets at
+// least one slot; levels without weight get none.
+func SplitConcurrency(total int, weights map[string]int) map[string]int {
+	levels := make([]string, 0, len(weights))
+	sum := 0
+	for level, weight := range weights {
+		if weight > 0 {
+			levels = append(levels, level)
+			sum += weight
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if weights[levels[i]] != weights[levels[j]] {
+			return weights[levels[i]] > weights[levels[j]]
+		}
+		return levels[i] < levels[j]
+	})
+
+	split := make(map[string]int, len(levels))
+	if len(levels) == 0 {
+		return split
+	}
+	if total < len(levels) {
+		total = len(levels)
+	}
+
+	// Every level is guaranteed one slot; the rest are shared by weight and
+	// the leftovers of the integer division go to the heaviest levels.
+	remaining := total - len(levels)
+	assigned := 0
+	for _, level := range levels {
+		share := remaining * weights[level] / sum
+		split[level] = 1 + share
+		assigned += share
+	}
+	for i := 0; assigned < remaining; i++ {
+		split[levels[i%len(levels)]]++
+		assigned++
+	}
+	return split
+}
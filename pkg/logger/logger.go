@@ -1,36 +1,154 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"os"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-var logger *zap.Logger
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
 
-func init() {
-	_logger, err := zap.NewProduction(
-		zap.AddStacktrace(zap.PanicLevel),
+var (
+	level  = zap.NewAtomicLevelAt(zap.InfoLevel)
+	logger = newLogger(FormatJSON)
+)
+
+type (
+	fieldsKey    struct{}
+	requestIDKey struct{}
+)
+
+func newLogger(format string) *zap.Logger {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	var encoder zapcore.Encoder
+	if format == FormatConsole {
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	} else {
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.Lock(os.Stderr), level)
+	return zap.New(core,
+		zap.AddCaller(),
 		zap.AddCallerSkip(1),
+		zap.AddStacktrace(zap.PanicLevel),
 	)
+}
+
+// Setup replaces the logger with one writing in the given format, json or
+// console, at the given level. Empty values keep the defaults.
+func Setup(logLevel, format string) error {
+	switch format {
+	case "", FormatJSON, FormatConsole:
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	if err := SetLevel(logLevel); err != nil {
+		return err
+	}
+	logger = newLogger(format)
+	return nil
+}
+
+// SetLevel changes the level of the logger in place; it is safe to call
+// while other goroutines are logging.
+func SetLevel(logLevel string) error {
+	if logLevel == "" {
+		return nil
+	}
+	parsed, err := zapcore.ParseLevel(logLevel)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("unknown log level %q", logLevel)
 	}
-	logger = _logger
+	level.SetLevel(parsed)
+	return nil
 }
 
-func Errorf(msg string, args ...any) {
-	logger.Error(fmt.Sprintf(msg, args...))
+// WithFields returns ctx carrying fields, which are added to every entry
+// logged with it.
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	existing, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	merged := make([]zap.Field, 0, len(existing)+len(fields))
+	merged = append(merged, existing...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, fieldsKey{}, merged)
 }
 
-func Fatalf(msg string, args ...any) {
-	logger.Fatal(fmt.Sprintf(msg, args...))
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	return WithFields(ctx, zap.String("request_id", id))
 }
 
-func Info(msg string) {
-	logger.Info(msg)
+// RequestID returns the ID stored by WithRequestID, or an empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
 }
 
-func Infof(msg string, args ...any) {
-	logger.Info(fmt.Sprintf(msg, args...))
+func WithTask(ctx context.Context, id, name, queue string) context.Context {
+	fields := []zap.Field{zap.String("task_id", id), zap.String("task_name", name)}
+	if queue != "" {
+		fields = append(fields, zap.String("queue", queue))
+	}
+	return WithFields(ctx, fields...)
+}
+
+// For returns the logger with the fields stored in ctx and the ID of the
+// trace ctx belongs to, if any.
+func For(ctx context.Context) *zap.Logger {
+	if ctx == nil {
+		return logger
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = append(fields[:len(fields):len(fields)],
+			zap.String("trace_id", spanContext.TraceID().String()),
+			zap.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	if len(fields) == 0 {
+		return logger
+	}
+	return logger.With(fields...)
+}
+
+func Debug(ctx context.Context, msg string, fields ...zap.Field) {
+	For(ctx).Debug(msg, fields...)
+}
+
+func Info(ctx context.Context, msg string, fields ...zap.Field) {
+	For(ctx).Info(msg, fields...)
+}
+
+func Warn(ctx context.Context, msg string, fields ...zap.Field) {
+	For(ctx).Warn(msg, fields...)
+}
+
+func Error(ctx context.Context, msg string, fields ...zap.Field) {
+	For(ctx).Error(msg, fields...)
+}
+
+func Fatal(ctx context.Context, msg string, fields ...zap.Field) {
+	For(ctx).Fatal(msg, fields...)
+}
+
+// StdLog returns a standard library logger writing entries at lvl, for
+// libraries such as machinery that accept a *log.Logger.
+func StdLog(lvl zapcore.Level) *log.Logger {
+	stdLog, err := zap.NewStdLogAt(logger.WithOptions(zap.AddCallerSkip(-1)), lvl)
+	if err != nil {
+		return zap.NewStdLog(logger)
+	}
+	return stdLog
 }